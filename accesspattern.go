@@ -0,0 +1,34 @@
+package autoquery
+
+// ExpectIndex asserts that the named index is selected for the expression. If index selection
+// chooses any other index, the query fails with an ErrUnexpectedIndex error rather than being
+// queried on the other index. Unlike RestrictToIndexes, ExpectIndex does not affect which indexes
+// are considered, so it may be used in tests to catch changes in the selected index caused by
+// schema or expression changes. The table's primary index may be named with PrimaryIndexName or
+// the client's PrimaryIndexLabel.
+func (expr *Expression) ExpectIndex(name string) *Expression {
+	expr.expectedIndex = name
+	return expr
+}
+
+// checkAccessPattern returns an error if the index selected for the expression is not its expected
+// index, or if the client disallows scans and the query would read an entire partition
+func (client *Client) checkAccessPattern(
+	tableName string, expr *Expression, index *tableIndex) error {
+
+	if expr.expectedIndex != "" && expr.expectedIndex != index.Name &&
+		expr.expectedIndex != client.indexLabel(index.Name) {
+
+		return &ErrUnexpectedIndex{
+			TableName:     tableName,
+			ExpectedIndex: expr.expectedIndex,
+			SelectedIndex: client.indexLabel(index.Name),
+		}
+	}
+
+	if client.DisallowScan && expr.onlyFiltersWithinPartition(index) {
+		return &ErrPartitionScan{TableName: tableName, IndexName: client.indexLabel(index.Name)}
+	}
+
+	return nil
+}
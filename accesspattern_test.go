@@ -0,0 +1,65 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDisallowScan(t *testing.T) {
+	testCases := []struct {
+		name         string
+		expr         *Expression
+		disallowScan bool
+		expectScan   bool
+	}{
+		{"partition scan allowed", NewExpression().Equal("pk", "a").Equal("x", 1), false, false},
+		{"partition scan disallowed", NewExpression().Equal("pk", "a").Equal("x", 1), true, true},
+		{"sort key condition", NewExpression().Equal("pk", "a").Equal("x", 1).
+			GreaterThan("sk", 3), true, false},
+		{"no filter conditions", NewExpression().Equal("pk", "a"), true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeService(1)
+			client := NewClient(service)
+			client.DisallowScan = tc.disallowScan
+
+			_, err := parseSortKeys(context.Background(), client.Query("T", tc.expr))
+			if tc.expectScan {
+				scanErr, ok := err.(*ErrPartitionScan)
+				if !ok {
+					t.Fatalf("expected ErrPartitionScan, got %v", err)
+				}
+				if scanErr.IndexName != PrimaryIndexName {
+					t.Errorf("expected scan of %s, got %s", PrimaryIndexName, scanErr.IndexName)
+				}
+				if calls := service.queryCallCount(); calls != 0 {
+					t.Errorf("expected no query calls, got %d", calls)
+				}
+			} else if err != nil {
+				t.Errorf("expected query to succeed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExpectIndex(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	expr := NewExpression().Equal("pk", "a").ExpectIndex("g-sk")
+	err := client.Query("T", expr).Prepare(context.Background())
+	unexpectedErr, ok := err.(*ErrUnexpectedIndex)
+	if !ok {
+		t.Fatalf("expected ErrUnexpectedIndex, got %v", err)
+	}
+	if unexpectedErr.ExpectedIndex != "g-sk" || unexpectedErr.SelectedIndex != PrimaryIndexName {
+		t.Errorf("expected g-sk and %s, got %s and %s", PrimaryIndexName,
+			unexpectedErr.ExpectedIndex, unexpectedErr.SelectedIndex)
+	}
+
+	expr = NewExpression().Equal("pk", "a").ExpectIndex(PrimaryIndexName)
+	if err := client.Query("T", expr).Prepare(context.Background()); err != nil {
+		t.Errorf("expected selected index to match, got %v", err)
+	}
+}
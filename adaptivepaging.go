@@ -0,0 +1,166 @@
+package autoquery
+
+import (
+	"context"
+	"math"
+	"reflect"
+)
+
+// PagingMode is the execution mode chosen for a parser with adaptive paging enabled.
+type PagingMode int
+
+const (
+	// DefaultPaging fetches one page per buffer refill using the parser's own page settings. It is
+	// the mode of parsers without adaptive paging and of parsers which have not yet predicted their
+	// result size.
+	DefaultPaging PagingMode = iota
+
+	// EagerPaging fetches all pages of the query on the first call to Next, for results predicted
+	// to be small.
+	EagerPaging
+
+	// StreamedPaging fetches smaller pages in the background ahead of the caller, for results
+	// predicted to be large.
+	StreamedPaging
+)
+
+// streamedPageLimit is the limit of each page query call in streamed paging mode
+const streamedPageLimit = 100
+
+// streamedPrefetchPages is the number of pages prefetched in streamed paging mode
+const streamedPrefetchPages = 2
+
+// partitionFraction is the assumed fraction of an index's items in the partition selected by the
+// partition key equality, as the number of partitions is not reported by DynamoDB
+const partitionFraction = 0.01
+
+// sortKeyFractionMap is the assumed fraction of a partition's items selected by each type of sort
+// key condition, mirroring the sort key preferences of index scoring
+var sortKeyFractionMap = map[reflect.Type]float64{
+	reflect.TypeOf(&equalsFilter{}):     0.01,
+	reflect.TypeOf(&betweenFilter{}):    0.1,
+	reflect.TypeOf(&beginsWithFilter{}): 0.2,
+	reflect.TypeOf(&existsFilter{}):     1.0,
+	reflect.TypeOf(nil):                 1.0,
+}
+
+// defaultSortKeyFraction is the assumed fraction of a partition's items selected by a less than or
+// greater than sort key condition
+const defaultSortKeyFraction = 0.5
+
+// SetAdaptivePaging enables choosing the parser's paging mode from a prediction of its result size,
+// made when the index is selected on the first call to Next. The prediction is derived from the
+// item count of the selected index, an assumed fraction of the index's items in the queried
+// partition, and the selectivity of the expression's sort key condition, and is capped by any total
+// limit. If the predicted result size is at least largeThreshold items, the parser uses
+// StreamedPaging, which limits each page query call to 100 items and prefetches 2 pages unless the
+// limit per page or prefetch has been set. Otherwise, the parser uses EagerPaging, which fetches all
+// pages of the query at once, stopping early at max pagination, the total limit, or the max
+// buffered items. If largeThreshold is 0 or less, adaptive paging is disabled.
+//
+// Adaptive paging does not apply to PartiQL expressions or expressions split with ParallelRange.
+// PagingMode reports the chosen mode. SetAdaptivePaging should be called before the first call to
+// Next.
+func (parser *Parser) SetAdaptivePaging(largeThreshold int) *Parser {
+	parser.adaptivePagingThreshold = largeThreshold
+	return parser
+}
+
+// PagingMode returns the paging mode chosen by adaptive paging, or DefaultPaging if adaptive paging
+// is disabled or the mode has not yet been chosen.
+func (parser *Parser) PagingMode() PagingMode {
+	return parser.pagingMode
+}
+
+// choosePagingMode predicts the parser's result size on the selected index and applies the paging
+// mode for the prediction, if adaptive paging is enabled and the mode has not yet been chosen
+func (parser *Parser) choosePagingMode(ctx context.Context) error {
+	if parser.adaptivePagingThreshold <= 0 || parser.pagingMode != DefaultPaging ||
+		parser.expr.usePartiQL || parser.expr.parallelSegments > 1 {
+
+		return nil
+	}
+
+	index, err := parser.selectIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	predictedSize := parser.expr.predictResultSize(index)
+	if parser.totalLimitSpecified {
+		predictedSize = math.Min(predictedSize, float64(parser.totalLimit))
+	}
+
+	if predictedSize < float64(parser.adaptivePagingThreshold) {
+		parser.pagingMode = EagerPaging
+		return nil
+	}
+
+	parser.pagingMode = StreamedPaging
+	if !parser.limitPerPageSpecified {
+		parser.limitPerPageSpecified = true
+		parser.limitPerPage = streamedPageLimit
+	}
+	if parser.prefetchPages <= 0 {
+		parser.prefetchPages = streamedPrefetchPages
+	}
+	return nil
+}
+
+// predictResultSize returns a rough prediction of the number of items returned by querying the
+// expression on a viable index
+func (expr *Expression) predictResultSize(index *tableIndex) float64 {
+	var sortKeyFilter conditionFilter
+	if index.IsComposite {
+		sortKeyFilter = expr.filters[index.SortKey]
+	}
+
+	// the table's primary key identifies at most one item
+	_, sortKeyEquals := sortKeyFilter.(*equalsFilter)
+	if index.Name == tablePrimaryIndexName && (!index.IsComposite || sortKeyEquals) {
+		return 1.0
+	}
+
+	sortKeyFraction, found := sortKeyFractionMap[reflect.TypeOf(sortKeyFilter)]
+	if !found {
+		sortKeyFraction = defaultSortKeyFraction
+	}
+
+	return float64(index.Size) * partitionFraction * sortKeyFraction
+}
+
+// fetchRemainingPages fetches the remaining pages of the query into a single page, stopping once
+// the total limit or the parser's max buffered items would be reached. An error after some pages
+// have been fetched is returned once the items of those pages have been returned, and by every
+// call after.
+func (parser *Parser) fetchRemainingPages(ctx context.Context) (*queryPage, error) {
+	if parser.eagerPagingErr != nil {
+		return nil, parser.eagerPagingErr
+	}
+
+	allPages := &queryPage{}
+	defer func() { parser.eagerPagedItems = 0 }()
+	for {
+		if parser.totalLimitSpecified &&
+			parser.returnedItems+len(allPages.items) >= parser.totalLimit {
+			break
+		}
+		if parser.maxBufferedItems > 0 && len(allPages.items) > 0 && parser.bufferSpace() <= 0 {
+			break
+		}
+
+		page, err := parser.fetchNextPage(ctx)
+		if err != nil && len(allPages.items) == 0 {
+			return nil, err
+		} else if err != nil {
+			parser.eagerPagingErr = err
+			break
+		}
+
+		allPages.items = append(allPages.items, page.items...)
+		allPages.consumedCapacity = addConsumedCapacity(
+			allPages.consumedCapacity, page.consumedCapacity)
+		parser.eagerPagedItems = len(allPages.items)
+	}
+	return allPages, nil
+}
@@ -0,0 +1,174 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestAdaptivePagingMode(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tableSize int64
+		expr      *Expression
+		mode      PagingMode
+	}{
+		// 1,000 items with 1% in the partition predicts 10 items
+		{"small partition", 1000, NewExpression().Equal("pk", "a"), EagerPaging},
+		// 100,000 items with 1% in the partition predicts 1,000 items
+		{"large partition", 100000, NewExpression().Equal("pk", "a"), StreamedPaging},
+		// a between condition on the sort key selects 10% of the partition, predicting 100 items
+		{"large partition with narrow range", 100000,
+			NewExpression().Equal("pk", "a").Between("sk", 1, 10), EagerPaging},
+		// the primary key identifies at most one item
+		{"primary key", 100000, NewExpression().Equal("pk", "a").Equal("sk", 1), EagerPaging},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			service := newFakeService(1)
+			service.table.ItemCount = aws.Int64(testCase.tableSize)
+			client := NewClient(service)
+
+			parser := client.Query("T", testCase.expr).SetAdaptivePaging(500)
+			defer parser.Close()
+			if mode := parser.PagingMode(); mode != DefaultPaging {
+				t.Fatalf("expected default paging before Next, got %v", mode)
+			}
+
+			var record testRecord
+			if err := parser.Next(context.Background(), &record); err != nil {
+				t.Fatal(err)
+			}
+			if mode := parser.PagingMode(); mode != testCase.mode {
+				t.Errorf("expected paging mode %v, got %v", testCase.mode, mode)
+			}
+		})
+	}
+}
+
+func TestAdaptivePagingTotalLimitCapsPrediction(t *testing.T) {
+	service := newFakeService(1)
+	service.table.ItemCount = aws.Int64(100000)
+	client := NewClient(service)
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a")).
+		SetAdaptivePaging(500).SetTotalLimit(10)
+
+	var record testRecord
+	if err := parser.Next(context.Background(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if mode := parser.PagingMode(); mode != EagerPaging {
+		t.Errorf("expected eager paging, got %v", mode)
+	}
+}
+
+func TestStreamedPagingSettings(t *testing.T) {
+	service := newFakeService(1, 1, 1)
+	service.table.ItemCount = aws.Int64(100000)
+	client := NewClient(service)
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a")).SetAdaptivePaging(500)
+	defer parser.Close()
+
+	var record testRecord
+	if err := parser.Next(context.Background(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if parser.prefetchPages != streamedPrefetchPages {
+		t.Errorf("expected %d prefetched pages, got %d", streamedPrefetchPages, parser.prefetchPages)
+	}
+
+	service.mutex.Lock()
+	limit := aws.Int64Value(service.queryInputs[0].Limit)
+	service.mutex.Unlock()
+	if limit != streamedPageLimit {
+		t.Errorf("expected page limit %d, got %d", streamedPageLimit, limit)
+	}
+}
+
+func TestEagerPagingFetchesAllPages(t *testing.T) {
+	service := newFakeService(2, 1, 0, 2)
+	client := NewClient(service)
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a")).SetAdaptivePaging(500)
+
+	var record testRecord
+	if err := parser.Next(context.Background(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if calls := service.queryCallCount(); calls != 4 {
+		t.Errorf("expected all 4 pages to be fetched on the first call to Next, got %d", calls)
+	}
+
+	values, err := parseSortKeys(context.Background(), parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{2, 3, 4, 5}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected items %v, got %v", expected, values)
+	}
+}
+
+func TestEagerPagingLimitsPagesToTotalLimit(t *testing.T) {
+	service := newFakeService(2, 1, 0, 2)
+	client := NewClient(service)
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a")).
+		SetAdaptivePaging(500).SetTotalLimit(3)
+	values, err := parseSortKeys(context.Background(), parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected items %v, got %v", expected, values)
+	}
+
+	// the second page only evaluates the items remaining after the first page
+	limits := []int64{}
+	for _, input := range service.queryInputs {
+		limits = append(limits, aws.Int64Value(input.Limit))
+	}
+	if expected := []int64{3, 1}; !reflect.DeepEqual(limits, expected) {
+		t.Errorf("expected page limits %v, got %v", expected, limits)
+	}
+}
+
+func TestEagerPagingErrorAfterFetchedPages(t *testing.T) {
+	service := newFakeService(2, 1, 2)
+	client := NewClient(service)
+
+	queryErr := errors.New("query failed")
+	client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		if service.queryCallCount() == 2 {
+			return nil, queryErr
+		}
+		return service.QueryWithContext(ctx, input, opts...)
+	}
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a")).SetAdaptivePaging(500)
+	values, err := parseSortKeys(context.Background(), parser)
+	if err != queryErr {
+		t.Errorf("expected query error after fetched items, got %v", err)
+	}
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected items %v before the error, got %v", expected, values)
+	}
+
+	// the error is not replaced by a query of the following page
+	var record testRecord
+	if err := parser.Next(context.Background(), &record); err != queryErr {
+		t.Errorf("expected query error on subsequent calls, got %v", err)
+	}
+	if calls := service.queryCallCount(); calls != 2 {
+		t.Errorf("expected no queries after the error, got %d successful queries", calls)
+	}
+}
@@ -0,0 +1,96 @@
+package autoquery
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// AggFunc is a running aggregation computed over a numeric attribute of parsed items.
+type AggFunc int
+
+const (
+	// AggCount counts the items which have a numeric value for the attribute.
+	AggCount AggFunc = iota
+
+	// AggSum sums the attribute's values.
+	AggSum
+
+	// AggMin finds the minimum of the attribute's values.
+	AggMin
+
+	// AggMax finds the maximum of the attribute's values.
+	AggMax
+)
+
+// AggregateResult is the running result of an aggregation registered with Parser.Aggregate.
+type AggregateResult struct {
+	// Attribute is the aggregated attribute.
+	Attribute string
+
+	// Func is the aggregation.
+	Func AggFunc
+
+	// Value is the result of the aggregation over the items parsed so far. It is 0 if Count is 0.
+	Value float64
+
+	// Count is the number of parsed items which had a numeric value for the attribute.
+	Count int
+}
+
+// Aggregate adds a running aggregation over the numeric attribute attr, which is updated as each
+// item is returned by Next, so that simple analytics may be computed over a query without
+// buffering its items. Items which do not have a numeric value for the attribute are not
+// aggregated. Values are aggregated as float64, so very large or high-precision numbers may lose
+// precision. The attribute must be selected if the expression specifies attributes.
+//
+// Aggregations apply to items returned after the call, and are cleared by Reset.
+func (parser *Parser) Aggregate(attr string, fn AggFunc) *Parser {
+	parser.aggregates = append(parser.aggregates, &AggregateResult{Attribute: attr, Func: fn})
+	return parser
+}
+
+// AggregateResult returns the running results of the parser's aggregations, in the order in which
+// they were added.
+func (parser *Parser) AggregateResult() []AggregateResult {
+	results := []AggregateResult{}
+	for _, aggregate := range parser.aggregates {
+		results = append(results, *aggregate)
+	}
+	return results
+}
+
+// aggregateItem updates the parser's aggregations with the item
+func (parser *Parser) aggregateItem(item map[string]*dynamodb.AttributeValue) {
+	for _, aggregate := range parser.aggregates {
+		value := item[aggregate.Attribute]
+		if value == nil || value.N == nil {
+			continue
+		}
+		number, err := strconv.ParseFloat(aws.StringValue(value.N), 64)
+		if err != nil {
+			continue
+		}
+		aggregate.add(number)
+	}
+}
+
+func (aggregate *AggregateResult) add(number float64) {
+	aggregate.Count++
+
+	switch aggregate.Func {
+	case AggCount:
+		aggregate.Value = float64(aggregate.Count)
+	case AggSum:
+		aggregate.Value += number
+	case AggMin:
+		if aggregate.Count == 1 || number < aggregate.Value {
+			aggregate.Value = number
+		}
+	case AggMax:
+		if aggregate.Count == 1 || number > aggregate.Value {
+			aggregate.Value = number
+		}
+	}
+}
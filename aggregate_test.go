@@ -0,0 +1,77 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 5; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: id * 10})
+	}
+	client := NewClient(newOrdersService(t, orders...))
+
+	testCases := []struct {
+		name          string
+		attr          string
+		fn            AggFunc
+		expectedValue float64
+		expectedCount int
+	}{
+		{"sum", "total", AggSum, 150, 5},
+		{"max", "total", AggMax, 50, 5},
+		{"min", "total", AggMin, 10, 5},
+		{"count", "total", AggCount, 5, 5},
+		{"non-numeric attribute", "status", AggSum, 0, 0},
+		{"missing attribute", "other", AggMax, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.Query("Orders", NewExpression().Equal("customer", "c")).
+				SetLimitPerPage(2).Aggregate(tc.attr, tc.fn)
+
+			if ids := parseOrderIDs(t, parser); len(ids) != 5 {
+				t.Fatalf("expected 5 items, got %v", ids)
+			}
+			if requests := parser.RequestCount(); requests < 3 {
+				t.Errorf("expected a multi-page stream, got %d requests", requests)
+			}
+
+			results := parser.AggregateResult()
+			if len(results) != 1 {
+				t.Fatalf("expected 1 aggregate result, got %v", results)
+			}
+			result := results[0]
+			if result.Value != tc.expectedValue || result.Count != tc.expectedCount {
+				t.Errorf("expected value %v over %d items, got %v over %d items",
+					tc.expectedValue, tc.expectedCount, result.Value, result.Count)
+			}
+
+			parser.Reset()
+			if result := parser.AggregateResult()[0]; result.Value != 0 || result.Count != 0 {
+				t.Errorf("expected aggregate to be cleared by reset, got %v", result)
+			}
+		})
+	}
+}
+
+func TestAggregateAppliesToLaterItems(t *testing.T) {
+	client := NewClient(newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Total: 10},
+		testOrder{Customer: "c", ID: 2, Total: 20},
+	))
+	parser := client.Query("Orders", NewExpression().Equal("customer", "c"))
+
+	var order testOrder
+	if err := parser.Next(context.Background(), &order); err != nil {
+		t.Fatal(err)
+	}
+	parser.Aggregate("total", AggSum)
+	parseOrderIDs(t, parser)
+
+	if result := parser.AggregateResult()[0]; result.Value != 20 || result.Count != 1 {
+		t.Errorf("expected only items after the call to be aggregated, got %v", result)
+	}
+}
@@ -0,0 +1,557 @@
+package autoquerytest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var conditionTokenPattern = regexp.MustCompile(`<=|>=|<>|[=<>(),.\[\]]|[^\s=<>(),.\[\]]+`)
+
+// condition is a parsed condition expression, such as a key condition or filter expression
+type condition interface {
+	evaluate(it item) bool
+}
+
+// operand is a document path, value placeholder, or size function in a condition expression
+type operand struct {
+	path  []pathElement
+	value *dynamodb.AttributeValue
+	size  bool
+}
+
+// pathElement is an attribute name or list index in a document path
+type pathElement struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+type andCondition struct{ left, right condition }
+type orCondition struct{ left, right condition }
+type notCondition struct{ operand condition }
+
+type comparisonCondition struct {
+	operator    string
+	left, right operand
+}
+
+type betweenCondition struct{ value, low, high operand }
+
+type inCondition struct {
+	value   operand
+	options []operand
+}
+
+type functionCondition struct {
+	function  string
+	path      operand
+	parameter operand
+}
+
+func (c andCondition) evaluate(it item) bool { return c.left.evaluate(it) && c.right.evaluate(it) }
+func (c orCondition) evaluate(it item) bool  { return c.left.evaluate(it) || c.right.evaluate(it) }
+func (c notCondition) evaluate(it item) bool { return !c.operand.evaluate(it) }
+
+func (c comparisonCondition) evaluate(it item) bool {
+	left, right := c.left.resolve(it), c.right.resolve(it)
+	if left == nil || right == nil {
+		return false
+	}
+
+	switch c.operator {
+	case "=":
+		return valuesEqual(left, right)
+	case "<>":
+		return !valuesEqual(left, right)
+	}
+
+	comparison, ok := compareValues(left, right)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	}
+	return false
+}
+
+func (c betweenCondition) evaluate(it item) bool {
+	value := c.value.resolve(it)
+	lowComparison, lowOk := compareValues(value, c.low.resolve(it))
+	highComparison, highOk := compareValues(value, c.high.resolve(it))
+	return lowOk && highOk && lowComparison >= 0 && highComparison <= 0
+}
+
+func (c inCondition) evaluate(it item) bool {
+	value := c.value.resolve(it)
+	for _, option := range c.options {
+		if valuesEqual(value, option.resolve(it)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c functionCondition) evaluate(it item) bool {
+	value := c.path.resolve(it)
+
+	switch c.function {
+	case "attribute_exists":
+		return value != nil
+	case "attribute_not_exists":
+		return value == nil
+	case "attribute_type":
+		parameter := c.parameter.resolve(it)
+		return value != nil && parameter != nil && parameter.S != nil &&
+			attributeType(value) == *parameter.S
+	case "begins_with":
+		parameter := c.parameter.resolve(it)
+		switch {
+		case value == nil || parameter == nil:
+			return false
+		case value.S != nil && parameter.S != nil:
+			return strings.HasPrefix(*value.S, *parameter.S)
+		case value.B != nil && parameter.B != nil:
+			return strings.HasPrefix(string(value.B), string(parameter.B))
+		}
+		return false
+	case "contains":
+		return containsValue(value, c.parameter.resolve(it))
+	}
+	return false
+}
+
+func containsValue(value, element *dynamodb.AttributeValue) bool {
+	switch {
+	case value == nil || element == nil:
+		return false
+	case value.S != nil && element.S != nil:
+		return strings.Contains(*value.S, *element.S)
+	case value.B != nil && element.B != nil:
+		return strings.Contains(string(value.B), string(element.B))
+	case value.SS != nil && element.S != nil:
+		for _, s := range value.SS {
+			if aws.StringValue(s) == *element.S {
+				return true
+			}
+		}
+	case value.NS != nil && element.N != nil:
+		for _, n := range value.NS {
+			if valuesEqual(&dynamodb.AttributeValue{N: n}, element) {
+				return true
+			}
+		}
+	case value.BS != nil && element.B != nil:
+		for _, b := range value.BS {
+			if string(b) == string(element.B) {
+				return true
+			}
+		}
+	case value.L != nil:
+		for _, listElement := range value.L {
+			if valuesEqual(listElement, element) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve returns the value of the operand for the item, or nil if the path is not in the item
+func (o operand) resolve(it item) *dynamodb.AttributeValue {
+	if o.value != nil {
+		return o.value
+	}
+
+	value := resolvePath(it, o.path)
+	if !o.size {
+		return value
+	}
+
+	var size int
+	switch {
+	case value == nil:
+		return nil
+	case value.S != nil:
+		size = utf8.RuneCountInString(*value.S)
+	case value.B != nil:
+		size = len(value.B)
+	case value.SS != nil:
+		size = len(value.SS)
+	case value.NS != nil:
+		size = len(value.NS)
+	case value.BS != nil:
+		size = len(value.BS)
+	case value.L != nil:
+		size = len(value.L)
+	case value.M != nil:
+		size = len(value.M)
+	default:
+		return nil
+	}
+	return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(size))}
+}
+
+func resolvePath(it item, path []pathElement) *dynamodb.AttributeValue {
+	if len(path) == 0 || path[0].isIndex {
+		return nil
+	}
+
+	value := it[path[0].name]
+	for _, element := range path[1:] {
+		switch {
+		case value == nil:
+			return nil
+		case element.isIndex && element.index < len(value.L):
+			value = value.L[element.index]
+		case !element.isIndex && value.M != nil:
+			value = value.M[element.name]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+type conditionParser struct {
+	tokens []string
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+}
+
+// parseCondition parses a condition expression with the placeholder maps of its request
+func parseCondition(expression string, names map[string]*string,
+	values map[string]*dynamodb.AttributeValue) (condition, error) {
+
+	p := &conditionParser{
+		tokens: conditionTokenPattern.FindAllString(expression, -1),
+		names:  names,
+		values: values,
+	}
+
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, validationError("invalid condition expression: unexpected token %s",
+			p.tokens[p.pos])
+	}
+
+	return c, nil
+}
+
+func (p *conditionParser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (condition, error) {
+	if p.acceptKeyword("NOT") {
+		c, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{operand: c}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (condition, error) {
+	if p.accept("(") {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+
+	if function := strings.ToLower(p.peek()); function != "size" && p.peekAt(1) == "(" {
+		return p.parseFunction(function)
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("AND") {
+			return nil, validationError("invalid condition expression: BETWEEN requires AND")
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCondition{value: left, low: low, high: high}, nil
+	case p.acceptKeyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		c := inCondition{value: left}
+		for {
+			option, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			c.options = append(c.options, option)
+			if !p.accept(",") {
+				break
+			}
+		}
+		return c, p.expect(")")
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch operator {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		return nil, validationError("invalid condition expression: unexpected token %s",
+			operator)
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return comparisonCondition{operator: operator, left: left, right: right}, nil
+}
+
+func (p *conditionParser) parseFunction(function string) (condition, error) {
+	p.pos++
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	path, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	c := functionCondition{function: function, path: path}
+
+	switch function {
+	case "attribute_exists", "attribute_not_exists":
+	case "attribute_type", "begins_with", "contains":
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if c.parameter, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, validationError("invalid condition expression: unknown function %s",
+			function)
+	}
+
+	return c, p.expect(")")
+}
+
+func (p *conditionParser) parseOperand() (operand, error) {
+	token, err := p.next()
+	if err != nil {
+		return operand{}, err
+	}
+
+	if strings.HasPrefix(token, ":") {
+		value, found := p.values[token]
+		if !found {
+			return operand{}, validationError(
+				"invalid condition expression: undefined value placeholder %s", token)
+		}
+		return operand{value: value}, nil
+	}
+
+	if strings.EqualFold(token, "size") && p.accept("(") {
+		token, err := p.next()
+		if err != nil {
+			return operand{}, err
+		}
+		p.pos--
+		path, err := p.parsePath(token)
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{path: path, size: true}, p.expect(")")
+	}
+
+	p.pos--
+	path, err := p.parsePath(token)
+	return operand{path: path}, err
+}
+
+// parsePath parses a document path beginning at the current token, which is first
+func (p *conditionParser) parsePath(first string) ([]pathElement, error) {
+	p.pos++
+	name, err := resolveName(first, p.names)
+	if err != nil {
+		return nil, err
+	}
+	path := []pathElement{{name: name}}
+
+	for {
+		switch {
+		case p.accept("."):
+			token, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			name, err := resolveName(token, p.names)
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, pathElement{name: name})
+		case p.accept("["):
+			token, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			index, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, validationError("invalid document path: list index %s", token)
+			}
+			path = append(path, pathElement{index: index, isIndex: true})
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+	}
+}
+
+func resolveName(token string, names map[string]*string) (string, error) {
+	if !strings.HasPrefix(token, "#") {
+		return token, nil
+	}
+	name, found := names[token]
+	if !found {
+		return "", validationError("undefined attribute name placeholder %s", token)
+	}
+	return aws.StringValue(name), nil
+}
+
+func (p *conditionParser) peek() string {
+	return p.peekAt(0)
+}
+
+func (p *conditionParser) peekAt(offset int) string {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+	return ""
+}
+
+func (p *conditionParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", validationError("invalid condition expression: unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, nil
+}
+
+func (p *conditionParser) accept(token string) bool {
+	if p.peek() == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) acceptKeyword(keyword string) bool {
+	if strings.EqualFold(p.peek(), keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) expect(token string) error {
+	if !p.accept(token) {
+		return validationError("invalid condition expression: expected %s", token)
+	}
+	return nil
+}
+
+// conditionAttributes returns the top-level attribute names referenced by the condition
+func conditionAttributes(c condition) []string {
+	attributes := []string{}
+	addOperand := func(o operand) {
+		if o.value == nil && len(o.path) > 0 {
+			attributes = append(attributes, o.path[0].name)
+		}
+	}
+
+	switch c := c.(type) {
+	case andCondition:
+		attributes = append(conditionAttributes(c.left), conditionAttributes(c.right)...)
+	case orCondition:
+		attributes = append(conditionAttributes(c.left), conditionAttributes(c.right)...)
+	case notCondition:
+		attributes = conditionAttributes(c.operand)
+	case comparisonCondition:
+		addOperand(c.left)
+		addOperand(c.right)
+	case betweenCondition:
+		addOperand(c.value)
+		addOperand(c.low)
+		addOperand(c.high)
+	case inCondition:
+		addOperand(c.value)
+		for _, option := range c.options {
+			addOperand(option)
+		}
+	case functionCondition:
+		addOperand(c.path)
+		addOperand(c.parameter)
+	}
+
+	return attributes
+}
@@ -0,0 +1,49 @@
+package autoquerytest
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// parseProjection returns the top-level attributes of a projection expression, or nil if the
+// expression is nil. Nested document paths project their entire top-level attribute.
+func parseProjection(projectionExpression *string, names map[string]*string) ([]string, error) {
+	if projectionExpression == nil {
+		return nil, nil
+	}
+
+	attributes := []string{}
+	for _, path := range strings.Split(*projectionExpression, ",") {
+		path = strings.TrimSpace(path)
+		path = strings.SplitN(path, ".", 2)[0]
+		path = strings.SplitN(path, "[", 2)[0]
+		if path == "" {
+			return nil, validationError("invalid projection expression: %s", *projectionExpression)
+		}
+
+		attribute, err := resolveName(path, names)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
+// projectItem returns the item with only the projected attributes, or the whole item if
+// projection is nil
+func projectItem(it item, projection []string) item {
+	if projection == nil {
+		return copyItem(it)
+	}
+
+	projected := map[string]*dynamodb.AttributeValue{}
+	for _, attribute := range projection {
+		if value, found := it[attribute]; found {
+			projected[attribute] = copyValue(value)
+		}
+	}
+	return projected
+}
@@ -0,0 +1,252 @@
+package autoquerytest
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// pageRequest holds the parameters shared by Query and Scan pages
+type pageRequest struct {
+	table             *table
+	index             *index
+	filter            condition
+	projection        []string
+	selectCount       bool
+	limit             int
+	exclusiveStartKey item
+}
+
+// Query returns a page of the items in one partition of the table or index that satisfy the key
+// condition and filter expressions.
+func (s *Service) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	return s.QueryWithContext(aws.BackgroundContext(), input)
+}
+
+// QueryWithContext is the same as Query with the addition of a context, which is ignored.
+func (s *Service) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput,
+	opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	page, err := s.newPageRequest(input.TableName, input.IndexName, input.FilterExpression,
+		input.ProjectionExpression, input.Select, input.Limit, input.ExclusiveStartKey,
+		input.ConsistentRead, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.KeyConditionExpression == nil {
+		return nil, validationError("query requires a key condition expression")
+	}
+	keyCondition, err := parseCondition(*input.KeyConditionExpression,
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateKeyCondition(keyCondition, page.index); err != nil {
+		return nil, err
+	}
+
+	items := []item{}
+	for _, it := range page.table.indexItems(page.index, page.projectsIndex()) {
+		if keyCondition.evaluate(it) {
+			items = append(items, it)
+		}
+	}
+
+	if input.ScanIndexForward != nil && !*input.ScanIndexForward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	output := &dynamodb.QueryOutput{}
+	output.Items, output.Count, output.ScannedCount, output.LastEvaluatedKey =
+		s.executePage(page, items)
+
+	return output, nil
+}
+
+// Scan returns a page of the items in the table or index that satisfy the filter expression, in
+// order of partition key and then sort key. Parallel scan segments are not supported.
+func (s *Service) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return s.ScanWithContext(aws.BackgroundContext(), input)
+}
+
+// ScanWithContext is the same as Scan with the addition of a context, which is ignored.
+func (s *Service) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput,
+	opts ...request.Option) (*dynamodb.ScanOutput, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if input.Segment != nil || input.TotalSegments != nil {
+		return nil, validationError("parallel scan is not supported")
+	}
+
+	page, err := s.newPageRequest(input.TableName, input.IndexName, input.FilterExpression,
+		input.ProjectionExpression, input.Select, input.Limit, input.ExclusiveStartKey,
+		input.ConsistentRead, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &dynamodb.ScanOutput{}
+	output.Items, output.Count, output.ScannedCount, output.LastEvaluatedKey =
+		s.executePage(page, page.table.indexItems(page.index, page.projectsIndex()))
+
+	return output, nil
+}
+
+func (s *Service) newPageRequest(tableName, indexName, filterExpression,
+	projectionExpression, selectAttributes *string, limit *int64, exclusiveStartKey item,
+	consistentRead *bool, names map[string]*string,
+	values map[string]*dynamodb.AttributeValue) (*pageRequest, error) {
+
+	t, err := s.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	i, err := t.index(indexName)
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.BoolValue(consistentRead) && i.isGlobal {
+		return nil, validationError(
+			"consistent reads are not supported on global secondary indexes")
+	}
+	if limit != nil && *limit < 1 {
+		return nil, validationError("limit must be greater than or equal to 1")
+	}
+
+	page := &pageRequest{
+		table:             t,
+		index:             i,
+		limit:             int(aws.Int64Value(limit)),
+		exclusiveStartKey: exclusiveStartKey,
+	}
+
+	if filterExpression != nil {
+		if page.filter, err = parseCondition(*filterExpression, names, values); err != nil {
+			return nil, err
+		}
+	}
+	if page.projection, err = parseProjection(projectionExpression, names); err != nil {
+		return nil, err
+	}
+
+	switch aws.StringValue(selectAttributes) {
+	case dynamodb.SelectCount:
+		page.selectCount = true
+	case dynamodb.SelectAllAttributes:
+		if i.isGlobal && i.projectionType != dynamodb.ProjectionTypeAll {
+			return nil, validationError(
+				"global secondary index %s does not project all attributes", i.name)
+		}
+	}
+
+	// global secondary indexes cannot fetch attributes from the table
+	if i.isGlobal {
+		for _, attribute := range page.projection {
+			if !t.projectsAttribute(i, attribute) {
+				return nil, validationError(
+					"global secondary index %s does not project attribute %s", i.name, attribute)
+			}
+		}
+	}
+
+	return page, nil
+}
+
+// projectsIndex returns true if the page reads only the index's projected attributes, rather than
+// fetching attributes from the table as local secondary indexes do
+func (page *pageRequest) projectsIndex() bool {
+	if page.index.isGlobal {
+		return true
+	}
+	if page.projection == nil {
+		return false
+	}
+	for _, attribute := range page.projection {
+		if !page.table.projectsAttribute(page.index, attribute) {
+			return false
+		}
+	}
+	return true
+}
+
+// executePage evaluates the ordered items following the exclusive start key up to the page's
+// limit, returning the items that satisfy the filter, the count of those items and of the
+// evaluated items, and the last evaluated key if evaluation stopped before all items
+func (s *Service) executePage(page *pageRequest, items []item) (
+	[]map[string]*dynamodb.AttributeValue, *int64, *int64, map[string]*dynamodb.AttributeValue) {
+
+	keyAttributes := page.table.keyAttributes(page.index)
+
+	start := 0
+	if len(page.exclusiveStartKey) > 0 {
+		startKey := keyString(page.exclusiveStartKey, keyAttributes)
+		for i, it := range items {
+			if keyString(it, keyAttributes) == startKey {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	limit := page.limit
+	if s.PageItemLimit > 0 && (limit == 0 || s.PageItemLimit < limit) {
+		limit = s.PageItemLimit
+	}
+
+	matchedItems := []map[string]*dynamodb.AttributeValue{}
+	scannedCount := 0
+	var lastEvaluatedKey map[string]*dynamodb.AttributeValue
+	for i := start; i < len(items); i++ {
+		it := items[i]
+		scannedCount++
+
+		if page.filter == nil || page.filter.evaluate(it) {
+			matchedItems = append(matchedItems, projectItem(it, page.projection))
+		}
+
+		// as with DynamoDB, a last evaluated key is returned whenever the limit is reached
+		if limit > 0 && scannedCount == limit {
+			lastEvaluatedKey = map[string]*dynamodb.AttributeValue{}
+			for _, attribute := range keyAttributes {
+				lastEvaluatedKey[attribute] = it[attribute]
+			}
+			break
+		}
+	}
+
+	count := aws.Int64(int64(len(matchedItems)))
+	if page.selectCount {
+		matchedItems = nil
+	}
+
+	return matchedItems, count, aws.Int64(int64(scannedCount)), lastEvaluatedKey
+}
+
+// validateKeyCondition returns an error if the key condition references attributes other than the
+// index's keys or does not reference its partition key
+func validateKeyCondition(keyCondition condition, i *index) error {
+	referencesPartitionKey := false
+	for _, attribute := range conditionAttributes(keyCondition) {
+		switch attribute {
+		case i.partitionKey:
+			referencesPartitionKey = true
+		case i.sortKey:
+		default:
+			return validationError(
+				"query key condition not supported: %s is not a key attribute", attribute)
+		}
+	}
+	if !referencesPartitionKey {
+		return validationError("query condition missed key schema element: %s", i.partitionKey)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package autoquerytest provides an in-memory DynamoDB service for testing code that queries
+// through autoquery without AWS or DynamoDB Local.
+package autoquerytest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Service is an in-memory implementation of the DynamoDB API, which may be passed to
+// autoquery.NewClient in place of a DynamoDB service. Tables are created with CreateTable and
+// populated with PutItem, after which DescribeTable, GetItem, BatchGetItem, Query, and Scan
+// answer requests from the stored items.
+//
+// Query and Scan honor key condition, filter, and projection expressions with their placeholder
+// maps, Select, Limit, ScanIndexForward, ExclusiveStartKey, and pagination, and secondary indexes
+// store only their projected attributes. Capacity is not modeled, so consumed capacity is never
+// returned. Calls to any other DynamoDB API operation panic.
+//
+// A Service is safe for concurrent use.
+type Service struct {
+	dynamodbiface.DynamoDBAPI
+
+	// PageItemLimit, if greater than 0, limits the number of items evaluated by each Query or
+	// Scan page, in addition to the request's Limit. This may be used to exercise pagination in
+	// place of DynamoDB's 1 MB page size.
+	PageItemLimit int
+
+	mutex  sync.Mutex
+	tables map[string]*table
+}
+
+// NewService creates a new Service with no tables.
+func NewService() *Service {
+	return &Service{
+		tables: map[string]*table{},
+	}
+}
+
+// CreateTable creates a table from the input's table name, key schema, attribute definitions, and
+// secondary indexes. The table is immediately active. Other input parameters are ignored.
+func (s *Service) CreateTable(
+	input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+
+	return s.CreateTableWithContext(aws.BackgroundContext(), input)
+}
+
+// CreateTableWithContext is the same as CreateTable with the addition of a context, which is
+// ignored.
+func (s *Service) CreateTableWithContext(ctx aws.Context, input *dynamodb.CreateTableInput,
+	opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tableName := aws.StringValue(input.TableName)
+	if _, found := s.tables[tableName]; found {
+		return nil, awserr.New(dynamodb.ErrCodeResourceInUseException,
+			fmt.Sprintf("table already exists: %s", tableName), nil)
+	}
+
+	t, err := newTable(input)
+	if err != nil {
+		return nil, err
+	}
+	s.tables[tableName] = t
+
+	return &dynamodb.CreateTableOutput{TableDescription: t.describe()}, nil
+}
+
+// DescribeTable returns the table's key schema, attribute definitions, secondary indexes, and the
+// current number of items in the table and in each index.
+func (s *Service) DescribeTable(
+	input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+
+	return s.DescribeTableWithContext(aws.BackgroundContext(), input)
+}
+
+// DescribeTableWithContext is the same as DescribeTable with the addition of a context, which is
+// ignored.
+func (s *Service) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, err := s.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: t.describe()}, nil
+}
+
+// PutItem stores the item, replacing any item with the same key. If the input has a condition
+// expression, it is evaluated against the existing item.
+func (s *Service) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return s.PutItemWithContext(aws.BackgroundContext(), input)
+}
+
+// PutItemWithContext is the same as PutItem with the addition of a context, which is ignored.
+func (s *Service) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput,
+	opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, err := s.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.validateKey(input.Item, t.primary); err != nil {
+		return nil, err
+	}
+	key := t.primaryKeyString(input.Item)
+
+	if input.ConditionExpression != nil {
+		cond, err := parseCondition(*input.ConditionExpression,
+			input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		existingItem := t.items[key]
+		if existingItem == nil {
+			existingItem = item{}
+		}
+		if !cond.evaluate(existingItem) {
+			return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException,
+				"the conditional request failed", nil)
+		}
+	}
+
+	t.items[key] = copyItem(input.Item)
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// GetItem returns the item with the input's key, if any.
+func (s *Service) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return s.GetItemWithContext(aws.BackgroundContext(), input)
+}
+
+// GetItemWithContext is the same as GetItem with the addition of a context, which is ignored.
+func (s *Service) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput,
+	opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, err := s.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := t.getItem(input.Key, input.ProjectionExpression, input.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: found}, nil
+}
+
+// BatchGetItem returns the items with each of the requested keys. All keys are processed, so the
+// output never contains unprocessed keys.
+func (s *Service) BatchGetItem(
+	input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+
+	return s.BatchGetItemWithContext(aws.BackgroundContext(), input)
+}
+
+// BatchGetItemWithContext is the same as BatchGetItem with the addition of a context, which is
+// ignored.
+func (s *Service) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput,
+	opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+
+	for tableName, keysAndAttributes := range input.RequestItems {
+		t, err := s.table(aws.String(tableName))
+		if err != nil {
+			return nil, err
+		}
+
+		responses := []map[string]*dynamodb.AttributeValue{}
+		for _, key := range keysAndAttributes.Keys {
+			found, err := t.getItem(key, keysAndAttributes.ProjectionExpression,
+				keysAndAttributes.ExpressionAttributeNames)
+			if err != nil {
+				return nil, err
+			}
+			if found != nil {
+				responses = append(responses, found)
+			}
+		}
+		output.Responses[tableName] = responses
+	}
+
+	return output, nil
+}
+
+// TableNames returns the names of the service's tables in sorted order.
+func (s *Service) TableNames() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tableNames := []string{}
+	for tableName := range s.tables {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	return tableNames
+}
+
+func (s *Service) table(tableName *string) (*table, error) {
+	t, found := s.tables[aws.StringValue(tableName)]
+	if !found {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException,
+			fmt.Sprintf("requested resource not found: table: %s not found",
+				aws.StringValue(tableName)), nil)
+	}
+	return t, nil
+}
+
+func validationError(format string, args ...interface{}) error {
+	return awserr.New("ValidationException", fmt.Sprintf(format, args...), nil)
+}
@@ -0,0 +1,373 @@
+package autoquerytest
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// newEventsService creates a service with an Events table keyed by user and timestamp, with a
+// keys-only global secondary index on kind and timestamp, populated with events for two users
+func newEventsService(t *testing.T) *Service {
+	t.Helper()
+
+	s := NewService()
+	_, err := s.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("Events"),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("user"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("ts"), KeyType: aws.String("RANGE")},
+		},
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("user"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("ts"), AttributeType: aws.String("N")},
+			{AttributeName: aws.String("kind"), AttributeType: aws.String("S")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("kind-ts"),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String("kind"), KeyType: aws.String("HASH")},
+					{AttributeName: aws.String("ts"), KeyType: aws.String("RANGE")},
+				},
+				Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, user := range []string{"ann", "bob"} {
+		for ts := 1; ts <= 10; ts++ {
+			kind := "view"
+			if ts%3 == 0 {
+				kind = "click"
+			}
+			_, err := s.PutItem(&dynamodb.PutItemInput{
+				TableName: aws.String("Events"),
+				Item: map[string]*dynamodb.AttributeValue{
+					"user":  {S: aws.String(user)},
+					"ts":    {N: aws.String(strconv.Itoa(ts))},
+					"kind":  {S: aws.String(kind)},
+					"score": {N: aws.String(strconv.Itoa(ts * 10))},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return s
+}
+
+// timestamps returns the ts values of the items
+func timestamps(items []map[string]*dynamodb.AttributeValue) []int {
+	values := []int{}
+	for _, it := range items {
+		value, _ := strconv.Atoi(aws.StringValue(it["ts"].N))
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestQueryKeyCondition(t *testing.T) {
+	s := newEventsService(t)
+
+	testCases := []struct {
+		name         string
+		keyCondition string
+		values       map[string]*dynamodb.AttributeValue
+		forward      bool
+		expected     []int
+	}{
+		{
+			name:         "partition only",
+			keyCondition: "#u = :u",
+			values:       map[string]*dynamodb.AttributeValue{":u": {S: aws.String("ann")}},
+			forward:      true,
+			expected:     []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		},
+		{
+			name:         "sort key between",
+			keyCondition: "#u = :u AND #t BETWEEN :lo AND :hi",
+			values: map[string]*dynamodb.AttributeValue{
+				":u": {S: aws.String("ann")}, ":lo": {N: aws.String("3")}, ":hi": {N: aws.String("5")},
+			},
+			forward:  true,
+			expected: []int{3, 4, 5},
+		},
+		{
+			name:         "sort key greater than descending",
+			keyCondition: "#u = :u AND #t > :lo",
+			values: map[string]*dynamodb.AttributeValue{
+				":u": {S: aws.String("bob")}, ":lo": {N: aws.String("7")},
+			},
+			forward:  false,
+			expected: []int{10, 9, 8},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := s.Query(&dynamodb.QueryInput{
+				TableName:                 aws.String("Events"),
+				KeyConditionExpression:    aws.String(tc.keyCondition),
+				ExpressionAttributeNames:  map[string]*string{"#u": aws.String("user"), "#t": aws.String("ts")},
+				ExpressionAttributeValues: tc.values,
+				ScanIndexForward:          aws.Bool(tc.forward),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := timestamps(output.Items); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestQueryRejectsNonKeyCondition(t *testing.T) {
+	s := newEventsService(t)
+
+	_, err := s.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("Events"),
+		KeyConditionExpression:    aws.String("score = :s"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":s": {N: aws.String("10")}},
+	})
+	awsErr, ok := err.(awserr.Error)
+	if !ok || awsErr.Code() != "ValidationException" {
+		t.Errorf("expected ValidationException, got %v", err)
+	}
+}
+
+func TestQueryFilterExpression(t *testing.T) {
+	s := newEventsService(t)
+
+	output, err := s.Query(&dynamodb.QueryInput{
+		TableName:              aws.String("Events"),
+		KeyConditionExpression: aws.String("#u = :u"),
+		FilterExpression:       aws.String("kind = :k AND score >= :min"),
+		ExpressionAttributeNames: map[string]*string{
+			"#u": aws.String("user"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":u": {S: aws.String("ann")}, ":k": {S: aws.String("click")}, ":min": {N: aws.String("50")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{6, 9}
+	if actual := timestamps(output.Items); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	// filtered items are evaluated, so they count toward the scanned count
+	if aws.Int64Value(output.Count) != 2 || aws.Int64Value(output.ScannedCount) != 10 {
+		t.Errorf("expected count 2 of 10 scanned, got %d of %d",
+			aws.Int64Value(output.Count), aws.Int64Value(output.ScannedCount))
+	}
+}
+
+func TestQueryProjectionExpression(t *testing.T) {
+	s := newEventsService(t)
+
+	output, err := s.Query(&dynamodb.QueryInput{
+		TableName:                aws.String("Events"),
+		KeyConditionExpression:   aws.String("#u = :u AND ts = :t"),
+		ProjectionExpression:     aws.String("score, kind"),
+		ExpressionAttributeNames: map[string]*string{"#u": aws.String("user")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":u": {S: aws.String("ann")}, ":t": {N: aws.String("3")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]*dynamodb.AttributeValue{
+		{"score": {N: aws.String("30")}, "kind": {S: aws.String("click")}},
+	}
+	if !reflect.DeepEqual(output.Items, expected) {
+		t.Errorf("expected %v, got %v", expected, output.Items)
+	}
+}
+
+func TestQueryIndexProjection(t *testing.T) {
+	s := newEventsService(t)
+
+	output, err := s.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("Events"),
+		IndexName:                 aws.String("kind-ts"),
+		KeyConditionExpression:    aws.String("kind = :k"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":k": {S: aws.String("click")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keys-only index items hold the index and table keys, ordered by the index sort key
+	expected := []int{3, 3, 6, 6, 9, 9}
+	if actual := timestamps(output.Items); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	for _, it := range output.Items {
+		if _, found := it["score"]; found || len(it) != 3 {
+			t.Errorf("expected only key attributes, got %v", it)
+		}
+	}
+
+	// selecting an unprojected attribute from a global secondary index is invalid
+	_, err = s.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("Events"),
+		IndexName:                 aws.String("kind-ts"),
+		KeyConditionExpression:    aws.String("kind = :k"),
+		ProjectionExpression:      aws.String("score"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":k": {S: aws.String("click")}},
+	})
+	if err == nil {
+		t.Error("expected error selecting unprojected attribute from global secondary index")
+	}
+}
+
+func TestQueryPagination(t *testing.T) {
+	testCases := []struct {
+		name          string
+		limit         int64
+		pageItemLimit int
+		filter        bool
+		expectedPages [][]int
+	}{
+		{
+			name:          "limit",
+			limit:         4,
+			expectedPages: [][]int{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10}},
+		},
+		{
+			name:          "limit dividing items evenly",
+			limit:         5,
+			expectedPages: [][]int{{1, 2, 3, 4, 5}, {6, 7, 8, 9, 10}, {}},
+		},
+		{
+			name:          "page item limit",
+			pageItemLimit: 6,
+			expectedPages: [][]int{{1, 2, 3, 4, 5, 6}, {7, 8, 9, 10}},
+		},
+		{
+			name:          "limit with filter",
+			limit:         4,
+			filter:        true,
+			expectedPages: [][]int{{3}, {6}, {9}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newEventsService(t)
+			s.PageItemLimit = tc.pageItemLimit
+
+			input := &dynamodb.QueryInput{
+				TableName:                 aws.String("Events"),
+				KeyConditionExpression:    aws.String("#u = :u"),
+				ExpressionAttributeNames:  map[string]*string{"#u": aws.String("user")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":u": {S: aws.String("bob")}},
+			}
+			if tc.limit > 0 {
+				input.Limit = aws.Int64(tc.limit)
+			}
+			if tc.filter {
+				input.FilterExpression = aws.String("kind = :k")
+				input.ExpressionAttributeValues[":k"] = &dynamodb.AttributeValue{S: aws.String("click")}
+			}
+
+			pages := [][]int{}
+			for {
+				output, err := s.Query(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				pages = append(pages, timestamps(output.Items))
+				if len(output.LastEvaluatedKey) == 0 {
+					break
+				}
+				input.ExclusiveStartKey = output.LastEvaluatedKey
+			}
+
+			if !reflect.DeepEqual(pages, tc.expectedPages) {
+				t.Errorf("expected pages %v, got %v", tc.expectedPages, pages)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	s := newEventsService(t)
+
+	output, err := s.Scan(&dynamodb.ScanInput{
+		TableName:                 aws.String("Events"),
+		FilterExpression:          aws.String("ts > :t"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":t": {N: aws.String("8")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := []string{}
+	for _, it := range output.Items {
+		actual = append(actual, fmt.Sprintf("%s/%s", *it["user"].S, *it["ts"].N))
+	}
+	expected := []string{"ann/9", "ann/10", "bob/9", "bob/10"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestDescribeTableItemCounts(t *testing.T) {
+	s := newEventsService(t)
+
+	output, err := s.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("Events")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if itemCount := aws.Int64Value(output.Table.ItemCount); itemCount != 20 {
+		t.Errorf("expected 20 table items, got %d", itemCount)
+	}
+	gsi := output.Table.GlobalSecondaryIndexes[0]
+	if itemCount := aws.Int64Value(gsi.ItemCount); itemCount != 20 {
+		t.Errorf("expected 20 index items, got %d", itemCount)
+	}
+}
+
+func TestStoredItemsAreCopied(t *testing.T) {
+	s := newEventsService(t)
+
+	input := &dynamodb.QueryInput{
+		TableName:                aws.String("Events"),
+		KeyConditionExpression:   aws.String("#u = :u AND ts = :t"),
+		ExpressionAttributeNames: map[string]*string{"#u": aws.String("user")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":u": {S: aws.String("ann")}, ":t": {N: aws.String("1")},
+		},
+	}
+	output, err := s.Query(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output.Items[0]["kind"].S = aws.String("modified")
+
+	output, err = s.Query(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind := aws.StringValue(output.Items[0]["kind"].S); kind != "view" {
+		t.Errorf("expected stored item to be unchanged, got kind %s", kind)
+	}
+}
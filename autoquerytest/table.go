@@ -0,0 +1,384 @@
+package autoquerytest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type item = map[string]*dynamodb.AttributeValue
+
+// index is the key schema and projection of a table's primary index or a secondary index
+type index struct {
+	name             string
+	partitionKey     string
+	sortKey          string
+	isGlobal         bool
+	projectionType   string
+	nonKeyAttributes []string
+	keySchema        []*dynamodb.KeySchemaElement
+	projection       *dynamodb.Projection
+}
+
+type table struct {
+	name                 string
+	attributeDefinitions []*dynamodb.AttributeDefinition
+	attributeTypes       map[string]string
+	primary              *index
+	globalIndexes        []*index
+	localIndexes         []*index
+
+	// items are keyed by their primary key string
+	items map[string]item
+}
+
+func newTable(input *dynamodb.CreateTableInput) (*table, error) {
+	t := &table{
+		name:                 aws.StringValue(input.TableName),
+		attributeDefinitions: input.AttributeDefinitions,
+		attributeTypes:       map[string]string{},
+		items:                map[string]item{},
+	}
+	if t.name == "" {
+		return nil, validationError("table name is required")
+	}
+
+	for _, definition := range input.AttributeDefinitions {
+		t.attributeTypes[aws.StringValue(definition.AttributeName)] =
+			aws.StringValue(definition.AttributeType)
+	}
+
+	var err error
+	t.primary, err = t.newIndex("", input.KeySchema, &dynamodb.Projection{
+		ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		index, err := t.newIndex(
+			aws.StringValue(gsi.IndexName), gsi.KeySchema, gsi.Projection, true)
+		if err != nil {
+			return nil, err
+		}
+		t.globalIndexes = append(t.globalIndexes, index)
+	}
+
+	for _, lsi := range input.LocalSecondaryIndexes {
+		index, err := t.newIndex(
+			aws.StringValue(lsi.IndexName), lsi.KeySchema, lsi.Projection, false)
+		if err != nil {
+			return nil, err
+		}
+		if index.partitionKey != t.primary.partitionKey {
+			return nil, validationError(
+				"local secondary index %s must have the table's partition key", index.name)
+		}
+		t.localIndexes = append(t.localIndexes, index)
+	}
+
+	return t, nil
+}
+
+func (t *table) newIndex(name string, keySchema []*dynamodb.KeySchemaElement,
+	projection *dynamodb.Projection, isGlobal bool) (*index, error) {
+
+	i := &index{
+		name:       name,
+		isGlobal:   isGlobal,
+		keySchema:  keySchema,
+		projection: projection,
+	}
+
+	for _, element := range keySchema {
+		attributeName := aws.StringValue(element.AttributeName)
+		if _, found := t.attributeTypes[attributeName]; !found {
+			return nil, validationError("key attribute %s is not defined", attributeName)
+		}
+		switch aws.StringValue(element.KeyType) {
+		case dynamodb.KeyTypeHash:
+			i.partitionKey = attributeName
+		case dynamodb.KeyTypeRange:
+			i.sortKey = attributeName
+		}
+	}
+	if i.partitionKey == "" {
+		return nil, validationError("key schema must include a partition key")
+	}
+
+	if projection == nil || projection.ProjectionType == nil {
+		return nil, validationError("index %s must have a projection", name)
+	}
+	i.projectionType = aws.StringValue(projection.ProjectionType)
+	i.nonKeyAttributes = aws.StringValueSlice(projection.NonKeyAttributes)
+
+	return i, nil
+}
+
+func (t *table) describe() *dynamodb.TableDescription {
+	description := &dynamodb.TableDescription{
+		TableName:            aws.String(t.name),
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		KeySchema:            t.primary.keySchema,
+		AttributeDefinitions: t.attributeDefinitions,
+		ItemCount:            aws.Int64(int64(len(t.items))),
+	}
+
+	for _, gsi := range t.globalIndexes {
+		description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes,
+			&dynamodb.GlobalSecondaryIndexDescription{
+				IndexName:   aws.String(gsi.name),
+				IndexStatus: aws.String(dynamodb.IndexStatusActive),
+				KeySchema:   gsi.keySchema,
+				Projection:  gsi.projection,
+				ItemCount:   aws.Int64(int64(len(t.indexItems(gsi, false)))),
+			})
+	}
+
+	for _, lsi := range t.localIndexes {
+		description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes,
+			&dynamodb.LocalSecondaryIndexDescription{
+				IndexName:  aws.String(lsi.name),
+				KeySchema:  lsi.keySchema,
+				Projection: lsi.projection,
+				ItemCount:  aws.Int64(int64(len(t.indexItems(lsi, false)))),
+			})
+	}
+
+	return description
+}
+
+// index returns the named index, or the table's primary index if the name is empty
+func (t *table) index(indexName *string) (*index, error) {
+	if indexName == nil {
+		return t.primary, nil
+	}
+	for _, i := range append(append([]*index{}, t.globalIndexes...), t.localIndexes...) {
+		if i.name == *indexName {
+			return i, nil
+		}
+	}
+	return nil, validationError("table %s does not have the specified index: %s",
+		t.name, *indexName)
+}
+
+// keyAttributes returns the index's key attributes followed by the table's key attributes, without
+// duplicates
+func (t *table) keyAttributes(i *index) []string {
+	attributes := []string{}
+	seen := map[string]struct{}{}
+	for _, attribute := range []string{i.partitionKey, i.sortKey,
+		t.primary.partitionKey, t.primary.sortKey} {
+		if _, found := seen[attribute]; attribute != "" && !found {
+			seen[attribute] = struct{}{}
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes
+}
+
+// validateKey returns an error if the item is missing an index key attribute or has a key
+// attribute of the wrong type
+func (t *table) validateKey(it item, i *index) error {
+	for _, attribute := range []string{i.partitionKey, i.sortKey} {
+		if attribute == "" {
+			continue
+		}
+		if !hasKeyValue(it[attribute], t.attributeTypes[attribute]) {
+			return validationError("missing or invalid key attribute %s of type %s",
+				attribute, t.attributeTypes[attribute])
+		}
+	}
+	return nil
+}
+
+func (t *table) primaryKeyString(it item) string {
+	return keyString(it, []string{t.primary.partitionKey, t.primary.sortKey})
+}
+
+func (t *table) getItem(key item, projectionExpression *string,
+	names map[string]*string) (item, error) {
+
+	if err := t.validateKey(key, t.primary); err != nil {
+		return nil, err
+	}
+
+	projection, err := parseProjection(projectionExpression, names)
+	if err != nil {
+		return nil, err
+	}
+
+	found, ok := t.items[t.primaryKeyString(key)]
+	if !ok {
+		return nil, nil
+	}
+
+	return projectItem(found, projection), nil
+}
+
+// indexItems returns the items in the index, ordered by partition key, then sort key, then the
+// table's primary key. Items only include the index's projected attributes if project is true.
+func (t *table) indexItems(i *index, project bool) []item {
+	items := []item{}
+	for _, it := range t.items {
+		if i != t.primary && t.validateKey(it, i) != nil {
+			// items without the index's key attributes are not in the index
+			continue
+		}
+		if project {
+			items = append(items, t.projectForIndex(it, i))
+		} else {
+			items = append(items, copyItem(it))
+		}
+	}
+
+	orderAttributes := append([]string{i.partitionKey, i.sortKey},
+		t.primary.partitionKey, t.primary.sortKey)
+	sort.SliceStable(items, func(a, b int) bool {
+		for _, attribute := range orderAttributes {
+			if attribute == "" {
+				continue
+			}
+			if c, _ := compareValues(items[a][attribute], items[b][attribute]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	return items
+}
+
+// projectForIndex returns the attributes of the item projected into the index
+func (t *table) projectForIndex(it item, i *index) item {
+	if i.projectionType == dynamodb.ProjectionTypeAll {
+		return copyItem(it)
+	}
+
+	projected := item{}
+	attributes := t.keyAttributes(i)
+	if i.projectionType == dynamodb.ProjectionTypeInclude {
+		attributes = append(attributes, i.nonKeyAttributes...)
+	}
+	for _, attribute := range attributes {
+		if value, found := it[attribute]; found {
+			projected[attribute] = value
+		}
+	}
+	return projected
+}
+
+// projectsAttribute returns true if the index stores the attribute
+func (t *table) projectsAttribute(i *index, attribute string) bool {
+	if i.projectionType == dynamodb.ProjectionTypeAll {
+		return true
+	}
+	for _, key := range t.keyAttributes(i) {
+		if key == attribute {
+			return true
+		}
+	}
+	if i.projectionType == dynamodb.ProjectionTypeInclude {
+		for _, nonKeyAttribute := range i.nonKeyAttributes {
+			if nonKeyAttribute == attribute {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasKeyValue(value *dynamodb.AttributeValue, attributeType string) bool {
+	if value == nil {
+		return false
+	}
+	switch attributeType {
+	case dynamodb.ScalarAttributeTypeS:
+		return value.S != nil
+	case dynamodb.ScalarAttributeTypeN:
+		return value.N != nil
+	case dynamodb.ScalarAttributeTypeB:
+		return value.B != nil
+	}
+	return false
+}
+
+// keyString returns a string which uniquely identifies an item by the attributes
+func keyString(it item, attributes []string) string {
+	parts := []string{}
+	for _, attribute := range attributes {
+		if attribute != "" {
+			parts = append(parts, valueString(it[attribute]))
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// copyItem returns a deep copy of the item, so that items stored by the service are never shared
+// with callers
+func copyItem(it item) item {
+	copied := item{}
+	for attribute, value := range it {
+		copied[attribute] = copyValue(value)
+	}
+	return copied
+}
+
+func copyValue(value *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if value == nil {
+		return nil
+	}
+
+	copied := &dynamodb.AttributeValue{
+		S:    copyString(value.S),
+		N:    copyString(value.N),
+		BOOL: copyBool(value.BOOL),
+		NULL: copyBool(value.NULL),
+		SS:   copyStrings(value.SS),
+		NS:   copyStrings(value.NS),
+	}
+	if value.B != nil {
+		copied.B = append([]byte{}, value.B...)
+	}
+	for _, b := range value.BS {
+		copied.BS = append(copied.BS, append([]byte{}, b...))
+	}
+	if value.M != nil {
+		copied.M = copyItem(value.M)
+	}
+	if value.L != nil {
+		copied.L = []*dynamodb.AttributeValue{}
+		for _, element := range value.L {
+			copied.L = append(copied.L, copyValue(element))
+		}
+	}
+	return copied
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	return aws.String(*s)
+}
+
+func copyBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	return aws.Bool(*b)
+}
+
+func copyStrings(ss []*string) []*string {
+	if ss == nil {
+		return nil
+	}
+	copied := []*string{}
+	for _, s := range ss {
+		copied = append(copied, copyString(s))
+	}
+	return copied
+}
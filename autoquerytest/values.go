@@ -0,0 +1,98 @@
+package autoquerytest
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// compareValues compares two scalar values of the same type, returning false if the values are
+// missing, are not scalars, or are of different types
+func compareValues(a, b *dynamodb.AttributeValue) (int, bool) {
+	switch {
+	case a == nil || b == nil:
+		return 0, false
+	case a.S != nil && b.S != nil:
+		switch {
+		case *a.S < *b.S:
+			return -1, true
+		case *a.S > *b.S:
+			return 1, true
+		}
+		return 0, true
+	case a.N != nil && b.N != nil:
+		aNumber, aOk := new(big.Float).SetString(*a.N)
+		bNumber, bOk := new(big.Float).SetString(*b.N)
+		if !aOk || !bOk {
+			return 0, false
+		}
+		return aNumber.Cmp(bNumber), true
+	case a.B != nil && b.B != nil:
+		return bytes.Compare(a.B, b.B), true
+	}
+	return 0, false
+}
+
+// valuesEqual returns true if the values are of the same type and equal
+func valuesEqual(a, b *dynamodb.AttributeValue) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	if a == nil || b == nil || attributeType(a) != attributeType(b) {
+		return false
+	}
+	if attributeType(a) == dynamodb.ScalarAttributeTypeN {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// attributeType returns the DynamoDB type of the value, such as "S" or "M"
+func attributeType(value *dynamodb.AttributeValue) string {
+	switch {
+	case value == nil:
+		return ""
+	case value.S != nil:
+		return "S"
+	case value.N != nil:
+		return "N"
+	case value.B != nil:
+		return "B"
+	case value.BOOL != nil:
+		return "BOOL"
+	case value.NULL != nil:
+		return "NULL"
+	case value.SS != nil:
+		return "SS"
+	case value.NS != nil:
+		return "NS"
+	case value.BS != nil:
+		return "BS"
+	case value.L != nil:
+		return "L"
+	case value.M != nil:
+		return "M"
+	}
+	return ""
+}
+
+// valueString returns a string which uniquely identifies a key value
+func valueString(value *dynamodb.AttributeValue) string {
+	switch {
+	case value == nil:
+		return ""
+	case value.S != nil:
+		return "S:" + *value.S
+	case value.N != nil:
+		if number, ok := new(big.Float).SetString(*value.N); ok {
+			return "N:" + number.Text('g', -1)
+		}
+		return "N:" + *value.N
+	case value.B != nil:
+		return fmt.Sprintf("B:%x", value.B)
+	}
+	return fmt.Sprintf("%s:%v", attributeType(value), value)
+}
@@ -0,0 +1,62 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+)
+
+// pollExpression returns an expression for a dashboard-style poll of the latest items in a
+// partition
+func pollExpression() *Expression {
+	return NewExpression().Equal("pk", "a").GreaterThan("sk", 0).Select("pk", "sk")
+}
+
+// BenchmarkPollNewParser polls by constructing a new parser for each poll, so the query input is
+// constructed again on every poll.
+func BenchmarkPollNewParser(b *testing.B) {
+	client := NewClient(newFakeService(10))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseSortKeys(ctx, client.Query("T", pollExpression())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPollPlan polls by running a precomputed plan, which skips index selection but
+// constructs the query input on every poll.
+func BenchmarkPollPlan(b *testing.B) {
+	client := NewClient(newFakeService(10))
+	ctx := context.Background()
+	plan, err := client.Plan(ctx, "T", pollExpression())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseSortKeys(ctx, plan.Run(nil)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPollReset polls by resetting a single parser, which reuses the selected index and the
+// constructed query input for every poll.
+func BenchmarkPollReset(b *testing.B) {
+	client := NewClient(newFakeService(10))
+	ctx := context.Background()
+	parser := client.Query("T", pollExpression())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseSortKeys(ctx, parser.Reset()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
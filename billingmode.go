@@ -0,0 +1,73 @@
+package autoquery
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// CostWeights adjusts how index scoring weighs the cost of reading from an index under a table's
+// billing mode.
+type CostWeights struct {
+	// ProjectionPreference is the largest fraction by which a viable index's score is increased for
+	// projecting fewer attributes than the table, favoring indexes with smaller items to read.
+	ProjectionPreference float64
+
+	// CapacityPreference is the largest fraction by which a viable index's score is increased for
+	// having more provisioned read capacity than the table's other indexes, favoring indexes whose
+	// capacity is less contended. It has no effect on tables without provisioned read capacity.
+	CapacityPreference float64
+}
+
+// DefaultCostWeights are the cost weights used for each billing mode when Client.CostWeights has no
+// weights for the table's billing mode. Under on-demand billing, each read is paid for, so
+// narrower projections are preferred more strongly than under provisioned billing, where indexes
+// with more read capacity are also preferred. Tables whose description omits the billing mode use
+// the weights for provisioned billing.
+var DefaultCostWeights = map[string]CostWeights{
+	dynamodb.BillingModePayPerRequest: {ProjectionPreference: 0.5},
+	dynamodb.BillingModeProvisioned:   {ProjectionPreference: 0.2, CapacityPreference: 0.1},
+}
+
+// billingModeOf returns the billing mode of the table description, which is provisioned if not
+// reported
+func billingModeOf(table *dynamodb.TableDescription) string {
+	if table.BillingModeSummary == nil || table.BillingModeSummary.BillingMode == nil {
+		return dynamodb.BillingModeProvisioned
+	}
+	return aws.StringValue(table.BillingModeSummary.BillingMode)
+}
+
+// costWeights returns the cost weights for the billing mode
+func (client *Client) costWeights(billingMode string) CostWeights {
+	if weights, found := client.CostWeights[billingMode]; found {
+		return weights
+	}
+	return DefaultCostWeights[billingMode]
+}
+
+// readCapacityOf returns the provisioned read capacity units of the throughput description, or 0
+// if not provisioned
+func readCapacityOf(throughput *dynamodb.ProvisionedThroughputDescription) int64 {
+	if throughput == nil {
+		return 0
+	}
+	return aws.Int64Value(throughput.ReadCapacityUnits)
+}
+
+// setCapacityShares sets each index's share of read capacity relative to the index with the most
+// provisioned read capacity
+func (indexMetadata *tableIndexMetadata) setCapacityShares(readCapacities map[string]int64) {
+	maxReadCapacity := int64(0)
+	for _, readCapacity := range readCapacities {
+		if readCapacity > maxReadCapacity {
+			maxReadCapacity = readCapacity
+		}
+	}
+	if maxReadCapacity == 0 {
+		return
+	}
+
+	for _, index := range indexMetadata.Indexes {
+		index.CapacityShare = float64(readCapacities[index.Name]) / float64(maxReadCapacity)
+	}
+}
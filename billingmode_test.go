@@ -0,0 +1,77 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// newBillingModeService creates a fake service with two indexes on g and sk: wide, which projects
+// all attributes and has ten times the read capacity of narrow, which projects only v
+func newBillingModeService(billingMode string) *fakeService {
+	service := newFakeService(1)
+	service.table.BillingModeSummary = &dynamodb.BillingModeSummary{
+		BillingMode: aws.String(billingMode),
+	}
+	if billingMode == dynamodb.BillingModeProvisioned {
+		service.table.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits: aws.Int64(10),
+		}
+	}
+	service.table.GlobalSecondaryIndexes = []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName:  aws.String("wide"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", "sk"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits: aws.Int64(100),
+			},
+		},
+		{
+			IndexName: aws.String("narrow"),
+			ItemCount: aws.Int64(100),
+			KeySchema: keySchema("g", "sk"),
+			Projection: &dynamodb.Projection{
+				ProjectionType:   aws.String("INCLUDE"),
+				NonKeyAttributes: []*string{aws.String("v")},
+			},
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits: aws.Int64(10),
+			},
+		},
+	}
+	return service
+}
+
+func TestBillingModeCostWeights(t *testing.T) {
+	testCases := []struct {
+		billingMode   string
+		expectedIndex string
+	}{
+		{dynamodb.BillingModePayPerRequest, "narrow"},
+		{dynamodb.BillingModeProvisioned, "wide"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.billingMode, func(t *testing.T) {
+			client := NewClient(newBillingModeService(tc.billingMode))
+			client.DisableSparsenessInference = true
+			client.CostWeights = map[string]CostWeights{
+				dynamodb.BillingModePayPerRequest: {ProjectionPreference: 0.5},
+				dynamodb.BillingModeProvisioned:   {CapacityPreference: 0.5},
+			}
+
+			expr := NewExpression().Equal("g", "x").Select("v")
+			indexName, err := client.Query("T", expr).SelectedIndexName(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if indexName != tc.expectedIndex {
+				t.Errorf("expected index %s, got %s", tc.expectedIndex, indexName)
+			}
+		})
+	}
+}
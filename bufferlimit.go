@@ -0,0 +1,57 @@
+package autoquery
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SetMaxBufferedItems limits the number of items the parser holds in memory ahead of the caller to
+// n, protecting memory when items are consumed slowly. The limit of each page query call to
+// DynamoDB is reduced so that fetched items never exceed the available buffer space, and when
+// prefetch is enabled, background fetching pauses while the buffer is full and resumes as Next
+// returns items. If n is 0 or less, buffered items are only limited by page size and prefetch.
+//
+// The bound applies to Query pages. PartiQL pages and the sub-range queries of ParallelRange are
+// not limited, though prefetching of PartiQL pages still pauses while the buffer is full.
+// SetMaxBufferedItems should be called before the first call to Next.
+func (parser *Parser) SetMaxBufferedItems(n int) *Parser {
+	parser.maxBufferedItems = n
+	return parser
+}
+
+// bufferSpace returns the number of items which may be fetched without exceeding the parser's max
+// buffered items
+func (parser *Parser) bufferSpace() int {
+	return parser.maxBufferedItems - int(atomic.LoadInt32(&parser.bufferedItemCount))
+}
+
+// holdBufferedItems records items fetched into the parser's buffer
+func (parser *Parser) holdBufferedItems(n int) {
+	if parser.maxBufferedItems > 0 {
+		atomic.AddInt32(&parser.bufferedItemCount, int32(n))
+	}
+}
+
+// releaseBufferedItem records an item returned by Next, signaling any paused prefetch
+func (parser *Parser) releaseBufferedItem() {
+	if parser.maxBufferedItems <= 0 {
+		return
+	}
+	atomic.AddInt32(&parser.bufferedItemCount, -1)
+	select {
+	case parser.bufferReleased <- struct{}{}:
+	default:
+	}
+}
+
+// waitForBufferSpace blocks until the parser's buffer has space for at least one item
+func (parser *Parser) waitForBufferSpace(ctx context.Context) error {
+	for parser.maxBufferedItems > 0 && parser.bufferSpace() <= 0 {
+		select {
+		case <-parser.bufferReleased:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package autoquery
+
+// CacheStats contains statistics for a client's table metadata cache.
+type CacheStats struct {
+	// Hits is the number of metadata lookups served from the cache.
+	Hits int
+
+	// Misses is the number of metadata lookups which required the metadata provider.
+	Misses int
+
+	// Entries is the number of tables currently cached.
+	Entries int
+
+	// Evictions is the number of cache entries which have been removed or replaced.
+	Evictions int
+}
+
+// CacheStats returns statistics for the client's table metadata cache.
+func (client *Client) CacheStats() CacheStats {
+	client.cacheStatsMutex.Lock()
+	defer client.cacheStatsMutex.Unlock()
+
+	return client.cacheStats
+}
+
+func (client *Client) recordCacheLookup(hit bool) {
+	client.cacheStatsMutex.Lock()
+	defer client.cacheStatsMutex.Unlock()
+
+	if hit {
+		client.cacheStats.Hits++
+	} else {
+		client.cacheStats.Misses++
+	}
+}
+
+func (client *Client) recordCacheEntries(entries int) {
+	client.cacheStatsMutex.Lock()
+	defer client.cacheStatsMutex.Unlock()
+
+	client.cacheStats.Entries = entries
+}
+
+func (client *Client) recordCacheEviction() {
+	client.cacheStatsMutex.Lock()
+	defer client.cacheStatsMutex.Unlock()
+
+	client.cacheStats.Evictions++
+}
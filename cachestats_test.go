@@ -0,0 +1,39 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCacheStats(t *testing.T) {
+	service := newFakeService(1)
+	client := NewClient(service)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Query("T", NewExpression().Equal("pk", "a")).Prepare(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := client.CacheStats()
+	if stats.Misses != 1 || stats.Hits != 1 {
+		t.Errorf("expected cold query to miss and warm query to hit, got %d misses and %d hits",
+			stats.Misses, stats.Hits)
+	}
+	if stats.Entries != 1 || stats.Evictions != 0 {
+		t.Errorf("expected 1 entry and 0 evictions, got %d entries and %d evictions",
+			stats.Entries, stats.Evictions)
+	}
+
+	// refreshing replaces the cached entry
+	if err := client.RefreshTableMetadata(ctx, "T"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats = client.CacheStats()
+	if stats.Entries != 1 || stats.Evictions != 1 {
+		t.Errorf("expected 1 entry and 1 eviction after refresh, got %d entries and %d evictions",
+			stats.Entries, stats.Evictions)
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -15,12 +18,32 @@ import (
 
 // Client is a querying client for DynamoDB that enables automatic index selection.
 // The client caches table metadata to optimize calls on previously-queried tables.
+//
+// A Client is safe for concurrent use by multiple goroutines once its fields are set. Concurrent
+// queries on a table which is not yet cached share a single retrieval of the table's metadata.
 type Client struct {
 	dynamodbService dynamodbiface.DynamoDBAPI
 
 	metadataProvider TableDescriptionProvider
 
+	// metadataMutex guards the metadata cache and in-flight metadata fetches, which are keyed by
+	// metadata cache key
+	metadataMutex           sync.RWMutex
 	tableIndexMetadataCache map[string]*tableIndexMetadata
+	metadataFetches         map[string]*metadataFetch
+
+	cacheStatsMutex sync.Mutex
+	cacheStats      CacheStats
+
+	registeredTypesMutex sync.Mutex
+	registeredTypes      map[string][]string
+	registeredEntities   map[string]map[string][]string
+
+	indexUsageMutex  sync.Mutex
+	indexUsageCounts map[string]map[string]int
+
+	indexBackfillMutex sync.Mutex
+	indexBackfillTimes map[string]map[string]time.Time
 
 	// SecondaryIndexSparsenessThreshold sets the threshold for secondary indexes to be considered
 	// sparse vs non-sparse.
@@ -46,11 +69,131 @@ type Client struct {
 	// By default, all secondary indexes are considered sparse. If non-default behavior is
 	// desired, this value should be set before any queries are parsed with Parser.Next.
 	SecondaryIndexSparsenessThreshold float64
+
+	// SparsenessGracePeriod, if greater than 0, causes global secondary indexes to be considered
+	// non-sparse while they are being created or backfilled, as reported by their IndexStatus and
+	// Backfilling state, and for the grace period after the client last retrieved metadata in which
+	// they were. Item counts of a newly created global secondary index lag behind the table's item
+	// count while the index is populated, which can otherwise make a dense index appear sparse. The
+	// grace period is evaluated at index selection, so an index becomes subject to sparseness
+	// inference once its grace period elapses, without retrieving the table's metadata again.
+	// Indexes which are already active when the client first retrieves the table's metadata have no
+	// grace period.
+	SparsenessGracePeriod time.Duration
+
+	// DisableSparsenessInference, if true, causes every secondary index to be considered
+	// non-sparse with the same sparsity as the table, regardless of item counts, so that index
+	// selection depends only on index keys, projections, and expression conditions. This is
+	// intended for testing against DynamoDB Local, which reports an item count of 0 for secondary
+	// indexes. Without it, zero-count indexes are strongly preferred and indexes considered sparse
+	// require sort key conditions, so selection against DynamoDB Local can differ from production.
+	DisableSparsenessInference bool
+
+	// IndexDensityHint, if set, is called for each secondary index when indexes are selected or
+	// described. If ok is true, the index is considered non-sparse if dense is true and sparse
+	// otherwise, regardless of SecondaryIndexSparsenessThreshold and SparsenessGracePeriod.
+	IndexDensityHint func(tableName, indexName string) (dense, ok bool)
+
+	// ReturnConsumedCapacity sets the default ReturnConsumedCapacity parameter for query calls made
+	// by parsers created through the client. Valid values are the dynamodb.ReturnConsumedCapacity
+	// enum values. Expressions may override the default with Expression.ReturnConsumedCapacity.
+	//
+	// By default, this value is empty and consumed capacity is not requested.
+	ReturnConsumedCapacity string
+
+	// OverloadedIndexSelected, if set, is called when a query selects a global secondary index that
+	// appears to be overloaded with multiple entity types and the expression has no condition on
+	// the index's sort key to discriminate between them. An index is considered overloaded when it
+	// has a sort key and all of its key attributes have generic names such as GSI1PK and GSI1SK.
+	//
+	// Queries on such an index proceed as usual, but may return items of unexpected entity types.
+	OverloadedIndexSelected func(tableName, indexName string)
+
+	// ExpensiveQuery, if set, is called when a query's only key condition is the partition key
+	// equality while the remaining narrowing is applied as filter conditions, so the query reads
+	// the entire partition and discards non-matching items after they are read. The callback
+	// receives the selected index and its item count as an upper-bound estimate of the items read.
+	//
+	// The callback is only called when the estimate is at least ExpensiveQueryThreshold.
+	ExpensiveQuery func(tableName, indexName string, estimatedItemsRead int)
+
+	// ExpensiveQueryThreshold sets the minimum index item count for which ExpensiveQuery is called.
+	ExpensiveQueryThreshold int
+
+	// IndexNotViable, if set, is called when index selection fails for a query, once for each
+	// reason that each index is not viable for the expression. Reasons may be aggregated across an
+	// application to find common expression or schema mismatches.
+	IndexNotViable func(tableName, indexName, reason string)
+
+	// ConsistencyPolicy determines how expressions which specify consistent read are handled when
+	// only global secondary indexes are viable. By default, StrictReject is used.
+	ConsistencyPolicy ConsistencyPolicy
+
+	// ConsistentReadDowngraded, if set, is called when a consistent read expression is queried on
+	// a global secondary index with eventually consistent reads under the AutoDowngrade policy.
+	ConsistentReadDowngraded func(tableName, indexName string)
+
+	// RedundantIndex, if set, is called when a table's metadata is retrieved, once for each
+	// secondary index with the same partition and sort keys as another index of the same kind,
+	// which is named by sameKeysAs. Such indexes may differ only in projection, or may be
+	// unintended duplicates in a static table description.
+	RedundantIndex func(tableName, indexName, sameKeysAs string)
+
+	// CostWeights overrides the cost weights of index scoring for tables with each billing mode,
+	// keyed by dynamodb.BillingModePayPerRequest or dynamodb.BillingModeProvisioned. Billing modes
+	// without weights use DefaultCostWeights.
+	CostWeights map[string]CostWeights
+
+	// QueryFunc, if set, is used for Query calls instead of the DynamoDB service. This may be used
+	// to wrap query calls with instrumentation, retries, or fault injection.
+	QueryFunc QueryFunc
+
+	// DescribeTableFunc, if set, is used for DescribeTable calls made by the default metadata
+	// provider instead of the DynamoDB service. It has no effect on clients created with
+	// NewClientWithMetadataProvider.
+	DescribeTableFunc DescribeTableFunc
+
+	// IndexSelector sets the policy used to select an index from the scored candidates for each
+	// query. If nil, DefaultIndexSelector is used.
+	IndexSelector IndexSelector
+
+	// PrimaryIndexLabel, if set, identifies the table's primary index in index reporting in place
+	// of PrimaryIndexName, such as in DescribeIndexes, IndexUsageStats, query plans and
+	// explanations, and the names passed to the client's selection hooks. For example, it may be
+	// set to the table name for metrics pipelines which label queries on the base table that way.
+	// The label may also be passed to Expression.RestrictToIndexes. It is never sent to DynamoDB.
+	PrimaryIndexLabel string
+
+	// MetadataCache, if set, is checked for a table's description before the metadata provider is
+	// called, and descriptions retrieved from the provider are stored in it. Clients which share a
+	// MetadataCache describe each table only once between them. Each client still parses and caches
+	// the table's index metadata with its own settings.
+	MetadataCache MetadataCache
+
+	// MetadataTTL, if greater than 0, sets the duration for which a table's cached metadata is
+	// used before it is retrieved again, so that item counts and new or removed indexes are
+	// eventually observed by long-lived clients. Metadata refreshed after expiring bypasses the
+	// shared MetadataCache and replaces its entry. By default, metadata is cached indefinitely.
+	MetadataTTL time.Duration
+
+	// PerCallTimeoutRetries sets the number of times a DynamoDB call which exceeds an expression's
+	// per-call timeout is retried before an ErrCallTimeout error is returned. By default, calls
+	// which time out are not retried.
+	PerCallTimeoutRetries int
+
+	// DisallowScan, if true, causes queries which would read an entire partition of the selected
+	// index, because the expression has filter conditions but no condition on the index's sort
+	// key, to fail with an ErrPartitionScan error instead of being queried. The client never
+	// issues Scan calls, so such partition-wide reads are the closest to a full scan. This is
+	// intended for tests which assert that access patterns remain served by index keys.
+	DisallowScan bool
 }
 
 // NewClient creates a new Client instance.
 func NewClient(service dynamodbiface.DynamoDBAPI) *Client {
-	return NewClientWithMetadataProvider(service, newDefaultDescriptionProvider(service))
+	client := NewClientWithMetadataProvider(service, nil)
+	client.metadataProvider = newDefaultDescriptionProvider(client.describeTable)
+	return client
 }
 
 // NewClientWithMetadataProvider creates a new Client instance with a specified metadata provider.
@@ -60,13 +203,20 @@ func NewClient(service dynamodbiface.DynamoDBAPI) *Client {
 // as the metadata provider.
 //
 // An alternative TableDescriptionProvider may be needed in cases where the table cannot be
-// described using DescribeTable.
+// described using DescribeTable. Queries are still made through service. Descriptions returned by
+// the provider may omit item counts, such as for static descriptions; a missing table item count
+// is treated as an empty table, and a missing index item count as the table's item count.
 func NewClientWithMetadataProvider(
 	service dynamodbiface.DynamoDBAPI, provider TableDescriptionProvider) *Client {
 	return &Client{
 		dynamodbService:         service,
 		metadataProvider:        provider,
 		tableIndexMetadataCache: map[string]*tableIndexMetadata{},
+		metadataFetches:         map[string]*metadataFetch{},
+		indexUsageCounts:        map[string]map[string]int{},
+		indexBackfillTimes:      map[string]map[string]time.Time{},
+		registeredTypes:         map[string][]string{},
+		registeredEntities:      map[string]map[string][]string{},
 		// by default, all secondary indexes are considered sparse
 		SecondaryIndexSparsenessThreshold: 1.1,
 	}
@@ -89,7 +239,7 @@ func (client *Client) Get(ctx context.Context, tableName string, itemKey,
 	response, err := client.dynamodbService.GetItemWithContext(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(tableName),
 		Key:       key,
-	})
+	}, requestOptionsFromContext(ctx)...)
 	if err != nil {
 		return err
 	}
@@ -112,7 +262,7 @@ func (client *Client) Put(ctx context.Context, tableName string, item interface{
 	_, err = client.dynamodbService.PutItemWithContext(ctx, &dynamodb.PutItemInput{
 		TableName: aws.String(tableName),
 		Item:      tableItem,
-	})
+	}, requestOptionsFromContext(ctx)...)
 
 	return err
 }
@@ -124,6 +274,10 @@ func (client *Client) Put(ctx context.Context, tableName string, item interface{
 // the underlying metadata provider. The metadata is cached for subsequent queries to the table
 // through the same Client instance. The query automatically selects an index based on the table
 // metadata and any expression restrictions.
+//
+// Query makes no service calls; the table's metadata is retrieved and the index is selected on the
+// first call to Parser.Next, so many parsers may be constructed without retrieving metadata until
+// each is iterated. Parser.Prepare may be used to select the index before iterating.
 func (client *Client) Query(tableName string, expr *Expression) *Parser {
 	return &Parser{
 		client:        client,
@@ -133,35 +287,220 @@ func (client *Client) Query(tableName string, expr *Expression) *Parser {
 	}
 }
 
+// SelectIndexFor selects the best index for a query input that has been constructed outside of
+// autoquery. The input's KeyConditionExpression, ProjectionExpression, and ConsistentRead
+// parameters are used to derive an expression, which is scored against the table's indexes in the
+// same way as expressions passed to Query.
+//
+// The returned name should be set as the input's IndexName. If the table's primary index is
+// selected, the returned name is empty and IndexName should be left unset.
+func (client *Client) SelectIndexFor(
+	ctx context.Context, tableName string, input *dynamodb.QueryInput) (string, error) {
+
+	expr, err := expressionFromQueryInput(input)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := client.chooseIndex(ctx, tableName, expr)
+	if err != nil {
+		return "", err
+	}
+
+	if index.Name == tablePrimaryIndexName {
+		return "", nil
+	}
+	return index.Name, nil
+}
+
+// IndexesFor returns the names of all indexes on the table which use partitionKey as their
+// partition key, in the order primary index, global secondary indexes, then local secondary
+// indexes. The table's primary index is identified by PrimaryIndexName, or by PrimaryIndexLabel if
+// set.
+//
+// The table's index metadata is retrieved using the underlying metadata provider if it is not
+// already cached.
+func (client *Client) IndexesFor(
+	ctx context.Context, tableName, partitionKey string) ([]string, error) {
+
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	indexNames := []string{}
+	for _, index := range indexMetadata.Indexes {
+		if index.PartitionKey == partitionKey {
+			indexNames = append(indexNames, client.indexLabel(index.Name))
+		}
+	}
+
+	return indexNames, nil
+}
+
+// DescribeIndexes returns descriptions of all indexes on the table as they are considered for
+// index selection. The primary index is always listed first, followed by global secondary indexes
+// and then local secondary indexes, each ordered by name.
+//
+// The table's index metadata is retrieved using the underlying metadata provider if it is not
+// already cached.
+func (client *Client) DescribeIndexes(
+	ctx context.Context, tableName string) ([]IndexDescription, error) {
+
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	indexMetadata = client.applyDensityOverrides(ctx, tableName, indexMetadata)
+
+	descriptions := []IndexDescription{}
+	for _, index := range indexMetadata.Indexes {
+		descriptions = append(descriptions, newIndexDescription(index))
+	}
+	client.labelIndexDescriptions(descriptions)
+
+	return descriptions, nil
+}
+
+// IndexUsageStats returns the number of times each index on the table has been selected for a
+// query through the client, keyed by index name. The table's primary index is identified by
+// PrimaryIndexName, or by PrimaryIndexLabel if set. Indexes which have never been selected are not
+// included.
+//
+// Usage stats may be used to identify unused indexes or heavily used indexes. If the client's
+// metadata provider is a ScopedDescriptionProvider, usage is counted separately for each scope,
+// and the stats returned are those of the scope of ctx.
+func (client *Client) IndexUsageStats(ctx context.Context, tableName string) map[string]int {
+	client.indexUsageMutex.Lock()
+	defer client.indexUsageMutex.Unlock()
+
+	stats := map[string]int{}
+	for indexName, count := range client.indexUsageCounts[client.metadataCacheKey(ctx, tableName)] {
+		stats[client.indexLabel(indexName)] = count
+	}
+
+	return stats
+}
+
+// indexLabel returns the name that identifies the index in index reporting
+func (client *Client) indexLabel(indexName string) string {
+	if indexName == tablePrimaryIndexName && client.PrimaryIndexLabel != "" {
+		return client.PrimaryIndexLabel
+	}
+	return indexName
+}
+
+// labelIndexDescriptions replaces the primary index's name in descriptions with its label
+func (client *Client) labelIndexDescriptions(descriptions []IndexDescription) {
+	for i := range descriptions {
+		descriptions[i].Name = client.indexLabel(descriptions[i].Name)
+	}
+}
+
+func (client *Client) recordIndexUsage(cacheKey, indexName string) {
+	client.indexUsageMutex.Lock()
+	defer client.indexUsageMutex.Unlock()
+
+	counts, found := client.indexUsageCounts[cacheKey]
+	if !found {
+		counts = map[string]int{}
+		client.indexUsageCounts[cacheKey] = counts
+	}
+	counts[indexName]++
+}
+
+// Validate checks that the expression can be queried on the table without executing a query. It
+// retrieves the table's index metadata and selects an index for the expression, returning nil if
+// a viable index is selected. If no indexes are viable, an ErrNoViableIndexes error is returned
+// with the reasons why each index is considered non-viable.
+//
+// Validate does not count toward IndexUsageStats and does not call the client's selection hooks.
+func (client *Client) Validate(ctx context.Context, tableName string, expr *Expression) error {
+	expr = client.applyRegisteredType(client.metadataCacheKey(ctx, tableName), tableName, expr)
+	_, _, err := client.selectIndex(ctx, tableName, expr)
+	return err
+}
+
+// Latest retrieves the item with the highest sort key value that matches the expression. The item
+// is returned in returnItem, which should have dynamodbav attribute tags pertaining to the desired
+// return attributes in the table. Only indexes with a sort key are viable for the expression.
+//
+// If no items match the expression, an *ErrNoItems instance is returned.
+func (client *Client) Latest(ctx context.Context, tableName string, expr *Expression,
+	returnItem interface{}) error {
+
+	latestExpr := *expr
+	latestExpr.latest = true
+
+	err := client.Query(tableName, &latestExpr).SetTotalLimit(1).Next(ctx, returnItem)
+	if _, isComplete := err.(*ErrParsingComplete); isComplete {
+		return &ErrNoItems{}
+	}
+
+	return err
+}
+
 func (client *Client) pullIndexMetadata(
 	ctx context.Context, tableName string) (*tableIndexMetadata, error) {
 
-	indexMetadata, found := client.tableIndexMetadataCache[tableName]
-	if !found {
-		// attempt to pull table description from metadata provider
-		tableDescription, err := client.metadataProvider.Get(ctx, tableName)
-		if err != nil {
-			return nil, err
-		}
-		indexMetadata = client.parseTableIndexMetadata(tableDescription)
-		// add metadata to cache
-		client.tableIndexMetadataCache[tableName] = indexMetadata
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+	indexMetadata, found := client.cachedIndexMetadata(cacheKey)
+	stale := found && client.metadataStale(indexMetadata)
+	client.recordCacheLookup(found && !stale)
+	if !found || stale {
+		return client.fetchIndexMetadata(ctx, tableName, stale)
 	}
 
 	return indexMetadata, nil
 }
 
-func (client *Client) parseTableIndexMetadata(table *dynamodb.TableDescription) *tableIndexMetadata {
+// loadIndexMetadata retrieves the table's description, parses its index metadata, and adds it to
+// the client's cache. If refresh is true, the shared metadata cache is bypassed and updated.
+func (client *Client) loadIndexMetadata(
+	ctx context.Context, tableName string, refresh bool) (*tableIndexMetadata, error) {
+
+	// attempt to pull table description from shared cache or metadata provider
+	tableDescription, err := client.describeTableThroughCache(ctx, tableName, refresh)
+	if isAccessDenied(err) {
+		return nil, &ErrDescribeTableAccessDenied{TableName: tableName, Err: err}
+	} else if err != nil {
+		return nil, err
+	}
+	indexMetadata, err := client.parseTableIndexMetadata(tableName, tableDescription)
+	if err != nil {
+		return nil, err
+	}
+	indexMetadata.TableName = tableName
+	indexMetadata.Scope = client.metadataScope(ctx)
+	indexMetadata.FetchedAt = time.Now()
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+	client.recordBackfillingIndexes(cacheKey, indexMetadata)
+
+	client.cacheIndexMetadata(cacheKey, indexMetadata)
+
+	return indexMetadata, nil
+}
+
+func (client *Client) parseTableIndexMetadata(
+	tableName string, table *dynamodb.TableDescription) (*tableIndexMetadata, error) {
+
 	output := &tableIndexMetadata{
-		Indexes: []*tableIndex{},
+		Indexes:     []*tableIndex{},
+		BillingMode: billingModeOf(table),
 	}
 
+	// local secondary indexes share the table's read capacity
+	tableReadCapacity := readCapacityOf(table.ProvisionedThroughput)
+	readCapacities := map[string]int64{tablePrimaryIndexName: tableReadCapacity}
+
 	appendIndex := func(index *tableIndex) {
 		output.Indexes = append(output.Indexes, index)
 	}
 
-	// extract primary key index
-	tableSize := int(*table.ItemCount)
+	// extract primary key index; descriptions from static metadata providers may omit item counts,
+	// in which case the table is treated as empty and secondary indexes as the same size as the
+	// table
+	tableSize := int(aws.Int64Value(table.ItemCount))
 	tablePrimaryIndex := &tableIndex{
 		Name:                  tablePrimaryIndexName,
 		Size:                  tableSize,
@@ -172,21 +511,28 @@ func (client *Client) parseTableIndexMetadata(table *dynamodb.TableDescription)
 		SparsityMultiplier:    1.0,
 	}
 	tablePrimaryIndex.loadKeysFromSchema(table.KeySchema)
+	tablePrimaryIndex.loadKeyTypesFromDefinitions(table.AttributeDefinitions)
 	appendIndex(tablePrimaryIndex)
 
 	tablePrimaryIndexKeys := tablePrimaryIndex.getKeys()
+	tablePrimaryIndex.TableKeys = tablePrimaryIndexKeys
 
 	// extract global secondary indexes
 	if table.GlobalSecondaryIndexes != nil {
 		for _, gsi := range table.GlobalSecondaryIndexes {
 			index := &tableIndex{
 				Name: *gsi.IndexName,
-				Size: int(*gsi.ItemCount),
+				Size: itemCountOrDefault(gsi.ItemCount, tableSize),
 				// global secondary indexes do not support consistent read
 				ConsistentReadable: false,
+				IsGlobal:           true,
+				TableKeys:          tablePrimaryIndexKeys,
 			}
 			index.loadKeysFromSchema(gsi.KeySchema)
+			index.loadKeyTypesFromDefinitions(table.AttributeDefinitions)
 			index.loadAttributesFromProjection(gsi.Projection, tablePrimaryIndexKeys)
+			readCapacities[index.Name] = readCapacityOf(gsi.ProvisionedThroughput)
+			index.Backfilling = isBackfilling(gsi)
 			index.inferSparseness(tablePrimaryIndex, client.SecondaryIndexSparsenessThreshold)
 			appendIndex(index)
 		}
@@ -197,54 +543,195 @@ func (client *Client) parseTableIndexMetadata(table *dynamodb.TableDescription)
 		for _, lsi := range table.LocalSecondaryIndexes {
 			index := &tableIndex{
 				Name:               *lsi.IndexName,
-				Size:               int(*lsi.ItemCount),
+				Size:               itemCountOrDefault(lsi.ItemCount, tableSize),
 				ConsistentReadable: true,
 				IsSparse:           true,
+				TableKeys:          tablePrimaryIndexKeys,
 			}
 			index.loadKeysFromSchema(lsi.KeySchema)
+			index.loadKeyTypesFromDefinitions(table.AttributeDefinitions)
 			index.loadAttributesFromProjection(lsi.Projection, tablePrimaryIndexKeys)
+			readCapacities[index.Name] = tableReadCapacity
 			index.inferSparseness(tablePrimaryIndex, client.SecondaryIndexSparsenessThreshold)
 			appendIndex(index)
 		}
 	}
 
-	return output
+	if err := client.validateIndexes(tableName, output.Indexes); err != nil {
+		return nil, err
+	}
+
+	output.setCapacityShares(readCapacities)
+	output.sortIndexes()
+
+	return output, nil
 }
 
 func (client *Client) chooseIndex(ctx context.Context,
 	tableName string, expr *Expression) (*tableIndex, error) {
 
-	// pull metadata from cache
-	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	bestIndex, downgraded, err := client.selectIndex(ctx, tableName, expr)
 	if err != nil {
+		client.reportIndexNotViableReasons(tableName, err)
 		return nil, err
 	}
+	expr = expr.boundToIndex(bestIndex)
 
-	var bestIndex *tableIndex
-	bestIndexScore := 0.0
+	if err := client.checkAccessPattern(tableName, expr, bestIndex); err != nil {
+		return nil, err
+	}
 
-	// select index with best score based on the expression
-	inviableErrs := []*ErrIndexNotViable{}
-	for _, index := range indexMetadata.Indexes {
-		indexScore, inviableErr := client.scoreIndexOnExpr(index, expr)
-		if inviableErr != nil {
-			inviableErrs = append(inviableErrs, inviableErr)
-		} else if indexScore > bestIndexScore {
-			bestIndex = index
-			bestIndexScore = indexScore
+	client.recordIndexUsage(client.metadataCacheKey(ctx, tableName), bestIndex.Name)
+
+	if downgraded && client.ConsistentReadDowngraded != nil {
+		client.ConsistentReadDowngraded(tableName, client.indexLabel(bestIndex.Name))
+	}
+
+	// warn if filter conditions do all of the narrowing within the partition
+	if client.ExpensiveQuery != nil && bestIndex.Size >= client.ExpensiveQueryThreshold &&
+		expr.onlyFiltersWithinPartition(bestIndex) {
+		client.ExpensiveQuery(tableName, client.indexLabel(bestIndex.Name), bestIndex.Size)
+	}
+
+	// warn if an overloaded index is selected without a sort key discriminator
+	if client.OverloadedIndexSelected != nil && bestIndex.appearsOverloaded() {
+		if _, found := expr.filters[bestIndex.SortKey]; !found {
+			client.OverloadedIndexSelected(tableName, client.indexLabel(bestIndex.Name))
+		}
+	}
+
+	return bestIndex, nil
+}
+
+// reportIndexNotViableReasons calls the IndexNotViable hook with each reason in a selection error
+func (client *Client) reportIndexNotViableReasons(tableName string, err error) {
+	if client.IndexNotViable == nil {
+		return
+	}
+
+	var indexErrs []*ErrIndexNotViable
+	switch e := err.(type) {
+	case *ErrNoViableIndexes:
+		indexErrs = e.IndexErrs
+	case *ErrIndexNotViable:
+		indexErrs = []*ErrIndexNotViable{e}
+	}
+
+	for _, indexErr := range indexErrs {
+		for _, reason := range indexErr.NotViableReasons {
+			client.IndexNotViable(tableName, client.indexLabel(indexErr.IndexName), reason)
 		}
 	}
+}
 
-	// no viable indexes found
+// selectIndex selects the index for an expression without recording usage or calling hooks, and
+// returns whether consistent read was downgraded by the client's consistency policy
+func (client *Client) selectIndex(ctx context.Context,
+	tableName string, expr *Expression) (*tableIndex, bool, error) {
+
+	ctx = withPerCallTimeout(ctx, expr.perCallTimeout)
+	indexMetadata, candidates, downgraded, err := client.indexCandidates(ctx, tableName, expr)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// select index using the configured selector
+	var selector IndexSelector = DefaultIndexSelector{}
+	if client.IndexSelector != nil {
+		selector = client.IndexSelector
+	}
+	selectedIndexName, err := selector.Select(candidates)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var bestIndex *tableIndex
+	for i, index := range indexMetadata.Indexes {
+		if index.Name == selectedIndexName {
+			if candidates[i].NotViableErr != nil {
+				return nil, false, candidates[i].NotViableErr
+			}
+			bestIndex = index
+		}
+	}
 	if bestIndex == nil {
-		return nil, &ErrNoViableIndexes{IndexErrs: inviableErrs}
+		return nil, false, fmt.Errorf("selected index not found on table: %s", selectedIndexName)
 	}
 
-	return bestIndex, nil
+	return bestIndex, downgraded, nil
+}
+
+// indexCandidates scores each index of the table on the expression, returning true if consistent
+// read was downgraded to find a viable index
+func (client *Client) indexCandidates(ctx context.Context, tableName string,
+	expr *Expression) (*tableIndexMetadata, []IndexCandidate, bool, error) {
+
+	if err := expr.error(); err != nil {
+		return nil, nil, false, err
+	}
+
+	// pull metadata from cache
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	indexMetadata = client.applyDensityOverrides(ctx, tableName, indexMetadata)
+
+	// score each index based on the expression
+	candidates := client.scoreIndexes(indexMetadata, expr)
+
+	// under the auto-downgrade policy, fall back to eventually consistent reads if no index is
+	// viable with consistent read
+	downgraded := false
+	if expr.consistentRead && client.ConsistencyPolicy == AutoDowngrade &&
+		!anyCandidateViable(candidates) {
+		eventualExpr := *expr
+		eventualExpr.consistentRead = false
+		candidates = client.scoreIndexes(indexMetadata, &eventualExpr)
+		downgraded = true
+	}
+
+	return indexMetadata, candidates, downgraded, nil
+}
+
+func (client *Client) scoreIndexes(
+	indexMetadata *tableIndexMetadata, expr *Expression) []IndexCandidate {
+
+	tableAttributeCount := indexMetadata.tableAttributeCount(expr)
+	weights := client.costWeights(indexMetadata.BillingMode)
+
+	candidates := []IndexCandidate{}
+	for _, index := range indexMetadata.Indexes {
+		indexExpr := expr.boundToIndex(index)
+		indexScore, inviableErr := client.scoreIndexOnExpr(
+			index, indexExpr, tableAttributeCount, weights)
+		candidate := IndexCandidate{
+			IndexDescription:  newIndexDescription(index),
+			Score:             indexScore,
+			EstimatedReadCost: estimateReadCost(index, indexScore, tableAttributeCount),
+			NotViableErr:      inviableErr,
+		}
+		if inviableErr == nil && indexExpr.fetchesMissingAttributes(index) {
+			candidate.FetchesMissingAttributes = true
+			candidate.EstimatedReadCost += estimateFetchCost(index, indexScore)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates
+}
+
+func anyCandidateViable(candidates []IndexCandidate) bool {
+	for _, candidate := range candidates {
+		if candidate.NotViableErr == nil {
+			return true
+		}
+	}
+	return false
 }
 
-func (client *Client) scoreIndexOnExpr(
-	index *tableIndex, expr *Expression) (float64, *ErrIndexNotViable) {
+func (client *Client) scoreIndexOnExpr(index *tableIndex, expr *Expression,
+	tableAttributeCount int, weights CostWeights) (float64, *ErrIndexNotViable) {
 
 	indexNotViableReasons := client.listIndexViabilityInfractions(index, expr)
 	if len(indexNotViableReasons) > 0 {
@@ -264,9 +751,6 @@ func (client *Client) scoreIndexOnExpr(
 	if index.HasMaxSparsityMultiplier {
 		// if index is viable and has zero sparsity, then it suggests the expression has zero
 		// result items.
-		// TODO: if index starts out with zero items but gains items over a Client instance's
-		// lifetime, then the index size metadata will become outdated and may lead to non-optimal
-		// index selection. Consider metadata cache invalidation after some time.
 		return math.MaxFloat64, nil
 	}
 
@@ -278,6 +762,7 @@ func (client *Client) scoreIndexOnExpr(
 		reflect.TypeOf(&equalsFilter{}):     2.5, // equals filter is 2.5x preferred
 		reflect.TypeOf(&betweenFilter{}):    1.8, // between filter is 1.8x preferred
 		reflect.TypeOf(&beginsWithFilter{}): 1.5, // prefix filter is 1.5x preferred
+		reflect.TypeOf(&existsFilter{}):     0.2, // exists filter does not narrow the partition
 		reflect.TypeOf(nil):                 0.2, // no filter on sort key is not preferable
 	}
 	var exprSortKeyFilter conditionFilter = nil
@@ -289,7 +774,22 @@ func (client *Client) scoreIndexOnExpr(
 		sortKeyFilterTypeScore = defaultFilterTypeScore
 	}
 
-	indexScore := index.SparsityMultiplier * sortKeyFilterTypeScore
+	// Viable indexes which project fewer attributes than the table read smaller items, so a
+	// covering index with a narrow projection is preferred over an all-projecting index with an
+	// otherwise equal score. The preference is weighted by the table's billing mode and is small
+	// enough by default that better key conditions still win. Indexes whose missing attributes are
+	// fetched from the table return full items, so they are not preferred.
+	projectionScore := 1.0
+	if !expr.fetchesMissingAttributes(index) {
+		projectionScore += weights.ProjectionPreference *
+			(1.0 - index.projectionWidth(tableAttributeCount))
+	}
+
+	// Under provisioned billing, indexes with more read capacity are less likely to be throttled.
+	capacityScore := 1.0 + weights.CapacityPreference*index.CapacityShare
+
+	indexScore := index.SparsityMultiplier * sortKeyFilterTypeScore * projectionScore *
+		capacityScore
 
 	return indexScore, nil
 }
@@ -297,6 +797,17 @@ func (client *Client) scoreIndexOnExpr(
 func (client *Client) listIndexViabilityInfractions(
 	index *tableIndex, expr *Expression) []string {
 
+	// indexes excluded by the expression are not considered further
+	if expr.restrictedIndexes != nil {
+		_, found := expr.restrictedIndexes[index.Name]
+		if !found && index.Name == tablePrimaryIndexName && client.PrimaryIndexLabel != "" {
+			_, found = expr.restrictedIndexes[client.PrimaryIndexLabel]
+		}
+		if !found {
+			return []string{"index is not one of the expression's restricted indexes"}
+		}
+	}
+
 	notViableReasons := []string{}
 
 	// for index to be viable, there must be an equals filter on the index's partition key
@@ -307,6 +818,14 @@ func (client *Client) listIndexViabilityInfractions(
 		notViableReasons = append(notViableReasons, reason)
 	}
 
+	// a sort key prefix range is bound to the index's sort key unless it has none or the
+	// expression has another condition on it
+	if expr.sortKeyPrefixes != nil {
+		reason := "expression specifies a sort key prefix range, so it requires an index with " +
+			"a sort key that has no other condition"
+		notViableReasons = append(notViableReasons, reason)
+	}
+
 	// if consistent read is specified, index must be consistent-readable
 	if expr.consistentRead && !index.ConsistentReadable {
 		notViableReasons = append(notViableReasons,
@@ -321,8 +840,15 @@ func (client *Client) listIndexViabilityInfractions(
 		notViableReasons = append(notViableReasons, reason)
 	}
 
-	// index must include selected attributes, or project all attributes if not specified
-	if !index.IncludesAllAttributes {
+	// if latest item is requested, index must have a sort key to order on
+	if expr.latest && !index.IsComposite {
+		notViableReasons = append(notViableReasons,
+			"expression requests the latest item, so it requires an index with a sort key")
+	}
+
+	// index must include selected attributes, or project all attributes if not specified, unless
+	// missing attributes are fetched from the table
+	if !index.IncludesAllAttributes && !expr.fetchesMissingAttributes(index) {
 		if expr.attributesSpecified {
 			indexMissingAttrs := []string{}
 			for _, selectedAttr := range expr.attributes {
@@ -341,6 +867,61 @@ func (client *Client) listIndexViabilityInfractions(
 		}
 	}
 
+	// conditions are evaluated on the index's items before missing attributes are fetched
+	if expr.fetchesMissingAttributes(index) {
+		unprojectedConditionAttrs := []string{}
+		for attr := range expr.filters {
+			if _, found := index.AttributeSet[attr]; !found {
+				unprojectedConditionAttrs = append(unprojectedConditionAttrs, attr)
+			}
+		}
+		if len(unprojectedConditionAttrs) > 0 {
+			sort.Strings(unprojectedConditionAttrs)
+			reason := fmt.Sprintf("index does not include condition attributes: %s",
+				strings.Join(unprojectedConditionAttrs, ", "))
+			notViableReasons = append(notViableReasons, reason)
+		}
+	}
+
+	// time range and sort key prefix range conditions require the attribute to be the index's sort
+	// key of the matching type; attributes are ordered so that reasons are stable
+	filterAttrs := make([]string, 0, len(expr.filters))
+	for attr := range expr.filters {
+		filterAttrs = append(filterAttrs, attr)
+	}
+	sort.Strings(filterAttrs)
+	for _, attr := range filterAttrs {
+		// a compound condition cannot be queried as a key condition, and key attributes may not
+		// appear in filter conditions
+		_, isCompound := expr.filters[attr].(*andFilter)
+		if isCompound && index.IsComposite && attr == index.SortKey {
+			reason := fmt.Sprintf(
+				"expression specifies conditions which cannot be combined on sort key: %s", attr)
+			notViableReasons = append(notViableReasons, reason)
+		}
+
+		for _, filter := range filterParts(expr.filters[attr]) {
+			f, isBetween := filter.(*betweenFilter)
+			if !isBetween {
+				continue
+			}
+			// sort keys of unknown type are not excluded
+			if f.requiresNumericSortKey && (attr != index.SortKey ||
+				(index.SortKeyType != "" && index.SortKeyType != dynamodb.ScalarAttributeTypeN)) {
+				reason := fmt.Sprintf(
+					"expression specifies a time range, so it requires an index with numeric sort key: %s",
+					attr)
+				notViableReasons = append(notViableReasons, reason)
+			}
+			if f.requiresStringSortKey && (attr != index.SortKey ||
+				(index.SortKeyType != "" && index.SortKeyType != dynamodb.ScalarAttributeTypeS)) {
+				reason := fmt.Sprintf("expression specifies a sort key prefix range, "+
+					"so it requires an index with string sort key: %s", attr)
+				notViableReasons = append(notViableReasons, reason)
+			}
+		}
+	}
+
 	// if index is sparse, then both partition and sort attributes must appear in expression
 	if index.IsSparse {
 		// equals condition on partition key takes precedence, so only need to check sort key
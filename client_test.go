@@ -0,0 +1,342 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestOverloadedIndexSelected(t *testing.T) {
+	service := newFakeService(1)
+	for _, attr := range []string{"GSI1PK", "GSI1SK", "GSI2PK"} {
+		service.table.AttributeDefinitions = append(service.table.AttributeDefinitions,
+			&dynamodb.AttributeDefinition{AttributeName: aws.String(attr), AttributeType: aws.String("S")})
+	}
+	service.table.GlobalSecondaryIndexes = []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName:  aws.String("GSI1"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("GSI1PK", "GSI1SK"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		},
+		{
+			IndexName:  aws.String("GSI2"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("GSI2PK", ""),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		expr          *Expression
+		expectedIndex string
+		expectWarning bool
+	}{
+		{
+			name:          "generic composite index without sort key condition",
+			expr:          NewExpression().Equal("GSI1PK", "ORDER#1"),
+			expectedIndex: "GSI1",
+			expectWarning: true,
+		},
+		{
+			name:          "generic composite index with sort key discriminator",
+			expr:          NewExpression().Equal("GSI1PK", "ORDER#1").BeginsWith("GSI1SK", "ITEM#"),
+			expectedIndex: "GSI1",
+			expectWarning: false,
+		},
+		{
+			name:          "generic index without sort key",
+			expr:          NewExpression().Equal("GSI2PK", "ORDER#1"),
+			expectedIndex: "GSI2",
+			expectWarning: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(service)
+			client.DisableSparsenessInference = true
+			warnings := []string{}
+			client.OverloadedIndexSelected = func(tableName, indexName string) {
+				warnings = append(warnings, indexName)
+			}
+
+			indexName, err := client.Query("T", tc.expr).SelectedIndexName(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if indexName != tc.expectedIndex {
+				t.Fatalf("expected index %s, got %s", tc.expectedIndex, indexName)
+			}
+
+			if tc.expectWarning && (len(warnings) != 1 || warnings[0] != tc.expectedIndex) {
+				t.Errorf("expected warning for %s, got %v", tc.expectedIndex, warnings)
+			} else if !tc.expectWarning && len(warnings) > 0 {
+				t.Errorf("expected no warning, got %v", warnings)
+			}
+		})
+	}
+}
+
+func TestPrimaryIndexQueries(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 5; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open"})
+	}
+	orders = append(orders, testOrder{Customer: "d", ID: 1, Status: "open"})
+	client := NewClient(newOrdersService(t, orders...))
+
+	testCases := []struct {
+		name     string
+		expr     *Expression
+		expected []int
+	}{
+		{
+			name:     "range query",
+			expr:     NewExpression().Equal("customer", "c").Between("id", 2, 4),
+			expected: []int{2, 3, 4},
+		},
+		{
+			name:     "partition-only query",
+			expr:     NewExpression().Equal("customer", "c"),
+			expected: []int{1, 2, 3, 4, 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.Query("Orders", tc.expr)
+
+			indexName, err := parser.SelectedIndexName(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if indexName != PrimaryIndexName {
+				t.Errorf("expected primary index, got %s", indexName)
+			}
+
+			if ids := parseOrderIDs(t, parser); !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestMoreSelectiveSortKeyConditionWins(t *testing.T) {
+	service := newFakeService(1)
+	for _, attr := range []string{"a", "b"} {
+		service.table.AttributeDefinitions = append(service.table.AttributeDefinitions,
+			&dynamodb.AttributeDefinition{AttributeName: aws.String(attr), AttributeType: aws.String("S")})
+	}
+	service.table.GlobalSecondaryIndexes = []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName:  aws.String("g-a"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", "a"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		},
+		{
+			IndexName:  aws.String("g-b"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", "b"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		},
+	}
+	client := NewClient(service)
+	client.DisableSparsenessInference = true
+
+	testCases := []struct {
+		name          string
+		expr          *Expression
+		expectedIndex string
+	}{
+		{
+			name:          "equal over prefix",
+			expr:          NewExpression().Equal("g", "x").BeginsWith("a", "p").Equal("b", "y"),
+			expectedIndex: "g-b",
+		},
+		{
+			name:          "range over none",
+			expr:          NewExpression().Equal("g", "x").Between("a", "c", "f"),
+			expectedIndex: "g-a",
+		},
+		{
+			name:          "prefix over lower bound",
+			expr:          NewExpression().Equal("g", "x").GreaterThan("a", "c").BeginsWith("b", "p"),
+			expectedIndex: "g-b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			indexName, err := client.Query("T", tc.expr).SelectedIndexName(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if indexName != tc.expectedIndex {
+				t.Errorf("expected index %s, got %s", tc.expectedIndex, indexName)
+			}
+		})
+	}
+}
+
+func TestSelectIndexFor(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	values := map[string]*dynamodb.AttributeValue{
+		":p":    {S: aws.String("a")},
+		":g":    {S: aws.String("x")},
+		":low":  {N: aws.String("1")},
+		":high": {N: aws.String("9")},
+	}
+
+	testCases := []struct {
+		name          string
+		input         *dynamodb.QueryInput
+		expectedIndex string
+		expectErr     bool
+	}{
+		{
+			name: "primary index with attribute name placeholder",
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression:   aws.String("#p = :p"),
+				ExpressionAttributeNames: map[string]*string{"#p": aws.String("pk")},
+			},
+			expectedIndex: "",
+		},
+		{
+			name: "secondary index with sort key range",
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("(g = :g) AND sk BETWEEN :low AND :high"),
+				ProjectionExpression:   aws.String("pk, sk"),
+			},
+			expectedIndex: "g-sk",
+		},
+		{
+			name: "consistent read on secondary index",
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("g = :g"),
+				ConsistentRead:         aws.Bool(true),
+			},
+			expectErr: true,
+		},
+		{
+			name:      "missing key condition",
+			input:     &dynamodb.QueryInput{},
+			expectErr: true,
+		},
+		{
+			name:      "invalid key condition",
+			input:     &dynamodb.QueryInput{KeyConditionExpression: aws.String("g = :g OR")},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.input.ExpressionAttributeValues = values
+			indexName, err := client.SelectIndexFor(context.Background(), "T", tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got index %q", indexName)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if indexName != tc.expectedIndex {
+				t.Errorf("expected index %q, got %q", tc.expectedIndex, indexName)
+			}
+		})
+	}
+}
+
+func TestIndexesFor(t *testing.T) {
+	service := newFakeService(1)
+	service.table.GlobalSecondaryIndexes = append(service.table.GlobalSecondaryIndexes,
+		&dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String("g-only"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", ""),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		})
+	service.table.LocalSecondaryIndexes = []*dynamodb.LocalSecondaryIndexDescription{
+		{
+			IndexName:  aws.String("pk-g"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("pk", "g"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		primaryLabel  string
+		partitionKey  string
+		expectedNames []string
+	}{
+		{"table partition key", "", "pk", []string{PrimaryIndexName, "pk-g"}},
+		{"labeled primary index", "main", "pk", []string{"main", "pk-g"}},
+		{"secondary partition key", "", "g", []string{"g-only", "g-sk"}},
+		{"sort key only", "", "sk", []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(service)
+			client.PrimaryIndexLabel = tc.primaryLabel
+
+			names, err := client.IndexesFor(context.Background(), "T", tc.partitionKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names, tc.expectedNames) {
+				t.Errorf("expected %v, got %v", tc.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	client := NewClient(newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Status: "open", Total: 10},
+		testOrder{Customer: "c", ID: 2, Status: "open", Total: 20},
+		testOrder{Customer: "c", ID: 3, Status: "closed", Total: 30},
+		testOrder{Customer: "d", ID: 4, Status: "open", Total: 40},
+	))
+
+	testCases := []struct {
+		name       string
+		expr       *Expression
+		expectedID int
+		expectNone bool
+	}{
+		{"highest sort key", NewExpression().Equal("customer", "c"), 3, false},
+		{"filtered", NewExpression().Equal("customer", "c").Equal("total", 20), 2, false},
+		{"secondary index", NewExpression().Equal("status", "open"), 4, false},
+		{"no items", NewExpression().Equal("customer", "e"), 0, true},
+		{"no items match filter", NewExpression().Equal("customer", "d").Equal("total", 1), 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var order testOrder
+			err := client.Latest(context.Background(), "Orders", tc.expr, &order)
+			if tc.expectNone {
+				if _, ok := err.(*ErrNoItems); !ok {
+					t.Errorf("expected ErrNoItems, got %v", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if order.ID != tc.expectedID {
+				t.Errorf("expected id %d, got %d", tc.expectedID, order.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package autoquery
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (expr *Expression) setFilter(attr string, filter conditionFilter) {
+	filter, err := exactConditionValues(filter)
+	if err != nil {
+		expr.setFilterErr(attr, fmt.Errorf("condition on attribute %s: %v", attr, err))
+		return
+	}
+
+	existingFilter, found := expr.filters[attr]
+	if !expr.combineConditions || !found {
+		expr.filters[attr] = filter
+		delete(expr.filterErrs, attr)
+		return
+	}
+
+	combinedFilter, err := combineFilters(existingFilter, filter)
+	if err != nil {
+		expr.setErr(fmt.Errorf("conflicting conditions on attribute %s: %v", attr, err))
+		return
+	}
+	expr.filters[attr] = combinedFilter
+}
+
+// combineFilters returns a single filter equivalent to both filters. Ranges are intersected,
+// keeping the tighter of two bounds in the same direction, and conditions which cannot be
+// combined into a single condition are combined into a compound filter condition. An error is
+// returned if no value can satisfy both filters.
+func combineFilters(a, b conditionFilter) (conditionFilter, error) {
+	if typesMatch(a, &existsFilter{}) {
+		return b, nil
+	} else if typesMatch(b, &existsFilter{}) {
+		return a, nil
+	}
+
+	if aEquals, ok := a.(*equalsFilter); ok {
+		if bEquals, ok := b.(*equalsFilter); ok {
+			equal, err := valuesEqual(aEquals.value, bEquals.value)
+			if err != nil {
+				return nil, err
+			} else if !equal {
+				return nil, errors.New("contradictory conditions: equal conditions have different values")
+			}
+			return a, nil
+		}
+	}
+
+	if aRange, ok := rangeOf(a); ok {
+		if bRange, ok := rangeOf(b); ok {
+			return intersectRanges(a, aRange, b, bRange)
+		}
+	}
+
+	aPrefix, aIsPrefix := a.(*beginsWithFilter)
+	bPrefix, bIsPrefix := b.(*beginsWithFilter)
+	switch {
+	case aIsPrefix && bIsPrefix:
+		if strings.HasPrefix(aPrefix.prefix, bPrefix.prefix) {
+			return a, nil
+		} else if strings.HasPrefix(bPrefix.prefix, aPrefix.prefix) {
+			return b, nil
+		}
+		return nil, errors.New("contradictory conditions: begins with conditions have different prefixes")
+	case aIsPrefix:
+		if equals, ok := b.(*equalsFilter); ok {
+			return equalsWithPrefix(equals, aPrefix)
+		}
+	case bIsPrefix:
+		if equals, ok := a.(*equalsFilter); ok {
+			return equalsWithPrefix(equals, bPrefix)
+		}
+	}
+
+	return &andFilter{filters: append(filterParts(a), filterParts(b)...)}, nil
+}
+
+// valueRange is the range of values which satisfy a filter, with unset bounds being unbounded
+type valueRange struct {
+	low, high               interface{}
+	hasLow, hasHigh         bool
+	excludeLow, excludeHigh bool
+	requiresNumericSortKey  bool
+	requiresStringSortKey   bool
+}
+
+// rangeOf returns the range of values which satisfy the filter, if the filter is a range
+func rangeOf(filter conditionFilter) (valueRange, bool) {
+	switch f := filter.(type) {
+	case *equalsFilter:
+		return valueRange{low: f.value, high: f.value, hasLow: true, hasHigh: true}, true
+	case *greaterThanFilter:
+		return valueRange{low: f.value, hasLow: true, excludeLow: true}, true
+	case *greaterThanEqualFilter:
+		return valueRange{low: f.value, hasLow: true}, true
+	case *lessThanFilter:
+		return valueRange{high: f.value, hasHigh: true, excludeHigh: true}, true
+	case *lessThanEqualFilter:
+		return valueRange{high: f.value, hasHigh: true}, true
+	case *betweenFilter:
+		return valueRange{
+			low: f.lowval, high: f.highval, hasLow: true, hasHigh: true,
+			excludeLow: f.excludeLow, excludeHigh: f.excludeHigh,
+			requiresNumericSortKey: f.requiresNumericSortKey,
+			requiresStringSortKey:  f.requiresStringSortKey,
+		}, true
+	}
+	return valueRange{}, false
+}
+
+// intersectRanges returns a filter for the values in both ranges, returning an equal filter
+// unchanged if its value is in the other range
+func intersectRanges(a conditionFilter, aRange valueRange,
+	b conditionFilter, bRange valueRange) (conditionFilter, error) {
+
+	r := valueRange{
+		requiresNumericSortKey: aRange.requiresNumericSortKey || bRange.requiresNumericSortKey,
+		requiresStringSortKey:  aRange.requiresStringSortKey || bRange.requiresStringSortKey,
+	}
+
+	// the greater lower bound is kept, excluding the bound if either excludes it
+	for _, bound := range []valueRange{aRange, bRange} {
+		if !bound.hasLow {
+			continue
+		}
+		cmp := 1
+		if r.hasLow {
+			var err error
+			if cmp, err = compareConditionValues(bound.low, r.low); err != nil {
+				return nil, err
+			}
+		}
+		if cmp > 0 {
+			r.low, r.hasLow, r.excludeLow = bound.low, true, bound.excludeLow
+		} else if cmp == 0 {
+			r.excludeLow = r.excludeLow || bound.excludeLow
+		}
+	}
+
+	// the lesser upper bound is kept, excluding the bound if either excludes it
+	for _, bound := range []valueRange{aRange, bRange} {
+		if !bound.hasHigh {
+			continue
+		}
+		cmp := -1
+		if r.hasHigh {
+			var err error
+			if cmp, err = compareConditionValues(bound.high, r.high); err != nil {
+				return nil, err
+			}
+		}
+		if cmp < 0 {
+			r.high, r.hasHigh, r.excludeHigh = bound.high, true, bound.excludeHigh
+		} else if cmp == 0 {
+			r.excludeHigh = r.excludeHigh || bound.excludeHigh
+		}
+	}
+
+	if r.hasLow && r.hasHigh {
+		cmp, err := compareConditionValues(r.low, r.high)
+		if err != nil {
+			return nil, err
+		} else if cmp > 0 || (cmp == 0 && (r.excludeLow || r.excludeHigh)) {
+			return nil, fmt.Errorf("contradictory conditions: %s and %s conditions match no values",
+				filterName(a), filterName(b))
+		}
+	}
+
+	if typesMatch(a, &equalsFilter{}) {
+		return a, nil
+	} else if typesMatch(b, &equalsFilter{}) {
+		return b, nil
+	}
+
+	switch {
+	case r.hasLow && r.hasHigh:
+		return &betweenFilter{lowval: r.low, highval: r.high,
+			excludeLow: r.excludeLow, excludeHigh: r.excludeHigh,
+			requiresNumericSortKey: r.requiresNumericSortKey,
+			requiresStringSortKey:  r.requiresStringSortKey}, nil
+	case r.hasLow && r.excludeLow:
+		return &greaterThanFilter{value: r.low}, nil
+	case r.hasLow:
+		return &greaterThanEqualFilter{value: r.low}, nil
+	case r.excludeHigh:
+		return &lessThanFilter{value: r.high}, nil
+	default:
+		return &lessThanEqualFilter{value: r.high}, nil
+	}
+}
+
+// equalsWithPrefix returns the equal filter if its value begins with the prefix. Values which are
+// not strings are not compared, so both conditions are kept in a compound filter.
+func equalsWithPrefix(equals *equalsFilter, prefix *beginsWithFilter) (conditionFilter, error) {
+	value := reflect.ValueOf(equals.value)
+	if value.Kind() != reflect.String {
+		return &andFilter{filters: []conditionFilter{equals, prefix}}, nil
+	}
+	if strings.HasPrefix(value.String(), prefix.prefix) {
+		return equals, nil
+	}
+	return nil, errors.New("contradictory conditions: equal value does not begin with prefix")
+}
+
+// filterParts returns the conditions of a compound filter, or the filter itself otherwise
+func filterParts(filter conditionFilter) []conditionFilter {
+	if f, ok := filter.(*andFilter); ok {
+		return f.filters
+	}
+	return []conditionFilter{filter}
+}
+
+// compareConditionValues compares condition values of the same type by their marshaled values,
+// comparing numbers by value, strings by their UTF-8 bytes, and binary values by their bytes
+func compareConditionValues(a, b interface{}) (int, error) {
+	aValue, err := dynamodbattribute.Marshal(a)
+	if err != nil {
+		return 0, err
+	}
+	bValue, err := dynamodbattribute.Marshal(b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case aValue.S != nil && bValue.S != nil:
+		return strings.Compare(*aValue.S, *bValue.S), nil
+	case aValue.B != nil && bValue.B != nil:
+		return bytes.Compare(aValue.B, bValue.B), nil
+	case aValue.N != nil && bValue.N != nil:
+		aNumber, aOk := new(big.Float).SetPrec(256).SetString(*aValue.N)
+		bNumber, bOk := new(big.Float).SetPrec(256).SetString(*bValue.N)
+		if aOk && bOk {
+			return aNumber.Cmp(bNumber), nil
+		}
+	}
+	return 0, errors.New("condition values are not comparable numbers, strings, or binary values")
+}
+
+func filterName(filter conditionFilter) string {
+	switch filter.(type) {
+	case *equalsFilter:
+		return "equal"
+	case *lessThanFilter:
+		return "less than"
+	case *greaterThanFilter:
+		return "greater than"
+	case *lessThanEqualFilter:
+		return "less than or equal"
+	case *greaterThanEqualFilter:
+		return "greater than or equal"
+	case *betweenFilter:
+		return "between"
+	case *beginsWithFilter:
+		return "begins with"
+	case *andFilter:
+		return "compound"
+	}
+	return "unknown"
+}
+
+// valuesEqual returns true if the values marshal to the same attribute value
+func valuesEqual(a, b interface{}) (bool, error) {
+	aValue, err := dynamodbattribute.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	bValue, err := dynamodbattribute.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(aValue, bValue), nil
+}
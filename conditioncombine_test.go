@@ -0,0 +1,177 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestCombineFilters(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     conditionFilter
+		combined conditionFilter
+	}{
+		{"lower and upper bound",
+			&greaterThanFilter{value: 5}, &lessThanFilter{value: 10},
+			&betweenFilter{lowval: 5, highval: 10, excludeLow: true, excludeHigh: true}},
+		{"upper and lower bound",
+			&lessThanEqualFilter{value: 10}, &greaterThanEqualFilter{value: 5},
+			&betweenFilter{lowval: 5, highval: 10}},
+		{"tighter lower bound",
+			&greaterThanFilter{value: 5}, &greaterThanFilter{value: 7},
+			&greaterThanFilter{value: 7}},
+		{"exclusive bound at equal value",
+			&greaterThanEqualFilter{value: 7}, &greaterThanFilter{value: 7},
+			&greaterThanFilter{value: 7}},
+		{"tighter upper bound",
+			&lessThanFilter{value: 10}, &lessThanEqualFilter{value: 8},
+			&lessThanEqualFilter{value: 8}},
+		{"range narrowed by bound",
+			&betweenFilter{lowval: 1, highval: 10}, &greaterThanEqualFilter{value: 3},
+			&betweenFilter{lowval: 3, highval: 10}},
+		{"time range keeps sort key requirement",
+			&betweenFilter{lowval: 1, highval: 10, requiresNumericSortKey: true},
+			&lessThanFilter{value: 5},
+			&betweenFilter{lowval: 1, highval: 5, excludeHigh: true, requiresNumericSortKey: true}},
+		{"single value range",
+			&greaterThanEqualFilter{value: 5}, &lessThanEqualFilter{value: 5},
+			&betweenFilter{lowval: 5, highval: 5}},
+		{"equal within range",
+			&equalsFilter{value: 5}, &greaterThanFilter{value: 3},
+			&equalsFilter{value: 5}},
+		{"repeated equal",
+			&equalsFilter{value: "a"}, &equalsFilter{value: "a"},
+			&equalsFilter{value: "a"}},
+		{"string bounds",
+			&greaterThanFilter{value: "b"}, &greaterThanFilter{value: "a"},
+			&greaterThanFilter{value: "b"}},
+		{"longer prefix",
+			&beginsWithFilter{prefix: "ab"}, &beginsWithFilter{prefix: "a"},
+			&beginsWithFilter{prefix: "ab"}},
+		{"equal with prefix",
+			&beginsWithFilter{prefix: "ab"}, &equalsFilter{value: "abc"},
+			&equalsFilter{value: "abc"}},
+		{"exists",
+			&existsFilter{}, &lessThanFilter{value: 5},
+			&lessThanFilter{value: 5}},
+		{"prefix and bound",
+			&beginsWithFilter{prefix: "a"}, &lessThanFilter{value: "am"},
+			&andFilter{filters: []conditionFilter{
+				&beginsWithFilter{prefix: "a"}, &lessThanFilter{value: "am"}}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			combined, err := combineFilters(testCase.a, testCase.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(combined, testCase.combined) {
+				t.Errorf("expected %#v, got %#v", testCase.combined, combined)
+			}
+		})
+	}
+}
+
+func TestCombineFiltersContradictions(t *testing.T) {
+	testCases := []struct {
+		name string
+		a, b conditionFilter
+	}{
+		{"empty range", &greaterThanFilter{value: 10}, &lessThanFilter{value: 5}},
+		{"excluded single value", &greaterThanFilter{value: 5}, &lessThanEqualFilter{value: 5}},
+		{"equal outside range", &equalsFilter{value: 5}, &greaterThanFilter{value: 5}},
+		{"different equal values", &equalsFilter{value: 1}, &equalsFilter{value: 2}},
+		{"different prefixes", &beginsWithFilter{prefix: "ab"}, &beginsWithFilter{prefix: "b"}},
+		{"equal without prefix", &equalsFilter{value: "b"}, &beginsWithFilter{prefix: "a"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := combineFilters(testCase.a, testCase.b); err == nil {
+				t.Error("expected contradictory conditions to be rejected")
+			} else if !strings.Contains(err.Error(), "contradictory") {
+				t.Errorf("expected contradiction error, got %v", err)
+			}
+		})
+	}
+
+	if _, err := combineFilters(&lessThanFilter{value: 5}, &lessThanFilter{value: "a"}); err == nil {
+		t.Error("expected values of different types to be rejected")
+	}
+}
+
+func TestCombineEqualNamedStringWithPrefix(t *testing.T) {
+	type status string
+
+	combined, err := combineFilters(&equalsFilter{value: status("open#1")},
+		&beginsWithFilter{prefix: "open#"})
+	if err != nil {
+		t.Fatalf("expected named string type beginning with prefix to be accepted, got %v", err)
+	}
+	if expected := (&equalsFilter{value: status("open#1")}); !reflect.DeepEqual(combined, expected) {
+		t.Errorf("expected %#v, got %#v", expected, combined)
+	}
+
+	_, err = combineFilters(&equalsFilter{value: status("closed")}, &beginsWithFilter{prefix: "open#"})
+	if err == nil {
+		t.Error("expected named string type without prefix to be rejected")
+	}
+}
+
+func TestCombineConditionsSortKeyRange(t *testing.T) {
+	service := newFakeService(1)
+	expr := NewExpression().CombineConditions().Equal("pk", "a").
+		GreaterThan("sk", 5).GreaterThan("sk", 7).LessThan("sk", 10)
+
+	_, bounds := keyConditionBounds(t, service, expr, "a")
+	if !containsAll(bounds, "7", "10") || len(bounds) != 2 {
+		t.Errorf("expected bounds 7 and 10, got %q", bounds)
+	}
+	if input := service.queryInputs[0]; input.FilterExpression != nil {
+		t.Errorf("expected no filter expression, got %s", aws.StringValue(input.FilterExpression))
+	}
+}
+
+func TestCombineConditionsContradictionError(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	expr := NewExpression().CombineConditions().Equal("pk", "a").
+		GreaterThan("sk", 10).LessThan("sk", 5)
+	err := client.Validate(context.Background(), "T", expr)
+	if err == nil || !strings.Contains(err.Error(), "contradictory") {
+		t.Errorf("expected contradictory conditions error, got %v", err)
+	}
+}
+
+func TestCombineConditionsCompoundFilter(t *testing.T) {
+	service := newFakeService(1)
+	client := NewClient(service)
+
+	expr := NewExpression().CombineConditions().Equal("pk", "a").
+		BeginsWith("name", "a").LessThan("name", "am")
+	if _, err := parseSortKeys(context.Background(), client.Query("T", expr)); err != nil {
+		t.Fatal(err)
+	}
+
+	filterExpression := aws.StringValue(service.queryInputs[0].FilterExpression)
+	if !strings.Contains(filterExpression, "begins_with") ||
+		!strings.Contains(filterExpression, "<") || !strings.Contains(filterExpression, "AND") {
+		t.Errorf("expected compound filter expression, got %s", filterExpression)
+	}
+}
+
+func TestCombineConditionsCompoundSortKeyNotViable(t *testing.T) {
+	client := NewClient(newPathIndexService())
+
+	expr := NewExpression().CombineConditions().Equal("g", "x").
+		BeginsWith("path", "a").LessThan("path", "am").RestrictToIndexes("g-path")
+	err := client.Validate(context.Background(), "T", expr)
+	if _, ok := err.(*ErrNoViableIndexes); !ok {
+		t.Errorf("expected ErrNoViableIndexes, got %v", err)
+	}
+}
@@ -22,10 +22,30 @@ type greaterThanEqualFilter struct {
 	value interface{}
 }
 
+type existsFilter struct{}
+
 type beginsWithFilter struct {
 	prefix string
 }
 
 type betweenFilter struct {
 	lowval, highval interface{}
+
+	// requiresNumericSortKey restricts viable indexes to those with the attribute as a numeric
+	// sort key
+	requiresNumericSortKey bool
+
+	// requiresStringSortKey restricts viable indexes to those with the attribute as a string sort
+	// key
+	requiresStringSortKey bool
+
+	// excludeLow and excludeHigh exclude items whose value equals lowval or highval, respectively
+	excludeLow, excludeHigh bool
+}
+
+// andFilter is a compound condition of filters on the same attribute which cannot be combined
+// into a single condition. It is only applied as a filter condition, so indexes which use the
+// attribute as a key are not viable.
+type andFilter struct {
+	filters []conditionFilter
 }
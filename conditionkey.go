@@ -1,5 +1,7 @@
 package autoquery
 
+import "time"
+
 // ConditionKey forms part of a condition of an expression.
 //
 // The ConditionKey should be followed by a value condition in order to form a complete
@@ -57,6 +59,29 @@ func (key *ConditionKey) Between(lowval, highval interface{}) *Expression {
 	return key.expr.Between(key.attr, lowval, highval)
 }
 
+// Exists adds a new attribute exists condition to the expression. Only items which have the key
+// attribute will be returned. See Expression.Exists for how exists conditions on index keys are
+// applied.
+func (key *ConditionKey) Exists() *Expression {
+	return key.expr.Exists(key.attr)
+}
+
+// BetweenBounds adds a new range condition to the expression with configurable bounds. Only items
+// where the value of the key attribute is between lowval and highval will be returned, where each
+// bound is included only if lowInclusive or highInclusive is true, respectively. See
+// Expression.BetweenBounds for how excluded bounds are applied.
+func (key *ConditionKey) BetweenBounds(lowval, highval interface{},
+	lowInclusive, highInclusive bool) *Expression {
+	return key.expr.BetweenBounds(key.attr, lowval, highval, lowInclusive, highInclusive)
+}
+
+// TimeRange adds a new between condition to the expression on a numeric sort key attribute
+// which stores times as Unix epoch seconds. Only items where the value of the key attribute is
+// between from and to (inclusive) will be returned.
+func (key *ConditionKey) TimeRange(from, to time.Time) *Expression {
+	return key.expr.TimeRange(key.attr, from, to)
+}
+
 // BeginsWith adds a new begins-with condition to the expression. Only items where the value of
 // the key attribute begins with the specified prefix will be returned.
 func (key *ConditionKey) BeginsWith(prefix string) *Expression {
@@ -0,0 +1,47 @@
+package autoquery
+
+// ConsistencyPolicy determines how expressions which specify consistent read are handled when
+// only global secondary indexes, which do not support consistent read, are viable.
+type ConsistencyPolicy int
+
+const (
+	// StrictReject treats global secondary indexes as non-viable for consistent read expressions.
+	// If no other index is viable, the query fails with ErrNoViableIndexes. This is the default.
+	StrictReject ConsistencyPolicy = iota
+
+	// AutoDowngrade allows a consistent read expression to be queried on a global secondary index
+	// with eventually consistent reads when no index supporting consistent read is viable.
+	AutoDowngrade
+)
+
+// ReadConsistency is the read consistency requested by an expression.
+type ReadConsistency int
+
+const (
+	// EventuallyConsistent reads are used for every index. This is the default.
+	EventuallyConsistent ReadConsistency = iota
+
+	// ConsistentIfSupported requests consistent read when the selected index supports it, which
+	// is the case for the table's primary index and local secondary indexes. Global secondary
+	// indexes remain viable and are queried with eventually consistent reads if selected.
+	ConsistentIfSupported
+
+	// ConsistentRequired requires consistent read, so global secondary indexes are only viable
+	// under the client's ConsistencyPolicy. This is equivalent to ConsistentRead(true).
+	ConsistentRequired
+)
+
+// ReadConsistency sets the read consistency of each query page request. Unlike ConsistentRead,
+// the ConsistentIfSupported mode requests consistent read on a best-effort basis without affecting
+// which indexes are viable, so it may be used when consistency is preferred but not required.
+func (expr *Expression) ReadConsistency(consistency ReadConsistency) *Expression {
+	expr.consistentRead = consistency == ConsistentRequired
+	expr.consistentReadIfSupported = consistency == ConsistentIfSupported
+	return expr
+}
+
+// readsConsistently returns true if consistent read is requested when querying the index, as
+// consistent read may have been downgraded by the client's consistency policy
+func (expr *Expression) readsConsistently(index *tableIndex) bool {
+	return (expr.consistentRead || expr.consistentReadIfSupported) && index.ConsistentReadable
+}
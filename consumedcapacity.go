@@ -0,0 +1,68 @@
+package autoquery
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func addConsumedCapacity(total, page *dynamodb.ConsumedCapacity) *dynamodb.ConsumedCapacity {
+	if page == nil {
+		return total
+	}
+	if total == nil {
+		total = &dynamodb.ConsumedCapacity{TableName: page.TableName}
+	}
+
+	total.CapacityUnits = addCapacityUnits(total.CapacityUnits, page.CapacityUnits)
+	total.ReadCapacityUnits = addCapacityUnits(total.ReadCapacityUnits, page.ReadCapacityUnits)
+	total.WriteCapacityUnits = addCapacityUnits(total.WriteCapacityUnits, page.WriteCapacityUnits)
+	total.Table = addCapacity(total.Table, page.Table)
+	total.GlobalSecondaryIndexes = addIndexCapacities(
+		total.GlobalSecondaryIndexes, page.GlobalSecondaryIndexes)
+	total.LocalSecondaryIndexes = addIndexCapacities(
+		total.LocalSecondaryIndexes, page.LocalSecondaryIndexes)
+
+	return total
+}
+
+func addCapacity(total, page *dynamodb.Capacity) *dynamodb.Capacity {
+	if page == nil {
+		return total
+	}
+	if total == nil {
+		total = &dynamodb.Capacity{}
+	}
+
+	total.CapacityUnits = addCapacityUnits(total.CapacityUnits, page.CapacityUnits)
+	total.ReadCapacityUnits = addCapacityUnits(total.ReadCapacityUnits, page.ReadCapacityUnits)
+	total.WriteCapacityUnits = addCapacityUnits(total.WriteCapacityUnits, page.WriteCapacityUnits)
+
+	return total
+}
+
+func addIndexCapacities(
+	total, page map[string]*dynamodb.Capacity) map[string]*dynamodb.Capacity {
+
+	if len(page) == 0 {
+		return total
+	}
+	if total == nil {
+		total = map[string]*dynamodb.Capacity{}
+	}
+
+	for indexName, capacity := range page {
+		total[indexName] = addCapacity(total[indexName], capacity)
+	}
+
+	return total
+}
+
+func addCapacityUnits(total, page *float64) *float64 {
+	if page == nil {
+		return total
+	}
+	if total == nil {
+		return aws.Float64(*page)
+	}
+	return aws.Float64(*total + *page)
+}
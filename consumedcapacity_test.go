@@ -0,0 +1,72 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestReturnConsumedCapacity(t *testing.T) {
+	testCases := []struct {
+		name          string
+		clientDefault string
+		override      string
+		expected      string
+	}{
+		{name: "not requested", expected: ""},
+		{name: "inherited from client", clientDefault: "TOTAL", expected: "TOTAL"},
+		{name: "overridden by expression", clientDefault: "TOTAL", override: "NONE",
+			expected: "NONE"},
+		{name: "set by expression", override: "INDEXES", expected: "INDEXES"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeService(2, 1)
+			client := NewClient(service)
+			client.ReturnConsumedCapacity = tc.clientDefault
+
+			// each page consumes one capacity unit when capacity is requested
+			client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+				opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+				output, err := service.QueryWithContext(ctx, input, opts...)
+				if err == nil && aws.StringValue(input.ReturnConsumedCapacity) != "" &&
+					aws.StringValue(input.ReturnConsumedCapacity) != "NONE" {
+
+					output.ConsumedCapacity = &dynamodb.ConsumedCapacity{
+						TableName: input.TableName, CapacityUnits: aws.Float64(1),
+					}
+				}
+				return output, err
+			}
+
+			expr := NewExpression().Equal("pk", "a")
+			if tc.override != "" {
+				expr.ReturnConsumedCapacity(tc.override)
+			}
+			parser := client.Query("T", expr)
+			if _, err := parseSortKeys(context.Background(), parser); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, input := range service.queryInputs {
+				if actual := aws.StringValue(input.ReturnConsumedCapacity); actual != tc.expected {
+					t.Errorf("expected ReturnConsumedCapacity %q, got %q", tc.expected, actual)
+				}
+			}
+
+			capacity := parser.ConsumedCapacity()
+			if tc.expected == "" || tc.expected == "NONE" {
+				if capacity != nil {
+					t.Errorf("expected no consumed capacity, got %v", capacity)
+				}
+			} else if capacity == nil || aws.Float64Value(capacity.CapacityUnits) != 2 {
+				t.Errorf("expected 2 capacity units across pages, got %v", capacity)
+			}
+		})
+	}
+}
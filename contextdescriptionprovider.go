@@ -0,0 +1,78 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ScopedDescriptionProvider is a TableDescriptionProvider whose descriptions depend on a scope
+// derived from the request context, such as a tenant whose tables are in a separate account. A
+// client with a scoped provider caches each table's metadata separately for each scope, so that
+// same-named tables in different scopes never share cache entries, including in the client's
+// MetadataCache.
+type ScopedDescriptionProvider interface {
+	TableDescriptionProvider
+
+	// Scope returns the scope of the request context.
+	Scope(ctx context.Context) string
+}
+
+// ContextDescriptionProvider is a ScopedDescriptionProvider which routes each request to one of
+// several providers, selected by the scope of the request context. This may be used by
+// multi-tenant systems in which tenants have differently configured tables of the same name.
+type ContextDescriptionProvider struct {
+	scope     func(ctx context.Context) string
+	providers map[string]TableDescriptionProvider
+}
+
+// NewContextDescriptionProvider creates a new ContextDescriptionProvider which routes requests to
+// the provider of the scope returned by scope, such as a tenant ID read from a context value. If
+// no provider is registered for a request's scope, the request returns an ErrUnknownScope error.
+func NewContextDescriptionProvider(scope func(ctx context.Context) string,
+	providers map[string]TableDescriptionProvider) *ContextDescriptionProvider {
+
+	p := &ContextDescriptionProvider{
+		scope:     scope,
+		providers: map[string]TableDescriptionProvider{},
+	}
+	for name, provider := range providers {
+		p.providers[name] = provider
+	}
+	return p
+}
+
+// Scope returns the scope of the request context.
+func (p *ContextDescriptionProvider) Scope(ctx context.Context) string {
+	return p.scope(ctx)
+}
+
+// Get returns the table description from the provider of the request context's scope.
+func (p *ContextDescriptionProvider) Get(
+	ctx context.Context, tableName string) (*dynamodb.TableDescription, error) {
+
+	scope := p.scope(ctx)
+	provider, found := p.providers[scope]
+	if !found {
+		return nil, &ErrUnknownScope{Scope: scope}
+	}
+	return provider.Get(ctx, tableName)
+}
+
+// metadataScope returns the scope of the request context if the client's metadata provider is
+// scoped, or an empty string otherwise
+func (client *Client) metadataScope(ctx context.Context) string {
+	if scoped, ok := client.metadataProvider.(ScopedDescriptionProvider); ok {
+		return scoped.Scope(ctx)
+	}
+	return ""
+}
+
+// metadataCacheKey returns the key under which the table's metadata is cached for the request
+// context. Table names cannot contain colons, so scoped keys never collide with table names.
+func (client *Client) metadataCacheKey(ctx context.Context, tableName string) string {
+	if scoped, ok := client.metadataProvider.(ScopedDescriptionProvider); ok {
+		return scoped.Scope(ctx) + ":" + tableName
+	}
+	return tableName
+}
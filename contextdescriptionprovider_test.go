@@ -0,0 +1,113 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// serviceDescriptionProvider describes tables with a fake service
+type serviceDescriptionProvider struct {
+	service *fakeService
+}
+
+func (p *serviceDescriptionProvider) Get(
+	ctx context.Context, tableName string) (*dynamodb.TableDescription, error) {
+
+	output, err := p.service.DescribeTableWithContext(ctx,
+		&dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return nil, err
+	}
+	return output.Table, nil
+}
+
+type tenantKey struct{}
+
+func withTenant(tenant string) context.Context {
+	return context.WithValue(context.Background(), tenantKey{}, tenant)
+}
+
+// newTenantClient creates a client for tenants "a" and "b", each with a table named "T" whose
+// g-ts index is backfilling for tenant "a" only
+func newTenantClient() *Client {
+	backfillingService := newSparseIndexService(dynamodb.IndexStatusCreating, true)
+	activeService := newSparseIndexService(dynamodb.IndexStatusActive, false)
+
+	provider := NewContextDescriptionProvider(func(ctx context.Context) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant
+	}, map[string]TableDescriptionProvider{
+		"a": &serviceDescriptionProvider{service: backfillingService},
+		"b": &serviceDescriptionProvider{service: activeService},
+	})
+
+	client := NewClientWithMetadataProvider(backfillingService, provider)
+	client.SparsenessGracePeriod = time.Hour
+	return client
+}
+
+func TestScopesKeepSeparateIndexUsage(t *testing.T) {
+	client := newTenantClient()
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a"))
+	if err := parser.Prepare(withTenant("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := client.IndexUsageStats(withTenant("a"), "T"); stats[PrimaryIndexName] != 1 {
+		t.Errorf("expected 1 primary index selection for tenant a, got %v", stats)
+	}
+	if stats := client.IndexUsageStats(withTenant("b"), "T"); len(stats) != 0 {
+		t.Errorf("expected no index selections for tenant b, got %v", stats)
+	}
+}
+
+func TestScopesKeepSeparateRegisteredTypes(t *testing.T) {
+	client := newTenantClient()
+	if err := client.RegisterType(withTenant("a"), "T", testRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RegisterEntity(withTenant("a"), "T", "record", testRecord{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expr := func() *Expression {
+		return NewExpression().Equal("pk", "a").Select("other")
+	}
+
+	if err := client.Query("T", expr()).Prepare(withTenant("a")); err == nil {
+		t.Error("expected attribute outside tenant a's registered type to be rejected")
+	}
+	if err := client.Query("T", expr()).Prepare(withTenant("b")); err != nil {
+		t.Errorf("expected tenant b to have no registered type, got %v", err)
+	}
+
+	err := client.Validate(withTenant("b"), "T", expr().ForEntity("record"))
+	if err == nil {
+		t.Error("expected entity registered for tenant a to be unknown for tenant b")
+	}
+	err = client.Validate(withTenant("a"), "T",
+		NewExpression().Equal("pk", "a").Select("sk").ForEntity("record"))
+	if err != nil {
+		t.Errorf("expected entity registered for tenant a to be known, got %v", err)
+	}
+}
+
+func TestScopesKeepSeparateBackfillTimes(t *testing.T) {
+	client := newTenantClient()
+
+	// tenant a observes g-ts backfilling, which must not make tenant b's g-ts dense
+	err := client.Validate(withTenant("a"), "T", NewExpression().Equal("g", "x"))
+	if err != nil {
+		t.Errorf("expected backfilling index to be viable for tenant a, got %v", err)
+	}
+
+	err = client.Validate(withTenant("b"), "T", NewExpression().Equal("g", "x"))
+	if _, ok := err.(*ErrNoViableIndexes); !ok {
+		t.Errorf("expected sparse index to require a sort key condition for tenant b, got %v", err)
+	}
+}
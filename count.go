@@ -0,0 +1,107 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Count returns the number of items in the table that match the expression. The index is selected
+// in the same way as for Query, and the count is computed by DynamoDB across all pages of the
+// query without returning items. Filter conditions are applied to the count. Count always uses
+// the Query API, even if the expression specifies UsePartiQL.
+func (client *Client) Count(ctx context.Context, tableName string, expr *Expression) (int, error) {
+	expr = client.applyRegisteredType(client.metadataCacheKey(ctx, tableName), tableName, expr)
+
+	index, err := client.chooseIndex(ctx, tableName, expr)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.countOnIndex(ctx, tableName, expr.boundToIndex(index), index)
+}
+
+// CountOrFetch counts the items in the table that match the expression and, if there are fewer
+// than threshold items, fetches them all. Fetched items are unmarshaled into returnItems, which
+// should be a pointer to a slice of items with dynamodbav attribute tags, and the returned parser
+// is nil. Otherwise, returnItems is left unchanged and a parser is returned to stream the items.
+// In both cases, the index is selected once and used for both the count and the items.
+func (client *Client) CountOrFetch(ctx context.Context, tableName string, expr *Expression,
+	threshold int, returnItems interface{}) (int, *Parser, error) {
+
+	plan, err := client.Plan(ctx, tableName, expr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	count, err := client.countOnIndex(ctx, tableName, plan.expr, plan.index)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	parser := plan.Run(nil)
+	if count >= threshold {
+		return count, parser, nil
+	}
+
+	items := []map[string]*dynamodb.AttributeValue{}
+	for {
+		page, err := parser.fetchNextPage(ctx)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			return 0, nil, err
+		}
+		items = append(items, page.items...)
+	}
+
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, returnItems); err != nil {
+		return 0, nil, err
+	}
+
+	return count, nil, nil
+}
+
+func (client *Client) countOnIndex(ctx context.Context, tableName string, expr *Expression,
+	index *tableIndex) (int, error) {
+
+	queryInput, err := expr.constructQueryInputGivenIndex(index)
+	if err != nil {
+		return 0, err
+	}
+
+	// count queries cannot be combined with a projection; an excluded sort key bound is queried
+	// inclusively, so its items must be returned to discard those on the bound
+	excludesBounds := expr.excludedSortKeyBounds(index) != nil
+	queryInput.TableName = aws.String(tableName)
+	queryInput.ProjectionExpression = nil
+	if !excludesBounds {
+		queryInput.Select = aws.String(dynamodb.SelectCount)
+	}
+	pruneUnusedPlaceholders(queryInput)
+
+	ctx = withPerCallTimeout(ctx, expr.perCallTimeout)
+	count := 0
+	for {
+		queryOutput, err := client.query(ctx, queryInput, requestOptionsFromContext(ctx)...)
+		if err != nil {
+			return 0, err
+		}
+		if excludesBounds {
+			items, err := expr.discardExcludedBounds(index, queryOutput.Items)
+			if err != nil {
+				return 0, err
+			}
+			count += len(items)
+		} else {
+			count += int(aws.Int64Value(queryOutput.Count))
+		}
+
+		if len(queryOutput.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+		queryInput.ExclusiveStartKey = queryOutput.LastEvaluatedKey
+	}
+}
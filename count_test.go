@@ -0,0 +1,100 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCountExcludesBounds(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 6; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: id * 10})
+	}
+	client := NewClient(newOrdersService(t, orders...))
+
+	testCases := []struct {
+		name          string
+		lowInclusive  bool
+		highInclusive bool
+		expected      int
+	}{
+		{"inclusive", true, true, 4},
+		{"exclusive low", false, true, 3},
+		{"exclusive high", true, false, 3},
+		{"exclusive", false, false, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := NewExpression().Equal("customer", "c").
+				BetweenBounds("id", 2, 5, tc.lowInclusive, tc.highInclusive)
+
+			count, err := client.Count(context.Background(), "Orders", expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tc.expected {
+				t.Errorf("expected count %d, got %d", tc.expected, count)
+			}
+		})
+	}
+}
+
+func TestCountOrFetch(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 6; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: id * 10})
+	}
+	client := NewClient(newOrdersService(t, orders...))
+
+	testCases := []struct {
+		name          string
+		threshold     int
+		lowInclusive  bool
+		expectFetched bool
+		expectedIDs   []int
+	}{
+		{"small fetches items", 5, true, true, []int{2, 3, 4, 5}},
+		{"large streams items", 4, true, false, []int{2, 3, 4, 5}},
+		{"excluded bound below threshold", 4, false, true, []int{3, 4, 5}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := NewExpression().Equal("customer", "c").
+				BetweenBounds("id", 2, 5, tc.lowInclusive, true)
+
+			fetched := []testOrder{}
+			count, parser, err := client.CountOrFetch(
+				context.Background(), "Orders", expr, tc.threshold, &fetched)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != len(tc.expectedIDs) {
+				t.Errorf("expected count %d, got %d", len(tc.expectedIDs), count)
+			}
+
+			ids := []int{}
+			if tc.expectFetched {
+				if parser != nil {
+					t.Fatal("expected no parser when items are fetched")
+				}
+				for _, order := range fetched {
+					ids = append(ids, order.ID)
+				}
+			} else {
+				if parser == nil {
+					t.Fatal("expected parser to stream items")
+				}
+				if len(fetched) != 0 {
+					t.Errorf("expected no fetched items, got %v", fetched)
+				}
+				ids = parseOrderIDs(t, parser)
+			}
+			if !reflect.DeepEqual(ids, tc.expectedIDs) {
+				t.Errorf("expected %v, got %v", tc.expectedIDs, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package autoquery
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Deduplicate causes items merged from multiple queries to be returned at most once, identified by
+// the table's primary key. This applies to the sub-range queries of ParallelRange, where an item
+// whose sort key is updated during the query may be read by more than one sub-range. Items are
+// returned in their first position, and the table's key attributes are added to the selected
+// attributes of the merged queries if attributes are specified, so items are deduplicated even if
+// the key attributes are not selected. With StrictProjection, the added key attributes are removed
+// from items after they are deduplicated.
+//
+// If maxKeys is greater than 0, at most maxKeys primary keys are remembered, with the oldest keys
+// forgotten first, bounding the memory used by large queries at the cost of allowing duplicates
+// separated by more than maxKeys items. If maxKeys is 0, every returned key is remembered. If
+// maxKeys is less than 0, Parser.Next returns an error.
+//
+// QueryReadYourWrites always merges its queries by primary key, so Deduplicate has no effect on
+// it, and queries from a single source are never deduplicated.
+func (expr *Expression) Deduplicate(maxKeys int) *Expression {
+	if maxKeys < 0 {
+		expr.setErr(fmt.Errorf("deduplicate max keys must not be negative: %d", maxKeys))
+		return expr
+	}
+	expr.deduplicate = true
+	expr.deduplicateMaxKeys = maxKeys
+	return expr
+}
+
+// deduplicatePage returns a copy of the page without items whose primary keys have already been
+// returned by the parser
+func (parser *Parser) deduplicatePage(page *queryPage) *queryPage {
+	deduplicated := &queryPage{
+		items:            []map[string]*dynamodb.AttributeValue{},
+		consumedCapacity: page.consumedCapacity,
+	}
+	for _, item := range page.items {
+		if parser.seenKeys.add(itemKeyString(item, parser.primaryKeys)) {
+			deduplicated.items = append(deduplicated.items, item)
+		}
+	}
+	return deduplicated
+}
+
+// keySet is a set of item key strings which holds at most capacity keys, forgetting the oldest key
+// when full. A capacity of 0 holds any number of keys.
+type keySet struct {
+	keys map[string]struct{}
+
+	// order is a ring of the held keys in insertion order when capacity is set
+	capacity int
+	order    []string
+	next     int
+}
+
+func newKeySet(capacity int) *keySet {
+	return &keySet{
+		keys:     map[string]struct{}{},
+		capacity: capacity,
+	}
+}
+
+// add adds the key to the set, returning false if the key was already held
+func (set *keySet) add(key string) bool {
+	if _, found := set.keys[key]; found {
+		return false
+	}
+
+	if set.capacity > 0 {
+		if len(set.order) < set.capacity {
+			set.order = append(set.order, key)
+		} else {
+			delete(set.keys, set.order[set.next])
+			set.order[set.next] = key
+			set.next = (set.next + 1) % set.capacity
+		}
+	}
+	set.keys[key] = struct{}{}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/dgravesa/dynamodb-autoquery/autoquerytest"
+)
+
+// withMovedOrder returns a query function which adds order 10 of customer c to the items of every
+// query which does not already return it, as if its sort key moved between sub-range queries
+func withMovedOrder(service *autoquerytest.Service) QueryFunc {
+	movedOrder := map[string]*dynamodb.AttributeValue{
+		"customer": {S: aws.String("c")},
+		"id":       {N: aws.String("10")},
+		"status":   {S: aws.String("open")},
+		"total":    {N: aws.String("0")},
+	}
+
+	return func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		output, err := service.QueryWithContext(ctx, input, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range output.Items {
+			if aws.StringValue(it["id"].N) == "10" {
+				return output, nil
+			}
+		}
+		output.Items = append(output.Items, copyTestItem(movedOrder))
+		return output, nil
+	}
+}
+
+func TestDeduplicateOverlappingSources(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 20; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open"})
+	}
+	service := newOrdersService(t, orders...)
+
+	client := NewClient(service)
+	client.QueryFunc = withMovedOrder(service)
+
+	expected := []int{}
+	for id := 1; id <= 20; id++ {
+		expected = append(expected, id)
+	}
+
+	expr := func() *Expression {
+		return NewExpression().Equal("customer", "c").Between("id", 1, 20).ParallelRange(4)
+	}
+
+	duplicated := parseOrderIDs(t, client.Query("Orders", expr()))
+	if len(duplicated) <= len(expected) {
+		t.Fatalf("expected overlapping sources to duplicate order 10, got %v", duplicated)
+	}
+
+	ids := parseOrderIDs(t, client.Query("Orders", expr().Deduplicate(0)))
+	sort.Ints(ids)
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected each order once, got %v", ids)
+	}
+}
+
+func TestDeduplicateWithoutSelectedKeys(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 20; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: id})
+	}
+	service := newOrdersService(t, orders...)
+	client := NewClient(service)
+	client.QueryFunc = withMovedOrder(service)
+
+	// the selected attributes omit the table's keys, which are projected to deduplicate items and
+	// then stripped
+	expr := NewExpression().Equal("customer", "c").Between("id", 1, 20).ParallelRange(4).
+		Deduplicate(0).Select("total").StrictProjection()
+	parser := client.Query("Orders", expr)
+
+	totals := []int{}
+	for {
+		item := map[string]interface{}{}
+		err := parser.Next(context.Background(), &item)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if len(item) != 1 {
+			t.Errorf("expected only the selected attribute, got %v", item)
+		}
+		totals = append(totals, int(item["total"].(float64)))
+	}
+
+	if len(totals) != 20 {
+		t.Errorf("expected 20 items, got %d: %v", len(totals), totals)
+	}
+}
+
+func TestKeySetCapacity(t *testing.T) {
+	set := newKeySet(2)
+
+	testCases := []struct {
+		key      string
+		expected bool
+	}{
+		{"a", true},
+		{"b", true},
+		{"a", false},
+		{"c", true},
+		// a is forgotten once c is added to the full set
+		{"a", true},
+		{"c", false},
+	}
+
+	for i, tc := range testCases {
+		if added := set.add(tc.key); added != tc.expected {
+			t.Errorf("add %d: expected %v adding %s, got %v", i, tc.expected, tc.key, added)
+		}
+	}
+	if len(set.keys) != 2 {
+		t.Errorf("expected 2 held keys, got %d", len(set.keys))
+	}
+}
+
+func TestDeduplicateNegativeMaxKeys(t *testing.T) {
+	err := NewExpression().Deduplicate(-1).error()
+	expected := "deduplicate max keys must not be negative: -1"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
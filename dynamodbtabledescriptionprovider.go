@@ -5,16 +5,15 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
 type dynamoDBTableDescriptionProvider struct {
-	dynamodbService dynamodbiface.DynamoDBAPI
+	describeTable DescribeTableFunc
 }
 
-func newDefaultDescriptionProvider(service dynamodbiface.DynamoDBAPI) *dynamoDBTableDescriptionProvider {
+func newDefaultDescriptionProvider(describeTable DescribeTableFunc) *dynamoDBTableDescriptionProvider {
 	return &dynamoDBTableDescriptionProvider{
-		dynamodbService: service,
+		describeTable: describeTable,
 	}
 }
 
@@ -25,7 +24,7 @@ func (p *dynamoDBTableDescriptionProvider) Get(
 	describeInput := &dynamodb.DescribeTableInput{
 		TableName: aws.String(tableName),
 	}
-	describeOutput, err := p.dynamodbService.DescribeTableWithContext(ctx, describeInput)
+	describeOutput, err := p.describeTable(ctx, describeInput, requestOptionsFromContext(ctx)...)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,9 @@ package autoquery
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
 // ErrParsingComplete is returned by Parser.Next when all query items have been returned or when
@@ -17,7 +20,8 @@ func (e ErrParsingComplete) Error() string {
 
 // ErrNoViableIndexes is returned by Client.Query when no table indexes are usable for the
 // requested expression. The string returned by ErrNoViableIndexes.Error includes reasons why each
-// index is considered non-viable.
+// index is considered non-viable. When returned by the default index selector, IndexErrs is ordered
+// by index name with the primary index first, so the error is stable across queries.
 type ErrNoViableIndexes struct {
 	IndexErrs []*ErrIndexNotViable
 }
@@ -51,3 +55,129 @@ type ErrItemNotFound struct{}
 func (ErrItemNotFound) Error() string {
 	return "item not found"
 }
+
+// ErrNoItems is returned by Latest when no items match the expression.
+type ErrNoItems struct{}
+
+func (ErrNoItems) Error() string {
+	return "no items match expression"
+}
+
+// ErrDescribeTableAccessDenied is returned when the table's index metadata cannot be retrieved
+// because the metadata provider was denied permission to describe the table. The error returned by
+// the provider is available through errors.Unwrap.
+type ErrDescribeTableAccessDenied struct {
+	TableName string
+	Err       error
+}
+
+func (e ErrDescribeTableAccessDenied) Error() string {
+	return fmt.Sprintf("access denied describing table %s; grant dynamodb:DescribeTable "+
+		"permission or create the client with NewClientWithMetadataProvider using a static table "+
+		"description: %s", e.TableName, e.Err)
+}
+
+func (e ErrDescribeTableAccessDenied) Unwrap() error {
+	return e.Err
+}
+
+// ErrTableNotActive is returned when the table's description reports a status in which the table
+// cannot be queried, such as CREATING or DELETING. The table's metadata is not cached, so it is
+// retrieved again by subsequent queries.
+type ErrTableNotActive struct {
+	TableName string
+	Status    string
+}
+
+func (e ErrTableNotActive) Error() string {
+	return fmt.Sprintf("table %s is not active: status is %s", e.TableName, e.Status)
+}
+
+// ErrUnknownScope is returned by a ContextDescriptionProvider when no provider is registered for
+// the scope of the request context.
+type ErrUnknownScope struct {
+	Scope string
+}
+
+func (e ErrUnknownScope) Error() string {
+	return fmt.Sprintf("no table description provider for scope: %q", e.Scope)
+}
+
+// ErrCallTimeout is returned when a DynamoDB call exceeds the expression's per-call timeout on
+// every attempt. The error returned by the final attempt is available through errors.Unwrap.
+type ErrCallTimeout struct {
+	Timeout  time.Duration
+	Attempts int
+	Err      error
+}
+
+func (e ErrCallTimeout) Error() string {
+	return fmt.Sprintf("call exceeded per-call timeout of %s (attempts: %d): %s",
+		e.Timeout, e.Attempts, e.Err)
+}
+
+func (e ErrCallTimeout) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnexpectedIndex is returned when the index selected for an expression is not the index named
+// by Expression.ExpectIndex.
+type ErrUnexpectedIndex struct {
+	TableName     string
+	ExpectedIndex string
+	SelectedIndex string
+}
+
+func (e ErrUnexpectedIndex) Error() string {
+	return fmt.Sprintf("expected index %s to be selected on table %s, but index %s was selected",
+		e.ExpectedIndex, e.TableName, e.SelectedIndex)
+}
+
+// ErrPartitionScan is returned when Client.DisallowScan is set and a query would read an entire
+// partition of the selected index, narrowing its items only with filter conditions.
+type ErrPartitionScan struct {
+	TableName string
+	IndexName string
+}
+
+func (e ErrPartitionScan) Error() string {
+	return fmt.Sprintf("query on index %s of table %s reads the entire partition without a sort "+
+		"key condition", e.IndexName, e.TableName)
+}
+
+// ErrDuplicateIndex is returned when a table's description lists more than one index with the same
+// name, such as from a misconfigured static table description provider. The table's metadata is
+// not cached.
+type ErrDuplicateIndex struct {
+	TableName string
+	IndexName string
+}
+
+func (e ErrDuplicateIndex) Error() string {
+	return fmt.Sprintf("table %s has more than one index named %s", e.TableName, e.IndexName)
+}
+
+// ErrInvalidQuery is returned when DynamoDB rejects a generated query input with a
+// ValidationException, such as from an unusual schema or an unsupported combination of conditions.
+// It includes the expressions that were sent so the malformed input may be identified. The error
+// returned by DynamoDB is available through errors.Unwrap.
+type ErrInvalidQuery struct {
+	TableName                 string
+	IndexName                 string
+	KeyConditionExpression    string
+	FilterExpression          string
+	ProjectionExpression      string
+	ExpressionAttributeNames  map[string]*string
+	ExpressionAttributeValues map[string]*dynamodb.AttributeValue
+	Err                       error
+}
+
+func (e ErrInvalidQuery) Error() string {
+	return fmt.Sprintf("query on table %s was rejected (index: %q, key condition: %q, filter: %q, "+
+		"projection: %q): %s", e.TableName, e.IndexName, e.KeyConditionExpression,
+		e.FilterExpression, e.ProjectionExpression, e.Err)
+}
+
+func (e ErrInvalidQuery) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,99 @@
+package autoquery
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// betweenCondition returns the filter condition for a range on a non-key attribute
+func betweenCondition(name expression.NameBuilder, f *betweenFilter) expression.ConditionBuilder {
+	if !f.excludeLow && !f.excludeHigh {
+		return name.Between(expression.Value(f.lowval), expression.Value(f.highval))
+	}
+
+	lowCondition := name.GreaterThanEqual(expression.Value(f.lowval))
+	if f.excludeLow {
+		lowCondition = name.GreaterThan(expression.Value(f.lowval))
+	}
+	highCondition := name.LessThanEqual(expression.Value(f.highval))
+	if f.excludeHigh {
+		highCondition = name.LessThan(expression.Value(f.highval))
+	}
+
+	return lowCondition.And(highCondition)
+}
+
+// excludedSortKeyBounds returns the expression's range condition on the index's sort key if either
+// of its bounds is excluded, or nil otherwise
+func (expr *Expression) excludedSortKeyBounds(index *tableIndex) *betweenFilter {
+	if !index.IsComposite {
+		return nil
+	}
+	f, ok := expr.filters[index.SortKey].(*betweenFilter)
+	if !ok || (!f.excludeLow && !f.excludeHigh) {
+		return nil
+	}
+	return f
+}
+
+// discardExcludedBounds removes items whose sort key equals a bound that is excluded from the
+// expression's range condition on the index's sort key, which is queried with an inclusive key
+// condition
+func (expr *Expression) discardExcludedBounds(index *tableIndex,
+	items []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+
+	f := expr.excludedSortKeyBounds(index)
+	if f == nil {
+		return items, nil
+	}
+
+	excludedValues := []*dynamodb.AttributeValue{}
+	for _, bound := range []struct {
+		value    interface{}
+		excluded bool
+	}{{f.lowval, f.excludeLow}, {f.highval, f.excludeHigh}} {
+		if bound.excluded {
+			excludedValue, err := dynamodbattribute.Marshal(bound.value)
+			if err != nil {
+				return nil, err
+			}
+			excludedValues = append(excludedValues, excludedValue)
+		}
+	}
+
+	remainingItems := []map[string]*dynamodb.AttributeValue{}
+	for _, item := range items {
+		excluded := false
+		for _, excludedValue := range excludedValues {
+			if keyValuesEqual(item[index.SortKey], excludedValue) {
+				excluded = true
+			}
+		}
+		if !excluded {
+			remainingItems = append(remainingItems, item)
+		}
+	}
+
+	return remainingItems, nil
+}
+
+// keyValuesEqual returns true if the key attribute values are equal, comparing numbers by value
+func keyValuesEqual(a, b *dynamodb.AttributeValue) bool {
+	switch {
+	case a == nil || b == nil:
+		return false
+	case a.S != nil && b.S != nil:
+		return *a.S == *b.S
+	case a.B != nil && b.B != nil:
+		return bytes.Equal(a.B, b.B)
+	case a.N != nil && b.N != nil:
+		aNumber, aOk := new(big.Float).SetString(*a.N)
+		bNumber, bOk := new(big.Float).SetString(*b.N)
+		return aOk && bOk && aNumber.Cmp(bNumber) == 0
+	}
+	return false
+}
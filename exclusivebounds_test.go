@@ -0,0 +1,46 @@
+package autoquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBetweenBounds(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 6; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: id * 10})
+	}
+	service := newOrdersService(t, orders...)
+	client := NewClient(service)
+
+	testCases := []struct {
+		name          string
+		attr          string
+		low, high     int
+		lowInclusive  bool
+		highInclusive bool
+		expected      []int
+	}{
+		{"sort key inclusive", "id", 2, 5, true, true, []int{2, 3, 4, 5}},
+		{"sort key exclusive low", "id", 2, 5, false, true, []int{3, 4, 5}},
+		{"sort key exclusive high", "id", 2, 5, true, false, []int{2, 3, 4}},
+		{"sort key exclusive", "id", 2, 5, false, false, []int{3, 4}},
+		{"sort key exclusive adjacent", "id", 2, 3, false, false, []int{}},
+		{"filter inclusive", "total", 20, 50, true, true, []int{2, 3, 4, 5}},
+		{"filter exclusive low", "total", 20, 50, false, true, []int{3, 4, 5}},
+		{"filter exclusive high", "total", 20, 50, true, false, []int{2, 3, 4}},
+		{"filter exclusive", "total", 20, 50, false, false, []int{3, 4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := NewExpression().Equal("customer", "c").
+				BetweenBounds(tc.attr, tc.low, tc.high, tc.lowInclusive, tc.highInclusive)
+
+			ids := parseOrderIDs(t, client.Query("Orders", expr))
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
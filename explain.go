@@ -0,0 +1,205 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// QueryExplanation describes the request constructed for an expression, including the exact
+// expression strings and placeholder maps that are sent to DynamoDB.
+type QueryExplanation struct {
+	TableName string
+
+	// IndexName is the name of the selected index. The table's primary index is identified by
+	// PrimaryIndexName, or by the client's PrimaryIndexLabel if set.
+	IndexName string
+
+	KeyConditionExpression    string
+	FilterExpression          string
+	ProjectionExpression      string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]*dynamodb.AttributeValue
+
+	// Statement and Parameters are set instead of the expression strings and placeholder maps when
+	// the expression is executed with PartiQL.
+	Statement  string
+	Parameters []*dynamodb.AttributeValue
+
+	ConsistentRead   bool
+	ScanIndexForward *bool
+
+	// Candidates lists every index of the table as it was considered for the expression, including
+	// each viable index's score and estimated read cost, in the order primary index, global
+	// secondary indexes, then local secondary indexes.
+	Candidates []IndexCandidate
+}
+
+// Explain selects an index for the parser's query and returns a description of the request that
+// is sent to DynamoDB for the first page, along with the candidate indexes that were considered.
+// Explain does not execute the query, so it may be used as a dry run to compare the estimated
+// read costs of viable indexes.
+//
+// As with Next, the table's index metadata is retrieved using the underlying metadata provider if
+// it is not already cached.
+func (parser *Parser) Explain(ctx context.Context) (*QueryExplanation, error) {
+	index, expr, err := parser.explainedIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, candidates, _, err := parser.client.indexCandidates(ctx, parser.tableName, parser.expr)
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		candidates[i].Name = parser.client.indexLabel(candidates[i].Name)
+	}
+
+	explanation := &QueryExplanation{
+		TableName:                 parser.tableName,
+		IndexName:                 parser.client.indexLabel(index.Name),
+		ExpressionAttributeNames:  map[string]string{},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{},
+		Candidates:                candidates,
+	}
+
+	if expr.usePartiQL {
+		statementInput, err := expr.constructStatementInputGivenIndex(
+			parser.tableName, index)
+		if err != nil {
+			return nil, err
+		}
+		explanation.Statement = *statementInput.Statement
+		explanation.Parameters = statementInput.Parameters
+		explanation.ConsistentRead = statementInput.ConsistentRead != nil &&
+			*statementInput.ConsistentRead
+		return explanation, nil
+	}
+
+	queryInput, err := expr.constructQueryInputGivenIndex(index)
+	if err != nil {
+		return nil, err
+	}
+
+	stringOrEmpty := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+
+	explanation.KeyConditionExpression = stringOrEmpty(queryInput.KeyConditionExpression)
+	explanation.FilterExpression = stringOrEmpty(queryInput.FilterExpression)
+	explanation.ProjectionExpression = stringOrEmpty(queryInput.ProjectionExpression)
+	for placeholder, name := range queryInput.ExpressionAttributeNames {
+		explanation.ExpressionAttributeNames[placeholder] = *name
+	}
+	for placeholder, value := range queryInput.ExpressionAttributeValues {
+		explanation.ExpressionAttributeValues[placeholder] = value
+	}
+	explanation.ConsistentRead = queryInput.ConsistentRead != nil && *queryInput.ConsistentRead
+	explanation.ScanIndexForward = queryInput.ScanIndexForward
+
+	return explanation, nil
+}
+
+// explainedIndex returns the index selected for the parser's query and the expression bound to
+// it, selecting the index as Validate does if the parser has not yet selected one, so that
+// explaining a query neither records index usage nor calls the client's selection hooks
+func (parser *Parser) explainedIndex(ctx context.Context) (*tableIndex, *Expression, error) {
+	if parser.selectedIndex != nil {
+		return parser.selectedIndex, parser.expr, nil
+	}
+
+	parser.applyRegisteredType(ctx)
+	if err := parser.expr.error(); err != nil {
+		return nil, nil, err
+	}
+
+	index, _, err := parser.client.selectIndex(ctx, parser.tableName, parser.expr)
+	if _, noViableIndexes := err.(*ErrNoViableIndexes); noViableIndexes &&
+		parser.switchToFallback() {
+
+		return parser.explainedIndex(ctx)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	expr := parser.expr.boundToIndex(index)
+	if err := parser.client.checkAccessPattern(parser.tableName, expr, index); err != nil {
+		return nil, nil, err
+	}
+
+	return index, expr, nil
+}
+
+// String returns a readable multi-line representation of the explanation.
+func (e QueryExplanation) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "TableName: %s\n", e.TableName)
+	fmt.Fprintf(&b, "IndexName: %s\n", e.IndexName)
+
+	if e.Statement != "" {
+		fmt.Fprintf(&b, "Statement: %s\n", e.Statement)
+		for i, parameter := range e.Parameters {
+			fmt.Fprintf(&b, "Parameter %d: %s\n", i+1, formatAttributeValue(parameter))
+		}
+	} else {
+		fmt.Fprintf(&b, "KeyConditionExpression: %s\n", e.KeyConditionExpression)
+		if e.FilterExpression != "" {
+			fmt.Fprintf(&b, "FilterExpression: %s\n", e.FilterExpression)
+		}
+		if e.ProjectionExpression != "" {
+			fmt.Fprintf(&b, "ProjectionExpression: %s\n", e.ProjectionExpression)
+		}
+		for _, placeholder := range sortedKeys(e.ExpressionAttributeNames) {
+			fmt.Fprintf(&b, "ExpressionAttributeName %s: %s\n",
+				placeholder, e.ExpressionAttributeNames[placeholder])
+		}
+		for _, placeholder := range sortedKeys(e.ExpressionAttributeValues) {
+			fmt.Fprintf(&b, "ExpressionAttributeValue %s: %s\n",
+				placeholder, formatAttributeValue(e.ExpressionAttributeValues[placeholder]))
+		}
+	}
+
+	fmt.Fprintf(&b, "ConsistentRead: %t\n", e.ConsistentRead)
+	if e.ScanIndexForward != nil {
+		fmt.Fprintf(&b, "ScanIndexForward: %t\n", *e.ScanIndexForward)
+	}
+
+	for _, candidate := range e.Candidates {
+		if candidate.NotViableErr != nil {
+			fmt.Fprintf(&b, "Candidate %s: not viable\n", candidate.Name)
+		} else if candidate.FetchesMissingAttributes {
+			fmt.Fprintf(&b, "Candidate %s: score %.3f, estimated read cost %.3f "+
+				"including fetch of missing attributes\n",
+				candidate.Name, candidate.Score, candidate.EstimatedReadCost)
+		} else {
+			fmt.Fprintf(&b, "Candidate %s: score %.3f, estimated read cost %.3f\n",
+				candidate.Name, candidate.Score, candidate.EstimatedReadCost)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func formatAttributeValue(value *dynamodb.AttributeValue) string {
+	// collapse the SDK's pretty-printed form onto a single line
+	return strings.Join(strings.Fields(value.String()), " ")
+}
+
+// sortedKeys returns the sorted keys of a map with string keys
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
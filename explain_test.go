@@ -0,0 +1,112 @@
+package autoquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestExplainMatchesQueryInput(t *testing.T) {
+	service := newFakeService(1)
+	client := NewClient(service)
+	ctx := context.Background()
+
+	expr := NewExpression().Equal("pk", "a").Between("sk", 1, 9).Equal("x", "y").Select("pk", "sk")
+	explanation, err := client.Query("T", expr).Explain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := client.Query("T", expr)
+	if _, err := parseSortKeys(ctx, parser); err != nil {
+		t.Fatal(err)
+	}
+	input := parser.LastRequest()
+
+	if explanation.KeyConditionExpression != aws.StringValue(input.KeyConditionExpression) {
+		t.Errorf("expected key condition %s, got %s",
+			aws.StringValue(input.KeyConditionExpression), explanation.KeyConditionExpression)
+	}
+	if explanation.FilterExpression != aws.StringValue(input.FilterExpression) {
+		t.Errorf("expected filter %s, got %s",
+			aws.StringValue(input.FilterExpression), explanation.FilterExpression)
+	}
+	if explanation.ProjectionExpression != aws.StringValue(input.ProjectionExpression) {
+		t.Errorf("expected projection %s, got %s",
+			aws.StringValue(input.ProjectionExpression), explanation.ProjectionExpression)
+	}
+	if len(explanation.ExpressionAttributeValues) != len(input.ExpressionAttributeValues) {
+		t.Errorf("expected %d values, got %d",
+			len(input.ExpressionAttributeValues), len(explanation.ExpressionAttributeValues))
+	}
+
+	// value placeholders are listed in sorted order
+	lastPlaceholder := ""
+	for _, line := range strings.Split(explanation.String(), "\n") {
+		if !strings.HasPrefix(line, "ExpressionAttributeValue ") {
+			continue
+		}
+		placeholder := strings.TrimSuffix(strings.Fields(line)[1], ":")
+		if placeholder < lastPlaceholder {
+			t.Errorf("expected sorted value placeholders, got %s after %s",
+				placeholder, lastPlaceholder)
+		}
+		lastPlaceholder = placeholder
+	}
+	if lastPlaceholder == "" {
+		t.Error("expected value placeholders in explanation")
+	}
+}
+
+func TestExplainSkipsUsageAndHooks(t *testing.T) {
+	client := NewClient(newFakeService(1))
+	expensiveQueries := 0
+	client.ExpensiveQuery = func(tableName, indexName string, estimatedItemsRead int) {
+		expensiveQueries++
+	}
+	ctx := context.Background()
+
+	expr := NewExpression().Equal("pk", "a").Equal("x", "y")
+	if _, err := client.Query("T", expr).Explain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if stats := client.IndexUsageStats(ctx, "T"); len(stats) != 0 {
+		t.Errorf("expected explain to record no index usage, got %v", stats)
+	}
+	if expensiveQueries != 0 {
+		t.Errorf("expected explain to call no hooks, got %d expensive queries", expensiveQueries)
+	}
+
+	if err := client.Query("T", expr).Prepare(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if stats := client.IndexUsageStats(ctx, "T"); stats[PrimaryIndexName] != 1 {
+		t.Errorf("expected query to record primary index usage, got %v", stats)
+	}
+	if expensiveQueries != 1 {
+		t.Errorf("expected query to call expensive query hook, got %d", expensiveQueries)
+	}
+}
+
+func TestExplainIsDeterministic(t *testing.T) {
+	client := NewClient(newFakeService(1))
+	ctx := context.Background()
+
+	expr := NewExpression().Equal("pk", "a").Equal("d", 4).Equal("c", 3).Equal("b", 2).Equal("a", 1)
+	first, err := client.Query("T", expr).Explain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		explanation, err := client.Query("T", expr).Explain(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if explanation.String() != first.String() {
+			t.Fatalf("expected identical explanations, got:\n%s\nand:\n%s", first, explanation)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package autoquery
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type queryExplanationJSON struct {
+	TableName                 string                         `json:"tableName"`
+	IndexName                 string                         `json:"indexName"`
+	KeyConditionExpression    string                         `json:"keyConditionExpression,omitempty"`
+	FilterExpression          string                         `json:"filterExpression,omitempty"`
+	ProjectionExpression      string                         `json:"projectionExpression,omitempty"`
+	ExpressionAttributeNames  map[string]string              `json:"expressionAttributeNames,omitempty"`
+	ExpressionAttributeValues map[string]*attributeValueJSON `json:"expressionAttributeValues,omitempty"`
+	Statement                 string                         `json:"statement,omitempty"`
+	Parameters                []*attributeValueJSON          `json:"parameters,omitempty"`
+	ConsistentRead            bool                           `json:"consistentRead"`
+	ScanIndexForward          *bool                          `json:"scanIndexForward,omitempty"`
+	Candidates                []IndexCandidate               `json:"candidates,omitempty"`
+}
+
+// attributeValueJSON is the DynamoDB JSON representation of an attribute value, in which only
+// the value's type is set, such as {"S": "value"}
+type attributeValueJSON struct {
+	B    []byte                         `json:"B,omitempty"`
+	BOOL *bool                          `json:"BOOL,omitempty"`
+	BS   [][]byte                       `json:"BS,omitempty"`
+	L    []*attributeValueJSON          `json:"L,omitempty"`
+	M    map[string]*attributeValueJSON `json:"M,omitempty"`
+	N    *string                        `json:"N,omitempty"`
+	NS   []*string                      `json:"NS,omitempty"`
+	NULL *bool                          `json:"NULL,omitempty"`
+	S    *string                        `json:"S,omitempty"`
+	SS   []*string                      `json:"SS,omitempty"`
+}
+
+// MarshalJSON returns the explanation as JSON, with attribute values in DynamoDB JSON form, such as
+// {"S": "value"}, so that query plans may be displayed or stored by external tools.
+func (e QueryExplanation) MarshalJSON() ([]byte, error) {
+	explanationJSON := queryExplanationJSON{
+		TableName:                e.TableName,
+		IndexName:                e.IndexName,
+		KeyConditionExpression:   e.KeyConditionExpression,
+		FilterExpression:         e.FilterExpression,
+		ProjectionExpression:     e.ProjectionExpression,
+		ExpressionAttributeNames: e.ExpressionAttributeNames,
+		Statement:                e.Statement,
+		ConsistentRead:           e.ConsistentRead,
+		ScanIndexForward:         e.ScanIndexForward,
+		Candidates:               e.Candidates,
+	}
+
+	if len(e.ExpressionAttributeValues) > 0 {
+		explanationJSON.ExpressionAttributeValues = map[string]*attributeValueJSON{}
+		for placeholder, value := range e.ExpressionAttributeValues {
+			explanationJSON.ExpressionAttributeValues[placeholder] = newAttributeValueJSON(value)
+		}
+	}
+	for _, parameter := range e.Parameters {
+		explanationJSON.Parameters = append(explanationJSON.Parameters,
+			newAttributeValueJSON(parameter))
+	}
+
+	return json.Marshal(explanationJSON)
+}
+
+// UnmarshalJSON parses an explanation from JSON produced by MarshalJSON.
+func (e *QueryExplanation) UnmarshalJSON(data []byte) error {
+	explanationJSON := queryExplanationJSON{}
+	if err := json.Unmarshal(data, &explanationJSON); err != nil {
+		return err
+	}
+
+	*e = QueryExplanation{
+		TableName:                 explanationJSON.TableName,
+		IndexName:                 explanationJSON.IndexName,
+		KeyConditionExpression:    explanationJSON.KeyConditionExpression,
+		FilterExpression:          explanationJSON.FilterExpression,
+		ProjectionExpression:      explanationJSON.ProjectionExpression,
+		ExpressionAttributeNames:  map[string]string{},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{},
+		Statement:                 explanationJSON.Statement,
+		ConsistentRead:            explanationJSON.ConsistentRead,
+		ScanIndexForward:          explanationJSON.ScanIndexForward,
+		Candidates:                explanationJSON.Candidates,
+	}
+
+	for placeholder, name := range explanationJSON.ExpressionAttributeNames {
+		e.ExpressionAttributeNames[placeholder] = name
+	}
+	for placeholder, value := range explanationJSON.ExpressionAttributeValues {
+		e.ExpressionAttributeValues[placeholder] = value.attributeValue()
+	}
+	for _, parameter := range explanationJSON.Parameters {
+		e.Parameters = append(e.Parameters, parameter.attributeValue())
+	}
+
+	return nil
+}
+
+func newAttributeValueJSON(value *dynamodb.AttributeValue) *attributeValueJSON {
+	if value == nil {
+		return nil
+	}
+
+	valueJSON := &attributeValueJSON{
+		B:    value.B,
+		BOOL: value.BOOL,
+		BS:   value.BS,
+		N:    value.N,
+		NS:   value.NS,
+		NULL: value.NULL,
+		S:    value.S,
+		SS:   value.SS,
+	}
+	for _, element := range value.L {
+		valueJSON.L = append(valueJSON.L, newAttributeValueJSON(element))
+	}
+	if value.M != nil {
+		valueJSON.M = map[string]*attributeValueJSON{}
+		for key, element := range value.M {
+			valueJSON.M[key] = newAttributeValueJSON(element)
+		}
+	}
+
+	return valueJSON
+}
+
+func (valueJSON *attributeValueJSON) attributeValue() *dynamodb.AttributeValue {
+	if valueJSON == nil {
+		return nil
+	}
+
+	value := &dynamodb.AttributeValue{
+		B:    valueJSON.B,
+		BOOL: valueJSON.BOOL,
+		BS:   valueJSON.BS,
+		N:    valueJSON.N,
+		NS:   valueJSON.NS,
+		NULL: valueJSON.NULL,
+		S:    valueJSON.S,
+		SS:   valueJSON.SS,
+	}
+	for _, element := range valueJSON.L {
+		value.L = append(value.L, element.attributeValue())
+	}
+	if valueJSON.M != nil {
+		value.M = map[string]*dynamodb.AttributeValue{}
+		for key, element := range valueJSON.M {
+			value.M[key] = element.attributeValue()
+		}
+	}
+
+	return value
+}
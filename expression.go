@@ -1,6 +1,11 @@
 package autoquery
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
@@ -10,16 +15,66 @@ import (
 type Expression struct {
 	filters map[string]conditionFilter
 
-	attributesSpecified bool
-	attributes          []string
+	// sortKeyPrefixes is a range condition on the sort key of whichever index is selected, which
+	// is bound to the index's sort key attribute when the index is scored or queried
+	sortKeyPrefixes    *betweenFilter
+	sortKeyPrefixesErr error
+
+	combineConditions bool
+
+	attributesSpecified  bool
+	attributes           []string
+	additionalAttributes []string
+
+	fetchMissingAttributes bool
+
+	entityType string
 
 	orderSpecified bool
 	orderAttribute string
 	orderAscending bool
 
-	consistentRead bool
+	consistentRead            bool
+	consistentReadIfSupported bool
+
+	latest bool
+
+	descendingOrder bool
+
+	parallelSegments int
+
+	deduplicate        bool
+	deduplicateMaxKeys int
+
+	maxPages int
+
+	strictProjection bool
+
+	perCallTimeout time.Duration
+
+	restrictedIndexes map[string]struct{}
+
+	expectedIndex string
+
+	returnConsumedCapacitySpecified bool
+	returnConsumedCapacity          string
+
+	usePartiQL bool
+
+	nameAliases map[string]string
 
 	additionalConditions []expression.ConditionBuilder
+
+	postFilters []func(map[string]*dynamodb.AttributeValue) bool
+
+	readTransforms []attributeTransform
+
+	// err records the first invalid condition, which is returned when the expression is used
+	err error
+
+	// filterErrs records invalid conditions by attribute, which are cleared when the attribute's
+	// condition is replaced
+	filterErrs map[string]error
 }
 
 // NewExpression creates a new Expression instance.
@@ -27,18 +82,45 @@ func NewExpression() *Expression {
 	return &Expression{
 		filters:              map[string]conditionFilter{},
 		attributes:           []string{},
+		nameAliases:          map[string]string{},
 		additionalConditions: []expression.ConditionBuilder{},
 	}
 }
 
+// CombineConditions combines conditions on the same attribute which are added to the expression
+// after the call, rather than replacing the earlier condition with the most recent one. Range
+// conditions (Equal, GreaterThan, GreaterThanEqual, LessThan, LessThanEqual, and ranges such as
+// Between) are intersected into a single condition, keeping the tighter of two bounds in the same
+// direction, so that a lower and an upper bound are combined into a range equivalent to
+// BetweenBounds, which may be used as a sort key condition. BeginsWith conditions are combined
+// with Equal conditions and with each other where one implies the other, and an Exists condition
+// is combined with any other condition.
+//
+// Other combinations, such as a BeginsWith condition and a range, are applied together as a
+// compound filter condition, so indexes which use the attribute as a sort key are not viable for
+// the expression. Contradictory conditions which no value can satisfy, such as Equal conditions
+// with different values or a lower bound above an upper bound, are rejected, and the query returns
+// an error.
+func (expr *Expression) CombineConditions() *Expression {
+	expr.combineConditions = true
+	return expr
+}
+
 // Equal adds a new equal condition to the expression. Only items where the value of the attribute
 // attr equals v will be returned. All query expressions require at least one equal condition
 // where the specified attribute attr is an index partition key.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
+//
+// Condition values are marshaled with dynamodbattribute.Marshal, so pointer values are
+// dereferenced and struct values are marshaled as maps, with the fields of embedded structs
+// flattened into the map. Since DynamoDB numbers are arbitrary-precision decimals, big.Int,
+// big.Float, and json.Number values are marshaled as exact numbers, and decimals which exceed the
+// precision of float64 should be given as dynamodbattribute.Number values. Integral float values
+// too large to be represented exactly cause Parser.Next to return an error.
 func (expr *Expression) Equal(attr string, v interface{}) *Expression {
-	expr.filters[attr] = &equalsFilter{value: v}
+	expr.setFilter(attr, &equalsFilter{value: v})
 	return expr
 }
 
@@ -46,9 +128,9 @@ func (expr *Expression) Equal(attr string, v interface{}) *Expression {
 // attribute attr is less than v will be returned.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
 func (expr *Expression) LessThan(attr string, v interface{}) *Expression {
-	expr.filters[attr] = &lessThanFilter{value: v}
+	expr.setFilter(attr, &lessThanFilter{value: v})
 	return expr
 }
 
@@ -56,9 +138,9 @@ func (expr *Expression) LessThan(attr string, v interface{}) *Expression {
 // the attribute attr is greater than v will be returned.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
 func (expr *Expression) GreaterThan(attr string, v interface{}) *Expression {
-	expr.filters[attr] = &greaterThanFilter{value: v}
+	expr.setFilter(attr, &greaterThanFilter{value: v})
 	return expr
 }
 
@@ -66,9 +148,9 @@ func (expr *Expression) GreaterThan(attr string, v interface{}) *Expression {
 // value of the attribute attr is less than or equal to v will be returned.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
 func (expr *Expression) LessThanEqual(attr string, v interface{}) *Expression {
-	expr.filters[attr] = &lessThanEqualFilter{value: v}
+	expr.setFilter(attr, &lessThanEqualFilter{value: v})
 	return expr
 }
 
@@ -76,9 +158,9 @@ func (expr *Expression) LessThanEqual(attr string, v interface{}) *Expression {
 // the value of the attribute attr is greater than or equal to v will be returned.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
 func (expr *Expression) GreaterThanEqual(attr string, v interface{}) *Expression {
-	expr.filters[attr] = &greaterThanEqualFilter{value: v}
+	expr.setFilter(attr, &greaterThanEqualFilter{value: v})
 	return expr
 }
 
@@ -86,9 +168,44 @@ func (expr *Expression) GreaterThanEqual(attr string, v interface{}) *Expression
 // attribute attr is between lowval and highval will be returned.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
 func (expr *Expression) Between(attr string, lowval, highval interface{}) *Expression {
-	expr.filters[attr] = &betweenFilter{lowval: lowval, highval: highval}
+	expr.setFilter(attr, &betweenFilter{lowval: lowval, highval: highval})
+	return expr
+}
+
+// BetweenBounds adds a new range condition to the expression with configurable bounds. Only
+// items where the value of the attribute attr is between lowval and highval will be returned,
+// where each bound is included only if lowInclusive or highInclusive is true, respectively.
+// BetweenBounds(attr, lowval, highval, true, true) is equivalent to Between(attr, lowval, highval).
+//
+// DynamoDB only supports inclusive ranges in key conditions and does not allow key attributes in
+// filter expressions. When the attribute is the sort key of the selected index, the range is
+// queried with an inclusive BETWEEN key condition and items equal to an excluded bound are
+// discarded by the parser after they are read. Otherwise, the range is applied as a filter
+// condition using greater than and less than comparisons for excluded bounds.
+func (expr *Expression) BetweenBounds(attr string, lowval, highval interface{},
+	lowInclusive, highInclusive bool) *Expression {
+
+	expr.setFilter(attr, &betweenFilter{
+		lowval:      lowval,
+		highval:     highval,
+		excludeLow:  !lowInclusive,
+		excludeHigh: !highInclusive,
+	})
+	return expr
+}
+
+// Exists adds a new attribute exists condition to the expression. Only items which have the
+// attribute attr will be returned.
+//
+// An exists condition on the sort key of a sparse secondary index satisfies the index's
+// requirement for a sort key condition, since only items with the sort key are in the index. Key
+// attributes are present on every item of an index, so when attr is a key of the selected index,
+// the condition is satisfied by the query itself and no condition is sent to DynamoDB. Otherwise,
+// the condition is applied as a filter condition.
+func (expr *Expression) Exists(attr string) *Expression {
+	expr.setFilter(attr, &existsFilter{})
 	return expr
 }
 
@@ -96,9 +213,56 @@ func (expr *Expression) Between(attr string, lowval, highval interface{}) *Expre
 // the attribute attr begins with the specified prefix will be returned.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
 func (expr *Expression) BeginsWith(attr string, prefix string) *Expression {
-	expr.filters[attr] = &beginsWithFilter{prefix: prefix}
+	expr.setFilter(attr, &beginsWithFilter{prefix: prefix})
+	return expr
+}
+
+// TimeRange adds a new between condition to the expression on a numeric sort key attribute
+// which stores times as Unix epoch seconds. Only items where the value of the attribute attr is
+// between from and to (inclusive) will be returned.
+//
+// Only indexes which use attr as a numeric sort key, or a sort key of unknown type, are viable for
+// the expression. If to is before from, Parser.Next returns an error, unless the condition is
+// replaced by a later condition on attr.
+//
+// If multiple filter conditions are specified on the same attribute, only the most recent
+// condition will apply to the expression, unless CombineConditions is used.
+func (expr *Expression) TimeRange(attr string, from, to time.Time) *Expression {
+	expr.setFilter(attr, &betweenFilter{
+		lowval:                 from.Unix(),
+		highval:                to.Unix(),
+		requiresNumericSortKey: true,
+	})
+	if to.Before(from) {
+		expr.setFilterErr(attr, fmt.Errorf("time range on attribute %s ends before it begins", attr))
+	}
+	return expr
+}
+
+// WhereSortKeyBetweenPrefixes adds a new condition to the expression on the sort key of the
+// selected index, for sort keys which store hierarchical string values. Only items whose sort key
+// is lexicographically between low and any value beginning with high will be returned, which
+// selects a range of hierarchical sort keys such as "2024-01" through "2024-03#...".
+//
+// Only indexes with a string sort key, or a sort key of unknown type, and no other condition on
+// their sort key are viable for the expression. If low is lexicographically greater than every
+// value beginning with high, Parser.Next returns an error, unless the condition is replaced by a
+// later call to WhereSortKeyBetweenPrefixes.
+func (expr *Expression) WhereSortKeyBetweenPrefixes(low, high string) *Expression {
+	// U+10FFFF has the greatest UTF-8 encoding, so appending it bounds all values beginning with
+	// high except those which themselves continue with U+10FFFF
+	expr.sortKeyPrefixes = &betweenFilter{
+		lowval:                low,
+		highval:               high + "\U0010FFFF",
+		requiresStringSortKey: true,
+	}
+	expr.sortKeyPrefixesErr = nil
+	if high+"\U0010FFFF" < low {
+		expr.sortKeyPrefixesErr = fmt.Errorf(
+			"sort key prefix range ends before it begins: %s, %s", low, high)
+	}
 	return expr
 }
 
@@ -113,26 +277,143 @@ func (expr *Expression) OrderBy(attr string, ascending bool) *Expression {
 	return expr
 }
 
+// Latest restricts the expression to indexes with a sort key and returns items in descending
+// sort key order, so that the first item returned is the item with the highest sort key value in
+// the partition. Latest overrides the direction of any OrderBy clause on the expression.
+//
+// Client.Latest may be used to retrieve only the latest item.
+func (expr *Expression) Latest() *Expression {
+	expr.latest = true
+	return expr
+}
+
+// Descending returns items in descending order of the selected index's sort key, without naming
+// the sort key attribute, so that it applies to whichever index is selected. Descending overrides
+// the direction of any OrderBy clause on the expression. Unlike Latest, Descending does not
+// restrict index selection; if the selected index has no sort key, the query returns an error.
+func (expr *Expression) Descending() *Expression {
+	expr.descendingOrder = true
+	return expr
+}
+
+// RestrictToIndexes restricts index selection to the named indexes, so that the best viable index
+// among them is selected. The table's primary index may be named with PrimaryIndexName, or with
+// the client's PrimaryIndexLabel if set. Subsequent calls to RestrictToIndexes add to the existing
+// restricted indexes. If none of the restricted indexes are viable for the expression, the query
+// returns an ErrNoViableIndexes error.
+func (expr *Expression) RestrictToIndexes(names ...string) *Expression {
+	restrictedIndexes := map[string]struct{}{}
+	for name := range expr.restrictedIndexes {
+		restrictedIndexes[name] = struct{}{}
+	}
+	for _, name := range names {
+		restrictedIndexes[name] = struct{}{}
+	}
+	expr.restrictedIndexes = restrictedIndexes
+	return expr
+}
+
+// ParallelRange splits a Between condition on a numeric sort key into the given number of
+// contiguous sub-ranges that are queried concurrently. Items from each sub-range are returned in
+// sort key order, so the parsed results match those of a single query over the whole range. This
+// may improve read throughput for large partitions such as time series, at the cost of buffering
+// each sub-range's items in memory until they are returned.
+//
+// The split only applies when the selected index has a sort key and the expression's condition on
+// that sort key is a Between or TimeRange with integer bounds; otherwise the query runs as a
+// single range. Max pagination, prefetch, and exclusive start keys are not applied to split
+// queries, and the limit per page applies to each sub-range query.
+func (expr *Expression) ParallelRange(segments int) *Expression {
+	expr.parallelSegments = segments
+	return expr
+}
+
+// MaxPages limits a query to n page requests, after which Parser.Next returns ErrParsingComplete
+// even if more items remain, protecting against unbounded queries. When the limit stops a query
+// before all items are parsed, Parser.NextToken returns the key from which the query may be
+// resumed. If the parser's max pagination is also set with Parser.SetMaxPagination, the parser's
+// setting takes precedence. If n is less than 1, Parser.Next returns an error.
+func (expr *Expression) MaxPages(n int) *Expression {
+	if n < 1 {
+		expr.setErr(fmt.Errorf("max pages must be at least 1: %d", n))
+		return expr
+	}
+	expr.maxPages = n
+	return expr
+}
+
 // Select specifies attributes that should be returned in queried items. Subsequent calls to
 // Select will append to the existing selected attributes for the expression.
 //
 // If Select is not specified for an expression, the query will project all attributes for each
 // returned item, but can only use indexes which project all attributes. When Select is specified,
 // any indexes which include every selected attribute and satisfy all other expression criteria
-// will be considered for the query index.
+// will be considered for the query index. The key attributes of the selected index and of the
+// table are also projected and included in returned items, unless StrictProjection is used.
 func (expr *Expression) Select(attrs ...string) *Expression {
 	expr.attributesSpecified = true
 	expr.attributes = append(expr.attributes, attrs...)
 	return expr
 }
 
+// SelectAlso specifies attributes that should be returned in queried items in addition to the
+// selected attributes, rather than in place of them. When the table has a type registered with
+// Client.RegisterType and the expression does not call Select, the attributes are added to the
+// registered type's attributes, and they are not required to be attributes of the type. If no
+// attributes are selected and no type is registered, all attributes are already returned and
+// SelectAlso has no effect.
+//
+// The union of selected and additional attributes is de-duplicated, and only indexes which
+// project every attribute in the union are viable for the expression.
+func (expr *Expression) SelectAlso(attrs ...string) *Expression {
+	expr.additionalAttributes = append(expr.additionalAttributes, attrs...)
+	return expr
+}
+
 // ConsistentRead sets the read consistency of each query page request.
 // Note that consistent read only guarantees consistency within each page.
 // Consistent read is not supported across all items in the query when pagination is required
 // to parse all items (i.e. when the query evaluates more than 1MB of data).
-// Consistent read is not supported on global secondary indexes.
+// Consistent read is not supported on global secondary indexes, which are only viable for a
+// consistent read expression if the client's ConsistencyPolicy is AutoDowngrade. ConsistentRead
+// is equivalent to ReadConsistency with ConsistentRequired if val is true, or EventuallyConsistent
+// otherwise.
 func (expr *Expression) ConsistentRead(val bool) *Expression {
 	expr.consistentRead = val
+	expr.consistentReadIfSupported = false
+	return expr
+}
+
+// ReturnConsumedCapacity sets the ReturnConsumedCapacity parameter of each query page request,
+// overriding the default set on the client. Valid values are the dynamodb.ReturnConsumedCapacity
+// enum values. The capacity consumed by the query is available through Parser.ConsumedCapacity.
+func (expr *Expression) ReturnConsumedCapacity(val string) *Expression {
+	expr.returnConsumedCapacitySpecified = true
+	expr.returnConsumedCapacity = val
+	return expr
+}
+
+// UsePartiQL executes the expression as a PartiQL ExecuteStatement call rather than a Query call.
+// Index selection is unchanged; the selected index is addressed in the statement's FROM clause
+// and all condition values are passed as statement parameters.
+//
+// Conditions applied with Filter cannot be translated to PartiQL, so Parser.Next returns an error
+// if the expression contains any. The parser's limit per page and exclusive start key do not
+// apply to PartiQL statements.
+func (expr *Expression) UsePartiQL() *Expression {
+	expr.usePartiQL = true
+	return expr
+}
+
+// AliasName sets the expression attribute name placeholder used for attr in the generated query
+// input, such as "#status" for the reserved word "status". The alias must begin with '#' followed
+// by alphanumeric characters or underscores. Attributes without an alias continue to use
+// generated placeholders.
+//
+// If an alias is assigned to more than one attribute or conflicts with a generated placeholder,
+// Parser.Next returns an error when the query input is constructed.
+func (expr *Expression) AliasName(attr, alias string) *Expression {
+	expr.nameAliases[attr] = alias
 	return expr
 }
 
@@ -142,7 +423,7 @@ func (expr *Expression) ConsistentRead(val bool) *Expression {
 // expression.
 //
 // If multiple filter conditions are specified on the same attribute, only the most recent
-// condition will apply to the expression.
+// condition will apply to the expression, unless CombineConditions is used.
 func (expr *Expression) And(attr string) *ConditionKey {
 	return &ConditionKey{
 		expr: expr,
@@ -161,25 +442,175 @@ func (expr *Expression) Filter(filterCondition expression.ConditionBuilder) *Exp
 	return expr
 }
 
+// ModifiedSince adds a filter condition that only returns items where the value of the version
+// attribute attr is greater than version. This supports reading items changed since a known
+// version when using optimistic concurrency.
+//
+// The condition is always applied as a filter condition and is not considered for index selection.
+func (expr *Expression) ModifiedSince(attr string, version int64) *Expression {
+	if attr == "" {
+		expr.setErr(errors.New("modified since condition requires a version attribute"))
+		return expr
+	}
+	return expr.Filter(expression.Name(attr).GreaterThan(expression.Value(version)))
+}
+
+// WhereType adds a filter condition that only returns items where the attribute attr is stored as
+// the DynamoDB type t. Valid types are "S", "SS", "N", "NS", "B", "BS", "BOOL", "NULL", "L", and
+// "M". If t is not a valid type, Parser.Next returns an error.
+//
+// The condition is always applied as a filter condition and is not considered for index selection.
+func (expr *Expression) WhereType(attr string, t string) *Expression {
+	switch expression.DynamoDBAttributeType(t) {
+	case expression.String, expression.StringSet, expression.Number, expression.NumberSet,
+		expression.Binary, expression.BinarySet, expression.Boolean, expression.Null,
+		expression.List, expression.Map:
+		return expr.Filter(expression.Name(attr).AttributeType(expression.DynamoDBAttributeType(t)))
+	default:
+		expr.setErr(fmt.Errorf("invalid attribute type for attribute %s: %s", attr, t))
+		return expr
+	}
+}
+
+// onlyFiltersWithinPartition returns true if the expression has filter conditions but no sort key
+// condition on the index, so the key condition reads the entire partition
+func (expr *Expression) onlyFiltersWithinPartition(index *tableIndex) bool {
+	if index.IsComposite {
+		// an exists condition on the sort key does not narrow the partition
+		if f, found := expr.filters[index.SortKey]; found && !typesMatch(f, &existsFilter{}) {
+			return false
+		}
+	}
+	return len(expr.filters) > 1 || len(expr.additionalConditions) > 0
+}
+
+// boundToIndex returns the expression with its sort key prefix range, if any, applied as a
+// condition on the index's sort key. The range is left unbound if the index has no sort key or the
+// expression has another condition on it, in which case the index is not viable.
+func (expr *Expression) boundToIndex(index *tableIndex) *Expression {
+	if expr.sortKeyPrefixes == nil || !index.IsComposite {
+		return expr
+	}
+	if _, found := expr.filters[index.SortKey]; found {
+		return expr
+	}
+
+	boundExpr := *expr
+	boundExpr.filters = map[string]conditionFilter{index.SortKey: expr.sortKeyPrefixes}
+	for attr, filter := range expr.filters {
+		boundExpr.filters[attr] = filter
+	}
+	boundExpr.sortKeyPrefixes = nil
+	return &boundExpr
+}
+
+// withAdditionalAttributes returns the expression with attributes added by SelectAlso included in
+// its selected attributes, without duplicates
+func (expr *Expression) withAdditionalAttributes() *Expression {
+	if !expr.attributesSpecified || len(expr.additionalAttributes) == 0 {
+		return expr
+	}
+
+	attributes := []string{}
+	attributeSet := map[string]struct{}{}
+	for _, attribute := range append(append([]string{}, expr.attributes...),
+		expr.additionalAttributes...) {
+		if _, found := attributeSet[attribute]; !found {
+			attributeSet[attribute] = struct{}{}
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	unionExpr := *expr
+	unionExpr.attributes = attributes
+	unionExpr.additionalAttributes = nil
+	return &unionExpr
+}
+
+// filterCondition returns the filter condition for a condition on a non-key attribute
+func filterCondition(
+	name expression.NameBuilder, filter conditionFilter) expression.ConditionBuilder {
+
+	var fc expression.ConditionBuilder
+	switch f := filter.(type) {
+	case *equalsFilter:
+		fc = name.Equal(expression.Value(f.value))
+	case *lessThanFilter:
+		fc = name.LessThan(expression.Value(f.value))
+	case *greaterThanFilter:
+		fc = name.GreaterThan(expression.Value(f.value))
+	case *lessThanEqualFilter:
+		fc = name.LessThanEqual(expression.Value(f.value))
+	case *greaterThanEqualFilter:
+		fc = name.GreaterThanEqual(expression.Value(f.value))
+	case *betweenFilter:
+		fc = betweenCondition(name, f)
+	case *beginsWithFilter:
+		fc = name.BeginsWith(f.prefix)
+	case *existsFilter:
+		fc = name.AttributeExists()
+	case *andFilter:
+		fc = filterCondition(name, f.filters[0])
+		for _, part := range f.filters[1:] {
+			fc = fc.And(filterCondition(name, part))
+		}
+	}
+	return fc
+}
+
+func errDescendingWithoutSortKey(index *tableIndex) error {
+	return fmt.Errorf("expression specifies descending order, but selected index has no sort key: %s",
+		index.Name)
+}
+
+func (expr *Expression) setErr(err error) {
+	if expr.err == nil {
+		expr.err = err
+	}
+}
+
+// setFilterErr records an invalid condition on the attribute, which is cleared if the attribute's
+// condition is replaced
+func (expr *Expression) setFilterErr(attr string, err error) {
+	if expr.filterErrs == nil {
+		expr.filterErrs = map[string]error{}
+	}
+	expr.filterErrs[attr] = err
+}
+
+// error returns the first invalid condition of the expression, if any, with invalid conditions on
+// attributes ordered by attribute name
+func (expr *Expression) error() error {
+	if expr.err != nil {
+		return expr.err
+	} else if expr.sortKeyPrefixesErr != nil {
+		return expr.sortKeyPrefixesErr
+	} else if len(expr.filterErrs) == 0 {
+		return nil
+	}
+
+	attrs := make([]string, 0, len(expr.filterErrs))
+	for attr := range expr.filterErrs {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	return expr.filterErrs[attrs[0]]
+}
+
 func (expr *Expression) constructQueryInputGivenIndex(
 	index *tableIndex) (*dynamodb.QueryInput, error) {
 
 	dynamodbExprBuilder := expression.NewBuilder()
 
-	// copy expression filters into local map
-	filters := map[string]conditionFilter{}
-	for k, v := range expr.filters {
-		filters[k] = v
-	}
-
 	// initialize partition equals part of key condition expression
 	kce := expression.Key(index.PartitionKey).
 		Equal(expression.Value(expr.filters[index.PartitionKey].(*equalsFilter).value))
-	delete(filters, index.PartitionKey)
 
 	// apply sort key condition to key condition expression if applicable
+	hasSortKeyFilter := false
 	if index.IsComposite {
-		filter, hasSortKeyFilter := filters[index.SortKey]
+		var filter conditionFilter
+		filter, hasSortKeyFilter = expr.filters[index.SortKey]
 		if hasSortKeyFilter {
 			builder := expression.Key(index.SortKey)
 			switch f := filter.(type) {
@@ -198,35 +629,27 @@ func (expr *Expression) constructQueryInputGivenIndex(
 					expression.Value(f.lowval), expression.Value(f.highval)))
 			case *beginsWithFilter:
 				kce = kce.And(builder.BeginsWith(f.prefix))
+			case *existsFilter:
+				// every item in the index has its sort key
 			}
-			delete(filters, index.SortKey)
 		}
 	}
 
 	dynamodbExprBuilder = dynamodbExprBuilder.WithKeyCondition(kce)
 
-	// apply remaining filters as filter conditions
-	filterConditions := []expression.ConditionBuilder{}
-	for key, filter := range filters {
-		var fc expression.ConditionBuilder
-		switch f := filter.(type) {
-		case *equalsFilter:
-			fc = expression.Name(key).Equal(expression.Value(f.value))
-		case *lessThanFilter:
-			fc = expression.Name(key).LessThan(expression.Value(f.value))
-		case *greaterThanFilter:
-			fc = expression.Name(key).GreaterThan(expression.Value(f.value))
-		case *lessThanEqualFilter:
-			fc = expression.Name(key).LessThanEqual(expression.Value(f.value))
-		case *greaterThanEqualFilter:
-			fc = expression.Name(key).GreaterThanEqual(expression.Value(f.value))
-		case *betweenFilter:
-			fc = expression.Name(key).Between(
-				expression.Value(f.lowval), expression.Value(f.highval))
-		case *beginsWithFilter:
-			fc = expression.Name(key).BeginsWith(f.prefix)
+	// apply remaining filters as filter conditions, ordered by attribute name so that the filter
+	// expression and its placeholders are the same for every query
+	filterAttrs := []string{}
+	for attr := range expr.filters {
+		if attr != index.PartitionKey && !(hasSortKeyFilter && attr == index.SortKey) {
+			filterAttrs = append(filterAttrs, attr)
 		}
-		filterConditions = append(filterConditions, fc)
+	}
+	sort.Strings(filterAttrs)
+	filterConditions := []expression.ConditionBuilder{}
+	for _, attr := range filterAttrs {
+		filterConditions = append(filterConditions,
+			filterCondition(expression.Name(attr), expr.filters[attr]))
 	}
 
 	// apply additional filter conditions, if specified
@@ -241,10 +664,11 @@ func (expr *Expression) constructQueryInputGivenIndex(
 			filterConditions[2:]...))
 	}
 
-	// set projection if specified
-	if expr.attributesSpecified {
+	// set projection if specified; when attributes are fetched from the table, the index's
+	// projected attributes, which always include the table's key, are queried instead
+	if expr.attributesSpecified && !expr.fetchesMissingAttributes(index) {
 		names := []expression.NameBuilder{}
-		for _, attribute := range expr.attributes {
+		for _, attribute := range expr.projectedAttributes(index) {
 			names = append(names, expression.Name(attribute))
 		}
 		proj := expression.NamesList(names[0], names[1:]...)
@@ -264,15 +688,27 @@ func (expr *Expression) constructQueryInputGivenIndex(
 		ProjectionExpression:      dynamodbExpr.Projection(),
 	}
 
+	if err := expr.applyNameAliases(queryInput); err != nil {
+		return nil, err
+	}
+
+	pruneUnusedPlaceholders(queryInput)
+
 	if index.Name != tablePrimaryIndexName {
 		queryInput.IndexName = aws.String(index.Name)
 	}
 
-	if expr.consistentRead {
+	if expr.readsConsistently(index) {
 		queryInput.ConsistentRead = aws.Bool(true)
 	}
 
-	if expr.orderSpecified {
+	if expr.descendingOrder && !index.IsComposite {
+		return nil, errDescendingWithoutSortKey(index)
+	}
+
+	if expr.latest || expr.descendingOrder {
+		queryInput.ScanIndexForward = aws.Bool(false)
+	} else if expr.orderSpecified {
 		queryInput.ScanIndexForward = aws.Bool(expr.orderAscending)
 	}
 
@@ -0,0 +1,431 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// newPathIndexService creates a fake service with an additional global secondary index, g-path,
+// keyed on g and a string sort key
+func newPathIndexService() *fakeService {
+	service := newFakeService(1)
+	service.table.AttributeDefinitions = append(service.table.AttributeDefinitions,
+		&dynamodb.AttributeDefinition{AttributeName: aws.String("path"), AttributeType: aws.String("S")})
+	service.table.GlobalSecondaryIndexes = append(service.table.GlobalSecondaryIndexes,
+		&dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String("g-path"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", "path"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		})
+	return service
+}
+
+// keyConditionBounds queries the expression and returns the selected index and the values of the
+// first query's key condition other than the partition key value
+func keyConditionBounds(t *testing.T, service *fakeService, expr *Expression,
+	partitionValue string) (string, []string) {
+
+	t.Helper()
+
+	client := NewClient(service)
+	if _, err := parseSortKeys(context.Background(), client.Query("T", expr)); err != nil {
+		t.Fatal(err)
+	}
+
+	input := service.queryInputs[0]
+	if !strings.Contains(aws.StringValue(input.KeyConditionExpression), "BETWEEN") {
+		t.Errorf("expected BETWEEN key condition, got %s",
+			aws.StringValue(input.KeyConditionExpression))
+	}
+
+	bounds := []string{}
+	for _, value := range input.ExpressionAttributeValues {
+		if value.S != nil && aws.StringValue(value.S) != partitionValue {
+			bounds = append(bounds, aws.StringValue(value.S))
+		} else if value.N != nil {
+			bounds = append(bounds, aws.StringValue(value.N))
+		}
+	}
+	return aws.StringValue(input.IndexName), bounds
+}
+
+func TestWhereSortKeyBetweenPrefixesBounds(t *testing.T) {
+	service := newPathIndexService()
+	expr := NewExpression().Equal("g", "x").WhereSortKeyBetweenPrefixes("2024-01", "2024-03")
+
+	indexName, bounds := keyConditionBounds(t, service, expr, "x")
+	if indexName != "g-path" {
+		t.Errorf("expected index g-path, got %q", indexName)
+	}
+	if !containsAll(bounds, "2024-01", "2024-03\U0010FFFF") || len(bounds) != 2 {
+		t.Errorf("expected bounds 2024-01 and 2024-03\\U0010FFFF, got %q", bounds)
+	}
+}
+
+func TestWhereSortKeyBetweenPrefixesRequiresStringSortKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		service *fakeService
+		expr    *Expression
+	}{
+		{
+			name:    "numeric sort key",
+			service: newFakeService(1),
+			expr:    NewExpression().Equal("g", "x").WhereSortKeyBetweenPrefixes("a", "b"),
+		},
+		{
+			name:    "other condition on sort key",
+			service: newPathIndexService(),
+			expr: NewExpression().Equal("g", "x").Equal("path", "a").
+				WhereSortKeyBetweenPrefixes("a", "b"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(tc.service)
+			err := client.Validate(context.Background(), "T", tc.expr)
+			if _, ok := err.(*ErrNoViableIndexes); !ok {
+				t.Errorf("expected ErrNoViableIndexes, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWhereSortKeyBetweenPrefixesUnknownSortKeyType(t *testing.T) {
+	service := newPathIndexService()
+	service.table.AttributeDefinitions = service.table.AttributeDefinitions[:3]
+	client := NewClient(service)
+
+	expr := NewExpression().Equal("g", "x").WhereSortKeyBetweenPrefixes("a", "b")
+	if err := client.Validate(context.Background(), "T", expr); err != nil {
+		t.Errorf("expected sort key of unknown type to be viable, got %v", err)
+	}
+}
+
+func TestWhereSortKeyBetweenPrefixesOrder(t *testing.T) {
+	client := NewClient(newPathIndexService())
+
+	testCases := []struct {
+		name        string
+		low, high   string
+		expectValid bool
+	}{
+		{"ascending", "2024-01", "2024-03", true},
+		{"low within high prefix", "2024-01#x", "2024-01", true},
+		{"reversed", "2024-03", "2024-01", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := NewExpression().Equal("g", "x").WhereSortKeyBetweenPrefixes(tc.low, tc.high)
+			err := client.Validate(context.Background(), "T", expr)
+			if tc.expectValid && err != nil {
+				t.Errorf("expected valid prefix range, got %v", err)
+			} else if !tc.expectValid && err == nil {
+				t.Error("expected error for reversed prefix range")
+			}
+		})
+	}
+}
+
+func TestTimeRangeBounds(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	testCases := []struct {
+		name      string
+		expr      *Expression
+		partition string
+		indexName string
+	}{
+		{"primary", NewExpression().Equal("pk", "a").TimeRange("sk", from, to), "a", ""},
+		{"secondary", NewExpression().Equal("g", "x").TimeRange("sk", from, to), "x", "g-sk"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			indexName, bounds := keyConditionBounds(t, newPathIndexService(), testCase.expr,
+				testCase.partition)
+			if indexName != testCase.indexName {
+				t.Errorf("expected index %q, got %q", testCase.indexName, indexName)
+			}
+			low, high := strconv.FormatInt(from.Unix(), 10), strconv.FormatInt(to.Unix(), 10)
+			if !containsAll(bounds, low, high) || len(bounds) != 2 {
+				t.Errorf("expected bounds %s and %s, got %q", low, high, bounds)
+			}
+		})
+	}
+}
+
+func TestTimeRangeIndexSelection(t *testing.T) {
+	from := time.Unix(100, 0)
+	to := time.Unix(200, 0)
+
+	testCases := []struct {
+		name   string
+		attr   string
+		viable bool
+	}{
+		{"numeric sort key", "sk", true},
+		{"string sort key", "path", false},
+		{"not a sort key", "other", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := NewClient(newPathIndexService())
+
+			expr := NewExpression().Equal("g", "x").TimeRange(testCase.attr, from, to)
+			err := client.Validate(context.Background(), "T", expr)
+			if testCase.viable && err != nil {
+				t.Errorf("expected viable index, got %v", err)
+			} else if _, ok := err.(*ErrNoViableIndexes); !testCase.viable && !ok {
+				t.Errorf("expected ErrNoViableIndexes, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTimeRangeUnknownSortKeyType(t *testing.T) {
+	service := newFakeService(1)
+	service.table.AttributeDefinitions = nil
+	client := NewClient(service)
+
+	expr := NewExpression().Equal("pk", "a").TimeRange("sk", time.Unix(100, 0), time.Unix(200, 0))
+	if err := client.Validate(context.Background(), "T", expr); err != nil {
+		t.Errorf("expected sort key of unknown type to be viable, got %v", err)
+	}
+}
+
+func TestTimeRangeReversed(t *testing.T) {
+	client := NewClient(newFakeService(1))
+	from := time.Unix(200, 0)
+	to := time.Unix(100, 0)
+
+	expr := NewExpression().Equal("pk", "a").TimeRange("sk", from, to)
+	if err := client.Validate(context.Background(), "T", expr); err == nil {
+		t.Error("expected error for reversed time range")
+	}
+
+	// a later valid condition on the attribute replaces the reversed range
+	expr.TimeRange("sk", to, from)
+	if err := client.Validate(context.Background(), "T", expr); err != nil {
+		t.Errorf("expected replaced time range to be valid, got %v", err)
+	}
+
+	// a condition on another attribute does not
+	expr = NewExpression().Equal("pk", "a").TimeRange("sk", from, to).TimeRange("other", to, from)
+	if err := client.Validate(context.Background(), "T", expr); err == nil {
+		t.Error("expected error for reversed time range")
+	}
+}
+
+func TestWhereSortKeyBetweenPrefixesReplaced(t *testing.T) {
+	client := NewClient(newPathIndexService())
+
+	expr := NewExpression().Equal("g", "x").WhereSortKeyBetweenPrefixes("b", "a").
+		WhereSortKeyBetweenPrefixes("a", "b")
+	if err := client.Validate(context.Background(), "T", expr); err != nil {
+		t.Errorf("expected replaced prefix range to be valid, got %v", err)
+	}
+}
+
+func containsAll(values []string, expected ...string) bool {
+	found := map[string]bool{}
+	for _, value := range values {
+		found[value] = true
+	}
+	for _, value := range expected {
+		if !found[value] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestModifiedSince(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 4; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: id * 10})
+	}
+	client := NewClient(newOrdersService(t, orders...))
+
+	expr := NewExpression().Equal("customer", "c").ModifiedSince("total", 20)
+	explanation, err := client.Query("Orders", expr).Explain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(explanation.FilterExpression, " > ") {
+		t.Errorf("expected greater than filter, got %s", explanation.FilterExpression)
+	}
+	if strings.Contains(explanation.KeyConditionExpression, " AND ") {
+		t.Errorf("expected no sort key condition, got %s", explanation.KeyConditionExpression)
+	}
+
+	ids := parseOrderIDs(t, client.Query("Orders", expr))
+	if expected := []int{3, 4}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestModifiedSinceNotUsedForIndexSelection(t *testing.T) {
+	client := NewClient(newSparseIndexService(dynamodb.IndexStatusActive, false))
+
+	testCases := []struct {
+		name string
+		expr *Expression
+	}{
+		// g-ts is sparse, so it requires a condition on ts
+		{"sort key of sparse index", NewExpression().Equal("g", "x").ModifiedSince("ts", 1)},
+		{"missing version attribute", NewExpression().Equal("pk", "a").ModifiedSince("", 1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := client.Validate(context.Background(), "T", tc.expr); err == nil {
+				t.Error("expected expression to be rejected")
+			}
+		})
+	}
+}
+
+func TestWhereType(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 3; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: id * 10})
+	}
+	client := NewClient(newOrdersService(t, orders...))
+
+	testCases := []struct {
+		name     string
+		attr, t  string
+		expected []int
+	}{
+		{"number attribute", "total", "N", []int{1, 2, 3}},
+		{"string attribute", "status", "S", []int{1, 2, 3}},
+		{"mismatched type", "total", "S", []int{}},
+		{"missing attribute", "other", "M", []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := NewExpression().Equal("customer", "c").WhereType(tc.attr, tc.t)
+
+			explanation, err := client.Query("Orders", expr).Explain(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(explanation.FilterExpression, "attribute_type (") {
+				t.Errorf("expected attribute_type filter, got %s", explanation.FilterExpression)
+			}
+
+			ids := parseOrderIDs(t, client.Query("Orders", expr))
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestWhereTypeRejected(t *testing.T) {
+	client := NewClient(newSparseIndexService(dynamodb.IndexStatusActive, false))
+
+	testCases := []struct {
+		name string
+		expr *Expression
+	}{
+		{"invalid type token", NewExpression().Equal("pk", "a").WhereType("x", "STRING")},
+		// g-ts is sparse, so it requires a condition on ts
+		{"sort key of sparse index", NewExpression().Equal("g", "x").WhereType("ts", "N")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := client.Validate(context.Background(), "T", tc.expr); err == nil {
+				t.Error("expected expression to be rejected")
+			}
+		})
+	}
+}
+
+func TestSelectAlso(t *testing.T) {
+	testCases := []struct {
+		name         string
+		registerType bool
+		expr         *Expression
+		expected     []string
+	}{
+		{
+			name:         "registered type",
+			registerType: true,
+			expr:         NewExpression().Equal("pk", "a").SelectAlso("g", "sk", "g"),
+			expected:     []string{"pk", "sk", "g"},
+		},
+		{
+			name: "selected attributes",
+			expr: NewExpression().Equal("pk", "a").Select("pk").SelectAlso("pk", "g"),
+			// key attributes are always projected
+			expected: []string{"pk", "g", "sk"},
+		},
+		{
+			name:     "all attributes",
+			expr:     NewExpression().Equal("pk", "a").SelectAlso("g"),
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(newFakeService(1))
+			ctx := context.Background()
+			if tc.registerType {
+				if err := client.RegisterType(ctx, "T", testRecord{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			explanation, err := client.Query("T", tc.expr).Explain(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			projected := []string{}
+			if explanation.ProjectionExpression != "" {
+				for _, name := range strings.Split(explanation.ProjectionExpression, ", ") {
+					projected = append(projected, explanation.ExpressionAttributeNames[name])
+				}
+			}
+			if !reflect.DeepEqual(projected, tc.expected) {
+				t.Errorf("expected projection %v, got %v", tc.expected, projected)
+			}
+		})
+	}
+}
+
+func TestSelectAlsoRequiresIndexCoverage(t *testing.T) {
+	service := newFakeService(1)
+	service.table.GlobalSecondaryIndexes[0].Projection = &dynamodb.Projection{
+		ProjectionType: aws.String("KEYS_ONLY"),
+	}
+	client := NewClient(service)
+
+	expr := NewExpression().Equal("g", "x").Select("pk", "sk").SelectAlso("other")
+	err := client.Validate(context.Background(), "T", expr)
+	if _, ok := err.(*ErrNoViableIndexes); !ok {
+		t.Errorf("expected ErrNoViableIndexes for unprojected attribute, got %v", err)
+	}
+
+	expr = NewExpression().Equal("g", "x").Select("pk").SelectAlso("sk")
+	if err := client.Validate(context.Background(), "T", expr); err != nil {
+		t.Errorf("expected projected attributes to be viable, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// NewQueryWithFallback initializes a query defined by the primary expression on a table, which is
+// queried with the fallback expression instead if no index is viable for the primary expression,
+// such as a broader query when a precise one cannot be served by the table's indexes. The fallback
+// is chosen when the index is selected, on the first call to Parser.Next, so no items from the
+// primary expression have been returned. If no index is viable for the fallback either, Next
+// returns the fallback's ErrNoViableIndexes error.
+//
+// By default, the fallback is only used when the primary expression has no viable index. With
+// Parser.SetFallbackOnNoItems, the fallback is also used when the primary expression's query
+// returns no items. Parser.UsedFallback reports whether the fallback expression was queried.
+func (client *Client) NewQueryWithFallback(
+	tableName string, primary, fallback *Expression) *Parser {
+
+	parser := client.Query(tableName, primary)
+	parser.fallbackExpr = fallback
+	return parser
+}
+
+// SetFallbackOnNoItems sets whether a parser created with NewQueryWithFallback also queries its
+// fallback expression when the primary expression's query completes without returning any items.
+// The fallback is not used if max pagination stopped the primary query before it completed.
+func (parser *Parser) SetFallbackOnNoItems(val bool) *Parser {
+	parser.fallbackOnNoItems = val
+	return parser
+}
+
+// UsedFallback returns true if the parser's fallback expression has been queried in place of its
+// primary expression.
+func (parser *Parser) UsedFallback() bool {
+	return parser.usingFallback
+}
+
+// switchToFallback replaces the parser's expression and index selection with its fallback
+// expression, and returns false if the parser has no fallback or has already switched. Any
+// pagination state from the primary expression's query must be cleared by the caller.
+func (parser *Parser) switchToFallback() bool {
+	if parser.fallbackExpr == nil || parser.usingFallback {
+		return false
+	}
+
+	parser.expr = parser.fallbackExpr
+	parser.usingFallback = true
+	parser.selectedIndex = nil
+	parser.queryInput = nil
+	parser.statementInput = nil
+	parser.parallelRangeUnsplittable = false
+	parser.pagingMode = DefaultPaging
+
+	return true
+}
+
+// fallsBackOnNoItems returns true if the primary query's completion without items should switch
+// the parser to its fallback expression
+func (parser *Parser) fallsBackOnNoItems(err error) bool {
+	complete, ok := err.(*ErrParsingComplete)
+	return ok && parser.fallbackOnNoItems && parser.returnedItems == 0 &&
+		complete.reason == "all items have been parsed"
+}
+
+// nextItemWithFallback returns the next item in the query, switching to the fallback expression
+// if the primary expression's query completes without items and the parser falls back on no items
+func (parser *Parser) nextItemWithFallback(
+	ctx context.Context) (map[string]*dynamodb.AttributeValue, error) {
+
+	item, err := parser.nextItem(ctx)
+	if parser.fallsBackOnNoItems(err) && parser.fallbackExpr != nil && !parser.usingFallback {
+		parser.Reset()
+		parser.switchToFallback()
+		return parser.nextItem(ctx)
+	}
+	return item, err
+}
@@ -0,0 +1,78 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryWithFallback(t *testing.T) {
+	service := newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Status: "open"},
+		testOrder{Customer: "c", ID: 2, Status: "closed"},
+	)
+	client := NewClient(service)
+
+	testCases := []struct {
+		name              string
+		primary           *Expression
+		fallbackOnNoItems bool
+		expectedIDs       []int
+		expectFallback    bool
+	}{
+		{
+			name:           "primary has no viable index",
+			primary:        NewExpression().Equal("total", 0),
+			expectedIDs:    []int{1, 2},
+			expectFallback: true,
+		},
+		{
+			name:        "primary has viable index",
+			primary:     NewExpression().Equal("status", "open"),
+			expectedIDs: []int{1},
+		},
+		{
+			name:        "primary has no items",
+			primary:     NewExpression().Equal("customer", "d"),
+			expectedIDs: []int{},
+		},
+		{
+			name:              "primary has no items with fallback on no items",
+			primary:           NewExpression().Equal("customer", "d"),
+			fallbackOnNoItems: true,
+			expectedIDs:       []int{1, 2},
+			expectFallback:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.NewQueryWithFallback("Orders", tc.primary,
+				NewExpression().Equal("customer", "c"))
+			parser.SetFallbackOnNoItems(tc.fallbackOnNoItems)
+
+			ids := parseOrderIDs(t, parser)
+			if !reflect.DeepEqual(ids, tc.expectedIDs) {
+				t.Errorf("expected ids %v, got %v", tc.expectedIDs, ids)
+			}
+			if parser.UsedFallback() != tc.expectFallback {
+				t.Errorf("expected used fallback %v, got %v", tc.expectFallback, parser.UsedFallback())
+			}
+		})
+	}
+}
+
+func TestQueryWithFallbackAppliesRegisteredType(t *testing.T) {
+	client := NewClient(newOrdersService(t, testOrder{Customer: "c", ID: 1, Status: "open"}))
+	if err := client.RegisterType(context.Background(), "Orders", testOrder{}); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := client.NewQueryWithFallback("Orders", NewExpression().Equal("total", 0),
+		NewExpression().Equal("customer", "c").Select("note"))
+	err := parser.Prepare(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not in registered type") {
+		t.Errorf("expected fallback selecting an unregistered attribute to fail, got %v", err)
+	}
+}
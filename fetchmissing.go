@@ -0,0 +1,152 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// maxBatchGetKeys is the maximum number of keys in a single BatchGetItem request
+const maxBatchGetKeys = 100
+
+// FetchMissingAttributes allows an index which does not project all of the expression's selected
+// attributes, or all attributes if none are selected, to be selected for the query. Items are
+// queried from the index and then fetched from the table by key with BatchGetItem, so a sparse
+// or otherwise narrow index may be used to find items whose remaining attributes are only
+// available from the table.
+//
+// Fetching items by key is more expensive than reading them through a query, so such an index is
+// only selected by DefaultIndexSelector if the combined estimated cost of the query and the fetch
+// is lower than that of the best index which returns all attributes itself. The index must still
+// project every attribute with a condition in the expression, since conditions are evaluated on
+// the index's items, and conditions added with Filter should likewise only reference projected
+// attributes. Post-filters are applied to the fetched items. Items that are deleted between the
+// query and the fetch are omitted from the results. Missing attributes are not fetched for PartiQL
+// expressions.
+func (expr *Expression) FetchMissingAttributes() *Expression {
+	expr.fetchMissingAttributes = true
+	return expr
+}
+
+// fetchesMissingAttributes returns true if the items queried from the index are missing attributes
+// returned by the expression, which are fetched from the table by key
+func (expr *Expression) fetchesMissingAttributes(index *tableIndex) bool {
+	if !expr.fetchMissingAttributes || expr.usePartiQL || index.IncludesAllAttributes {
+		return false
+	}
+	if !expr.attributesSpecified {
+		return true
+	}
+
+	for _, attribute := range expr.attributes {
+		if _, found := index.AttributeSet[attribute]; !found {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchItems fetches the expression's attributes for each queried item from the table by key,
+// returning the fetched items in the order of the queried items
+func (client *Client) fetchItems(ctx context.Context, tableName string, expr *Expression,
+	items []map[string]*dynamodb.AttributeValue, returnConsumedCapacity *string) (
+	[]map[string]*dynamodb.AttributeValue, *dynamodb.ConsumedCapacity, error) {
+
+	if len(items) == 0 {
+		return items, nil, nil
+	}
+
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	primaryKeys := indexMetadata.Indexes[0].getKeys()
+
+	keysAndAttributes := &dynamodb.KeysAndAttributes{}
+	if expr.consistentRead || expr.consistentReadIfSupported {
+		keysAndAttributes.ConsistentRead = aws.Bool(true)
+	}
+
+	// fetched items must include the table's key attributes to be matched to queried items
+	if expr.attributesSpecified {
+		keyedExpr := expr.withKeyAttributes(primaryKeys)
+		names := []expression.NameBuilder{}
+		for _, attribute := range keyedExpr.attributes {
+			names = append(names, expression.Name(attribute))
+		}
+		projection, err := expression.NewBuilder().
+			WithProjection(expression.NamesList(names[0], names[1:]...)).Build()
+		if err != nil {
+			return nil, nil, err
+		}
+		keysAndAttributes.ProjectionExpression = projection.Projection()
+		keysAndAttributes.ExpressionAttributeNames = projection.Names()
+	}
+
+	// collect the unique keys of the queried items
+	keys := []map[string]*dynamodb.AttributeValue{}
+	requested := map[string]struct{}{}
+	for _, item := range items {
+		keyString := itemKeyString(item, primaryKeys)
+		if _, found := requested[keyString]; found {
+			continue
+		}
+		requested[keyString] = struct{}{}
+
+		key := map[string]*dynamodb.AttributeValue{}
+		for _, keyAttribute := range primaryKeys {
+			key[keyAttribute] = item[keyAttribute]
+		}
+		keys = append(keys, key)
+	}
+
+	fetchedItems := map[string]map[string]*dynamodb.AttributeValue{}
+	var consumedCapacity *dynamodb.ConsumedCapacity
+	for start := 0; start < len(keys); start += maxBatchGetKeys {
+		end := start + maxBatchGetKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batchKeysAndAttributes := *keysAndAttributes
+		batchKeysAndAttributes.Keys = keys[start:end]
+		requestItems := map[string]*dynamodb.KeysAndAttributes{tableName: &batchKeysAndAttributes}
+
+		// request any unprocessed keys until every key in the batch is processed
+		for len(requestItems) > 0 {
+			var output *dynamodb.BatchGetItemOutput
+			err := client.callWithTimeout(ctx, func(ctx context.Context) error {
+				var err error
+				output, err = client.dynamodbService.BatchGetItemWithContext(ctx,
+					&dynamodb.BatchGetItemInput{
+						RequestItems:           requestItems,
+						ReturnConsumedCapacity: returnConsumedCapacity,
+					}, requestOptionsFromContext(ctx)...)
+				return err
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, item := range output.Responses[tableName] {
+				fetchedItems[itemKeyString(item, primaryKeys)] = item
+			}
+			for _, capacity := range output.ConsumedCapacity {
+				consumedCapacity = addConsumedCapacity(consumedCapacity, capacity)
+			}
+			requestItems = output.UnprocessedKeys
+		}
+	}
+
+	// items deleted since they were queried are not returned
+	orderedItems := []map[string]*dynamodb.AttributeValue{}
+	for _, item := range items {
+		if fetchedItem, found := fetchedItems[itemKeyString(item, primaryKeys)]; found {
+			orderedItems = append(orderedItems, fetchedItem)
+		}
+	}
+
+	return orderedItems, consumedCapacity, nil
+}
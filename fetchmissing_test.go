@@ -0,0 +1,105 @@
+package autoquery
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/dgravesa/dynamodb-autoquery/autoquerytest"
+)
+
+// newFlaggedOrdersService creates an in-memory service with an Orders table of 40 orders for
+// customer "c", the first flaggedOrders of which are flagged and appear in a keys-only index
+func newFlaggedOrdersService(t *testing.T, flaggedOrders int) *autoquerytest.Service {
+	t.Helper()
+
+	service := autoquerytest.NewService()
+	_, err := service.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("Orders"),
+		KeySchema: keySchema("customer", "id"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("customer"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("id"), AttributeType: aws.String("N")},
+			{AttributeName: aws.String("flagged"), AttributeType: aws.String("S")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName:  aws.String("flagged-id"),
+				KeySchema:  keySchema("flagged", "id"),
+				Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id := 1; id <= 40; id++ {
+		it := map[string]*dynamodb.AttributeValue{
+			"customer": {S: aws.String("c")},
+			"id":       {N: aws.String(strconv.Itoa(id))},
+			"note":     {S: aws.String("note " + strconv.Itoa(id))},
+		}
+		if id <= flaggedOrders {
+			it["flagged"] = &dynamodb.AttributeValue{S: aws.String("c")}
+		}
+		_, err := service.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Orders"), Item: it})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return service
+}
+
+func TestFetchMissingAttributesChoosesCheaperPlan(t *testing.T) {
+	testCases := []struct {
+		name          string
+		flaggedOrders int
+		expectedIndex string
+	}{
+		{"sparse index and fetch is cheaper", 2, "flagged-id"},
+		{"all-projecting primary index is cheaper", 40, PrimaryIndexName},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(newFlaggedOrdersService(t, tc.flaggedOrders))
+			expr := NewExpression().Equal("customer", "c").Equal("flagged", "c").
+				FetchMissingAttributes()
+
+			parser := client.Query("Orders", expr)
+			indexName, err := parser.SelectedIndexName(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if indexName != tc.expectedIndex {
+				t.Fatalf("expected index %s, got %s", tc.expectedIndex, indexName)
+			}
+
+			// attributes outside the index projection are returned either way
+			count := 0
+			for {
+				var order struct {
+					ID   int    `dynamodbav:"id"`
+					Note string `dynamodbav:"note"`
+				}
+				err := parser.Next(context.Background(), &order)
+				if _, complete := err.(*ErrParsingComplete); complete {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				if expected := "note " + strconv.Itoa(order.ID); order.Note != expected {
+					t.Errorf("expected note %q, got %q", expected, order.Note)
+				}
+				count++
+			}
+			if count != tc.flaggedOrders {
+				t.Errorf("expected %d orders, got %d", tc.flaggedOrders, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// GroupBy parses all remaining items in the query and groups them by their value of the attribute
+// attr, which is useful when a single query spans multiple logical groups, such as events of
+// different types in one partition. Items are unmarshaled into returnGroups, which should be a
+// pointer to a map from string to a slice of items with dynamodbav attribute tags. String and
+// number values are used as group keys as they are stored, and items without the attribute are
+// grouped under the empty string. Within each group, items are in query order.
+//
+// If the expression selects attributes and attr is not projected by the query, attr is added to
+// the projection so that items may be grouped, and is removed from items before they are
+// unmarshaled. Since the projection is chosen when the index is selected, GroupBy returns an error
+// without querying any items if attr is not projected and the parser has already queried a page.
+// An error is also returned if an item's value of attr is not a string or number. Max pagination
+// and total limits apply as with Next.
+func (parser *Parser) GroupBy(ctx context.Context, attr string, returnGroups interface{}) error {
+	groupsValue := reflect.ValueOf(returnGroups)
+	if groupsValue.Kind() != reflect.Ptr || groupsValue.IsNil() ||
+		groupsValue.Elem().Kind() != reflect.Map ||
+		groupsValue.Elem().Type().Key().Kind() != reflect.String ||
+		groupsValue.Elem().Type().Elem().Kind() != reflect.Slice {
+
+		return fmt.Errorf("groups must be a non-nil pointer to a map of string to slice: %T",
+			returnGroups)
+	}
+
+	addedAttr, err := parser.ensureProjected(ctx, attr)
+	if err != nil {
+		return err
+	}
+
+	groupsType := groupsValue.Elem().Type()
+	groups := reflect.MakeMap(groupsType)
+	for {
+		item, err := parser.nextItemWithFallback(ctx)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		groupKey, err := groupKeyOf(item, attr)
+		if err != nil {
+			return err
+		}
+		if addedAttr {
+			delete(item, attr)
+		}
+
+		groupItem := reflect.New(groupsType.Elem().Elem())
+		if err := dynamodbattribute.UnmarshalMap(item, groupItem.Interface()); err != nil {
+			return err
+		}
+
+		key := reflect.ValueOf(groupKey).Convert(groupsType.Key())
+		group := groups.MapIndex(key)
+		if !group.IsValid() {
+			group = reflect.MakeSlice(groupsType.Elem(), 0, 1)
+		}
+		groups.SetMapIndex(key, reflect.Append(group, groupItem.Elem()))
+	}
+
+	groupsValue.Elem().Set(groups)
+	return nil
+}
+
+// ensureProjected adds the attribute to the expression's selected attributes if the expression
+// selects attributes and the attribute is not projected by the query, returning true if the
+// attribute was added. An error is returned if the attribute must be added after a page has been
+// queried.
+func (parser *Parser) ensureProjected(ctx context.Context, attr string) (bool, error) {
+	parser.applyRegisteredType(ctx)
+	if !parser.expr.attributesSpecified {
+		return false, nil
+	}
+
+	projectedAttributes := parser.expr.attributes
+	if !parser.expr.usePartiQL && !parser.expr.strictProjection {
+		index, err := parser.selectIndex(ctx)
+		if err != nil {
+			return false, err
+		}
+		projectedAttributes = parser.expr.projectedAttributes(index)
+	}
+
+	for _, attribute := range projectedAttributes {
+		if attribute == attr {
+			return false, nil
+		}
+	}
+
+	if parser.currentPage > 0 || parser.rangeSegments != nil || parser.cancelPrefetch != nil {
+		return false, fmt.Errorf("grouping attribute is not projected by the query: %s", attr)
+	}
+
+	// the index is selected again, since the added attribute may not be projected by the index
+	groupedExpr := *parser.expr
+	groupedExpr.additionalAttributes = []string{attr}
+	parser.expr = groupedExpr.withAdditionalAttributes()
+	parser.selectedIndex = nil
+	parser.queryInput = nil
+	parser.statementInput = nil
+	return true, nil
+}
+
+// groupKeyOf returns the item's value of the attribute as a group key
+func groupKeyOf(item map[string]*dynamodb.AttributeValue, attr string) (string, error) {
+	value, found := item[attr]
+	switch {
+	case !found || value.NULL != nil:
+		return "", nil
+	case value.S != nil:
+		return aws.StringValue(value.S), nil
+	case value.N != nil:
+		return aws.StringValue(value.N), nil
+	default:
+		return "", fmt.Errorf("grouping attribute %s must be a string or number: %s", attr, value)
+	}
+}
@@ -0,0 +1,110 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGroupBy(t *testing.T) {
+	client := NewClient(newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Status: "open", Total: 10},
+		testOrder{Customer: "c", ID: 2, Status: "closed", Total: 20},
+		testOrder{Customer: "c", ID: 3, Status: "open", Total: 10},
+		testOrder{Customer: "d", ID: 4, Status: "open", Total: 40},
+	))
+
+	testCases := []struct {
+		name     string
+		expr     *Expression
+		attr     string
+		expected map[string][]int
+	}{
+		{
+			name:     "string attribute",
+			expr:     NewExpression().Equal("customer", "c"),
+			attr:     "status",
+			expected: map[string][]int{"open": {1, 3}, "closed": {2}},
+		},
+		{
+			name:     "number attribute",
+			expr:     NewExpression().Equal("customer", "c"),
+			attr:     "total",
+			expected: map[string][]int{"10": {1, 3}, "20": {2}},
+		},
+		{
+			name:     "missing attribute",
+			expr:     NewExpression().Equal("customer", "c"),
+			attr:     "other",
+			expected: map[string][]int{"": {1, 2, 3}},
+		},
+		{
+			name:     "unprojected attribute",
+			expr:     NewExpression().Equal("customer", "c").Select("id"),
+			attr:     "status",
+			expected: map[string][]int{"open": {1, 3}, "closed": {2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := map[string][]testOrder{}
+			err := client.Query("Orders", tc.expr).GroupBy(context.Background(), tc.attr, &groups)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := map[string][]int{}
+			for key, group := range groups {
+				for _, order := range group {
+					ids[key] = append(ids[key], order.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("expected groups %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestGroupByUnprojectedAttributeRemoved(t *testing.T) {
+	client := NewClient(newOrdersService(t, testOrder{Customer: "c", ID: 1, Status: "open"}))
+
+	groups := map[string][]testOrder{}
+	parser := client.Query("Orders", NewExpression().Equal("customer", "c").Select("id"))
+	if err := parser.GroupBy(context.Background(), "status", &groups); err != nil {
+		t.Fatal(err)
+	}
+	if order := groups["open"][0]; order.Status != "" {
+		t.Errorf("expected added grouping attribute to be removed, got status %q", order.Status)
+	}
+}
+
+func TestGroupByErrors(t *testing.T) {
+	client := NewClient(newOrdersService(t, testOrder{Customer: "c", ID: 1, Status: "open"}))
+
+	testCases := []struct {
+		name   string
+		groups interface{}
+		prime  bool
+	}{
+		{"not a pointer", map[string][]testOrder{}, false},
+		{"not a map of slices", &map[string]testOrder{}, false},
+		{"unprojected after first page", &map[string][]testOrder{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.Query("Orders", NewExpression().Equal("customer", "c").Select("id"))
+			if tc.prime {
+				var order testOrder
+				if err := parser.Next(context.Background(), &order); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := parser.GroupBy(context.Background(), "status", tc.groups); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
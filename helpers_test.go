@@ -0,0 +1,189 @@
+package autoquery
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeService is a DynamoDB service which describes every table with the same description and
+// answers each query with scripted pages, regardless of the query's conditions. Pages are linked
+// by a last evaluated key holding the index of the next page.
+type fakeService struct {
+	dynamodbiface.DynamoDBAPI
+
+	mutex         sync.Mutex
+	table         *dynamodb.TableDescription
+	pages         [][]map[string]*dynamodb.AttributeValue
+	queryDelay    time.Duration
+	queryInputs   []*dynamodb.QueryInput
+	describeCalls map[string]int
+}
+
+// newFakeService creates a fake service for a table keyed by string pk and numeric sk, with an
+// all-projecting global secondary index keyed by g and sk, which returns pages of items in
+// partition "a"
+func newFakeService(pageSizes ...int) *fakeService {
+	service := &fakeService{
+		table: &dynamodb.TableDescription{
+			ItemCount:   aws.Int64(100),
+			TableStatus: aws.String(dynamodb.TableStatusActive),
+			KeySchema:   keySchema("pk", "sk"),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("pk"), AttributeType: aws.String("S")},
+				{AttributeName: aws.String("sk"), AttributeType: aws.String("N")},
+				{AttributeName: aws.String("g"), AttributeType: aws.String("S")},
+			},
+			GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+				{
+					IndexName:  aws.String("g-sk"),
+					ItemCount:  aws.Int64(100),
+					KeySchema:  keySchema("g", "sk"),
+					Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+				},
+			},
+		},
+		describeCalls: map[string]int{},
+	}
+
+	sk := 1
+	for _, pageSize := range pageSizes {
+		page := []map[string]*dynamodb.AttributeValue{}
+		for i := 0; i < pageSize; i++ {
+			page = append(page, testItem("a", sk))
+			sk++
+		}
+		service.pages = append(service.pages, page)
+	}
+
+	return service
+}
+
+func (service *fakeService) DescribeTableWithContext(ctx aws.Context,
+	input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	service.describeCalls[aws.StringValue(input.TableName)]++
+	table := *service.table
+	table.TableName = input.TableName
+	return &dynamodb.DescribeTableOutput{Table: &table}, nil
+}
+
+func (service *fakeService) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput,
+	opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+	if service.queryDelay > 0 {
+		select {
+		case <-time.After(service.queryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	recordedInput := *input
+	service.queryInputs = append(service.queryInputs, &recordedInput)
+
+	page := 0
+	if input.ExclusiveStartKey != nil {
+		page, _ = strconv.Atoi(aws.StringValue(input.ExclusiveStartKey["page"].N))
+	}
+
+	output := &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{}}
+	for _, it := range service.pages[page] {
+		output.Items = append(output.Items, copyTestItem(it))
+	}
+	if page+1 < len(service.pages) {
+		output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+			"page": {N: aws.String(strconv.Itoa(page + 1))},
+		}
+	}
+	return output, nil
+}
+
+// describeCallCount returns the number of DescribeTable calls made for the table
+func (service *fakeService) describeCallCount(tableName string) int {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	return service.describeCalls[tableName]
+}
+
+// queryCallCount returns the number of Query calls made
+func (service *fakeService) queryCallCount() int {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	return len(service.queryInputs)
+}
+
+func keySchema(partitionKey, sortKey string) []*dynamodb.KeySchemaElement {
+	schema := []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String(partitionKey), KeyType: aws.String("HASH")},
+	}
+	if sortKey != "" {
+		schema = append(schema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(sortKey), KeyType: aws.String("RANGE"),
+		})
+	}
+	return schema
+}
+
+func testItem(pk string, sk int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pk": {S: aws.String(pk)},
+		"sk": {N: aws.String(strconv.Itoa(sk))},
+	}
+}
+
+func copyTestItem(it map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	copied := map[string]*dynamodb.AttributeValue{}
+	for attr, value := range it {
+		copiedValue := *value
+		copied[attr] = &copiedValue
+	}
+	return copied
+}
+
+// sortKeys returns the sk values of the items
+func sortKeys(items []map[string]*dynamodb.AttributeValue) []int {
+	values := []int{}
+	for _, it := range items {
+		value, _ := strconv.Atoi(aws.StringValue(it["sk"].N))
+		values = append(values, value)
+	}
+	return values
+}
+
+// testRecord is the unmarshaled form of a test item
+type testRecord struct {
+	PK string `dynamodbav:"pk"`
+	SK int    `dynamodbav:"sk"`
+}
+
+// parseSortKeys calls Next until parsing completes or fails, returning the sk values of the
+// returned items and any error other than ErrParsingComplete
+func parseSortKeys(ctx context.Context, parser *Parser) ([]int, error) {
+	values := []int{}
+	for {
+		var record testRecord
+		err := parser.Next(ctx, &record)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			return values, nil
+		} else if err != nil {
+			return values, err
+		}
+		values = append(values, record.SK)
+	}
+}
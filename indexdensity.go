@@ -0,0 +1,97 @@
+package autoquery
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// isBackfilling returns true if the global secondary index is being created or backfilled, in
+// which case its item count lags behind the table's item count
+func isBackfilling(gsi *dynamodb.GlobalSecondaryIndexDescription) bool {
+	status := aws.StringValue(gsi.IndexStatus)
+	return aws.BoolValue(gsi.Backfilling) || (status != "" && status != dynamodb.IndexStatusActive)
+}
+
+// recordBackfillingIndexes records the current time as the last time the client observed each of
+// the table's backfilling indexes backfilling, keyed by the table's metadata cache key
+func (client *Client) recordBackfillingIndexes(
+	cacheKey string, indexMetadata *tableIndexMetadata) {
+
+	client.indexBackfillMutex.Lock()
+	defer client.indexBackfillMutex.Unlock()
+
+	for _, index := range indexMetadata.Indexes {
+		if !index.Backfilling {
+			continue
+		}
+		tableBackfillTimes, found := client.indexBackfillTimes[cacheKey]
+		if !found {
+			tableBackfillTimes = map[string]time.Time{}
+			client.indexBackfillTimes[cacheKey] = tableBackfillTimes
+		}
+		tableBackfillTimes[index.Name] = indexMetadata.FetchedAt
+	}
+}
+
+// withinBackfillGracePeriod returns true if the index is backfilling or was last observed
+// backfilling within the client's sparseness grace period
+func (client *Client) withinBackfillGracePeriod(cacheKey string, index *tableIndex) bool {
+	if client.SparsenessGracePeriod <= 0 {
+		return false
+	} else if index.Backfilling {
+		return true
+	}
+
+	client.indexBackfillMutex.Lock()
+	defer client.indexBackfillMutex.Unlock()
+
+	lastBackfilling, found := client.indexBackfillTimes[cacheKey][index.Name]
+	return found && time.Since(lastBackfilling) < client.SparsenessGracePeriod
+}
+
+// applyDensityOverrides returns the index metadata as considered for index selection, with
+// secondary indexes marked non-sparse if sparseness inference is disabled or the index is within
+// the client's sparseness grace period, and with any density hints applied. The cached metadata is
+// not modified; overridden indexes are copied.
+func (client *Client) applyDensityOverrides(ctx context.Context,
+	tableName string, indexMetadata *tableIndexMetadata) *tableIndexMetadata {
+
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+
+	overridden := *indexMetadata
+	overridden.Indexes = make([]*tableIndex, len(indexMetadata.Indexes))
+
+	for i, index := range indexMetadata.Indexes {
+		overridden.Indexes[i] = index
+		if index.Name == tablePrimaryIndexName {
+			continue
+		}
+
+		dense := client.DisableSparsenessInference ||
+			client.withinBackfillGracePeriod(cacheKey, index)
+		hinted := false
+		if client.IndexDensityHint != nil {
+			if hintedDense, ok := client.IndexDensityHint(tableName, index.Name); ok {
+				dense, hinted = hintedDense, true
+			}
+		}
+
+		if dense {
+			denseIndex := *index
+			denseIndex.IsSparse = false
+			denseIndex.Sparsity = 1.0
+			denseIndex.SparsityMultiplier = 1.0
+			denseIndex.HasMaxSparsityMultiplier = false
+			overridden.Indexes[i] = &denseIndex
+		} else if hinted && !index.IsSparse {
+			sparseIndex := *index
+			sparseIndex.IsSparse = true
+			overridden.Indexes[i] = &sparseIndex
+		}
+	}
+
+	return &overridden
+}
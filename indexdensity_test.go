@@ -0,0 +1,162 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// newSparseIndexService creates a fake service whose only secondary index, g-ts, is keyed on
+// attributes outside the table's primary key and holds a tenth of the table's items
+func newSparseIndexService(indexStatus string, backfilling bool) *fakeService {
+	service := newFakeService(1)
+	service.table.AttributeDefinitions = append(service.table.AttributeDefinitions,
+		&dynamodb.AttributeDefinition{AttributeName: aws.String("ts"), AttributeType: aws.String("N")})
+	service.table.GlobalSecondaryIndexes = []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName:   aws.String("g-ts"),
+			ItemCount:   aws.Int64(10),
+			IndexStatus: aws.String(indexStatus),
+			Backfilling: aws.Bool(backfilling),
+			KeySchema:   keySchema("g", "ts"),
+			Projection:  &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		},
+	}
+	return service
+}
+
+// describeSparseIndex returns the description of g-ts as considered for index selection
+func describeSparseIndex(t *testing.T, client *Client) IndexDescription {
+	descriptions, err := client.DescribeIndexes(context.Background(), "T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, description := range descriptions {
+		if description.Name == "g-ts" {
+			return description
+		}
+	}
+	t.Fatal("g-ts not described")
+	return IndexDescription{}
+}
+
+// assertDense checks that g-ts is considered dense and is selected without a sort key condition
+func assertDense(t *testing.T, client *Client) {
+	t.Helper()
+
+	description := describeSparseIndex(t, client)
+	if description.IsSparse || description.Sparsity != 1.0 {
+		t.Errorf("expected dense index with sparsity 1.0, got sparse %v with sparsity %v",
+			description.IsSparse, description.Sparsity)
+	}
+
+	err := client.Validate(context.Background(), "T", NewExpression().Equal("g", "x"))
+	if err != nil {
+		t.Errorf("expected dense index to be viable without a sort key condition, got %v", err)
+	}
+}
+
+// assertSparse checks that g-ts is considered sparse and requires a sort key condition
+func assertSparse(t *testing.T, client *Client) {
+	t.Helper()
+
+	if description := describeSparseIndex(t, client); !description.IsSparse {
+		t.Error("expected sparse index")
+	}
+
+	err := client.Validate(context.Background(), "T", NewExpression().Equal("g", "x"))
+	if _, ok := err.(*ErrNoViableIndexes); !ok {
+		t.Errorf("expected sparse index to require a sort key condition, got %v", err)
+	}
+}
+
+func TestBackfillingIndexIsDenseWithinGracePeriod(t *testing.T) {
+	testCases := []struct {
+		name        string
+		indexStatus string
+		backfilling bool
+	}{
+		{"creating", dynamodb.IndexStatusCreating, false},
+		{"backfilling", dynamodb.IndexStatusActive, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := NewClient(newSparseIndexService(testCase.indexStatus, testCase.backfilling))
+			client.SparsenessGracePeriod = time.Hour
+
+			assertDense(t, client)
+		})
+	}
+}
+
+func TestBackfilledIndexIsDenseUntilGracePeriodElapses(t *testing.T) {
+	service := newSparseIndexService(dynamodb.IndexStatusActive, true)
+	client := NewClient(service)
+	client.SparsenessGracePeriod = 50 * time.Millisecond
+	assertDense(t, client)
+
+	// backfill completes, but the index remains within the grace period
+	service.table.GlobalSecondaryIndexes[0].Backfilling = aws.Bool(false)
+	if err := client.RefreshTableMetadata(context.Background(), "T"); err != nil {
+		t.Fatal(err)
+	}
+	assertDense(t, client)
+
+	// the grace period is evaluated at selection, without retrieving metadata again
+	time.Sleep(60 * time.Millisecond)
+	assertSparse(t, client)
+	if calls := service.describeCallCount("T"); calls != 2 {
+		t.Errorf("expected 2 DescribeTable calls, got %d", calls)
+	}
+}
+
+func TestActiveIndexHasNoGracePeriod(t *testing.T) {
+	client := NewClient(newSparseIndexService(dynamodb.IndexStatusActive, false))
+	client.SparsenessGracePeriod = time.Hour
+
+	assertSparse(t, client)
+}
+
+func TestBackfillingIndexIsSparseWithoutGracePeriod(t *testing.T) {
+	client := NewClient(newSparseIndexService(dynamodb.IndexStatusCreating, false))
+
+	assertSparse(t, client)
+}
+
+func TestIndexDensityHint(t *testing.T) {
+	client := NewClient(newSparseIndexService(dynamodb.IndexStatusActive, false))
+
+	dense := true
+	client.IndexDensityHint = func(tableName, indexName string) (bool, bool) {
+		return dense, indexName == "g-ts"
+	}
+	assertDense(t, client)
+
+	// hints are applied at selection, so changing a hint does not require retrieving metadata
+	dense = false
+	client.DisableSparsenessInference = true
+	assertSparse(t, client)
+}
+
+func TestDisableSparsenessInferenceResetsZeroCountIndex(t *testing.T) {
+	service := newSparseIndexService(dynamodb.IndexStatusActive, false)
+	service.table.GlobalSecondaryIndexes[0].ItemCount = aws.Int64(0)
+	client := NewClient(service)
+	client.DisableSparsenessInference = true
+
+	assertDense(t, client)
+
+	// the zero-count index is not preferred as if the expression matches no items
+	plan, err := client.Plan(context.Background(), "T",
+		NewExpression().Equal("pk", "a").Equal("g", "x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.IndexName != PrimaryIndexName {
+		t.Errorf("expected primary index to be selected, got %q", plan.IndexName)
+	}
+}
@@ -0,0 +1,58 @@
+package autoquery
+
+import "sort"
+
+// IndexDescription describes a table index as it is considered for index selection.
+type IndexDescription struct {
+	// Name is the name of the index. The table's primary index is identified by PrimaryIndexName.
+	Name string `json:"name"`
+
+	// PartitionKey is the partition key attribute of the index.
+	PartitionKey string `json:"partitionKey"`
+
+	// SortKey is the sort key attribute of the index, or empty if the index has no sort key.
+	SortKey string `json:"sortKey,omitempty"`
+
+	// IsGlobal is true if the index is a global secondary index.
+	IsGlobal bool `json:"isGlobal"`
+
+	// IncludesAllAttributes is true if the index projects all table attributes.
+	IncludesAllAttributes bool `json:"includesAllAttributes"`
+
+	// ProjectedAttributes lists the attributes projected by the index in sorted order, including
+	// key attributes. It is empty if the index projects all attributes.
+	ProjectedAttributes []string `json:"projectedAttributes,omitempty"`
+
+	// Size is the number of items in the index at the time the metadata was retrieved.
+	Size int `json:"size"`
+
+	// IsSparse is true if the index is considered sparse for purposes of index selection.
+	IsSparse bool `json:"isSparse"`
+
+	// Sparsity is the ratio of the number of items in the index to the number of items in the
+	// table.
+	Sparsity float64 `json:"sparsity"`
+}
+
+func newIndexDescription(index *tableIndex) IndexDescription {
+	description := IndexDescription{
+		Name:                  index.Name,
+		PartitionKey:          index.PartitionKey,
+		SortKey:               index.SortKey,
+		IsGlobal:              index.IsGlobal,
+		IncludesAllAttributes: index.IncludesAllAttributes,
+		ProjectedAttributes:   []string{},
+		Size:                  index.Size,
+		IsSparse:              index.IsSparse,
+		Sparsity:              index.Sparsity,
+	}
+
+	if !index.IncludesAllAttributes {
+		for attr := range index.AttributeSet {
+			description.ProjectedAttributes = append(description.ProjectedAttributes, attr)
+		}
+		sort.Strings(description.ProjectedAttributes)
+	}
+
+	return description
+}
@@ -0,0 +1,122 @@
+package autoquery
+
+import "sort"
+
+// IndexCandidate is a table index considered for an expression during index selection.
+type IndexCandidate struct {
+	IndexDescription
+
+	// Score is the heuristic score of the index for the expression. Higher scores are preferred.
+	// The score is 0.0 if the index is not viable.
+	Score float64 `json:"score"`
+
+	// EstimatedReadCost is a rough, relative estimate of the cost of reading the expression's items
+	// from the index, derived from the index size, the width of its projection, and its score.
+	// Estimates are only comparable between candidates for the same expression. The estimate is
+	// 0.0 if the index is not viable.
+	EstimatedReadCost float64 `json:"estimatedReadCost"`
+
+	// FetchesMissingAttributes is true if the index does not project all of the expression's
+	// returned attributes and the missing attributes are fetched from the table by key, as allowed
+	// by Expression.FetchMissingAttributes. EstimatedReadCost includes the cost of the fetch.
+	FetchesMissingAttributes bool `json:"fetchesMissingAttributes,omitempty"`
+
+	// NotViableErr describes why the index is not viable for the expression, or is nil if the
+	// index is viable.
+	NotViableErr *ErrIndexNotViable `json:"notViableErr,omitempty"`
+}
+
+// IndexSelector selects the index used to query an expression.
+//
+// Select is called with every index on the table, in the order primary index, global secondary
+// indexes, then local secondary indexes, with secondary indexes ordered by name, and returns the
+// name of the selected index. Only viable candidates may be selected; if a non-viable candidate is
+// returned, the query fails with that candidate's NotViableErr.
+type IndexSelector interface {
+	Select(candidates []IndexCandidate) (string, error)
+}
+
+// DefaultIndexSelector is the IndexSelector used when Client.IndexSelector is not set. It selects
+// the viable index with the highest score. Ties are broken deterministically by preferring the
+// primary index, then the index with the fewest items, then the index with the lexicographically
+// smallest name. If the selected index fetches missing attributes from the table, its estimated
+// read cost is compared with that of the highest scoring viable index which does not, and the
+// cheaper of the two is selected. If no candidates are viable, it returns an ErrNoViableIndexes
+// error with the reasons for each index ordered by index name, with the primary index first.
+type DefaultIndexSelector struct{}
+
+// Select returns the name of the viable candidate with the highest score, or of the cheaper
+// candidate when the highest scoring candidate fetches missing attributes.
+func (DefaultIndexSelector) Select(candidates []IndexCandidate) (string, error) {
+	bestCandidate := highestScoringCandidate(candidates, func(IndexCandidate) bool { return true })
+
+	// no viable indexes found
+	if bestCandidate == nil {
+		inviableErrs := []*ErrIndexNotViable{}
+		for _, candidate := range candidates {
+			if candidate.NotViableErr != nil {
+				inviableErrs = append(inviableErrs, candidate.NotViableErr)
+			}
+		}
+		sort.SliceStable(inviableErrs, func(i, j int) bool {
+			return breaksTie(inviableErrs[i].IndexName, inviableErrs[j].IndexName)
+		})
+		return "", &ErrNoViableIndexes{IndexErrs: inviableErrs}
+	}
+
+	// an index that returns all attributes itself may be cheaper than the query and fetch
+	if bestCandidate.FetchesMissingAttributes {
+		coveringCandidate := highestScoringCandidate(candidates, func(candidate IndexCandidate) bool {
+			return !candidate.FetchesMissingAttributes
+		})
+		if coveringCandidate != nil &&
+			coveringCandidate.EstimatedReadCost < bestCandidate.EstimatedReadCost {
+			bestCandidate = coveringCandidate
+		}
+	}
+
+	return bestCandidate.Name, nil
+}
+
+// highestScoringCandidate returns the viable candidate with the highest score among candidates
+// accepted by include, or nil if there are none
+func highestScoringCandidate(candidates []IndexCandidate,
+	include func(candidate IndexCandidate) bool) *IndexCandidate {
+
+	var bestCandidate *IndexCandidate
+
+	for i, candidate := range candidates {
+		if candidate.NotViableErr != nil || !include(candidate) {
+			continue
+		}
+		if bestCandidate == nil || candidate.Score > bestCandidate.Score ||
+			(candidate.Score == bestCandidate.Score &&
+				candidateBreaksTie(candidate, *bestCandidate)) {
+			bestCandidate = &candidates[i]
+		}
+	}
+
+	return bestCandidate
+}
+
+// candidateBreaksTie returns true if the candidate is preferred over the other candidate between
+// candidates with equal scores
+func candidateBreaksTie(candidate, otherCandidate IndexCandidate) bool {
+	if candidate.Name != tablePrimaryIndexName && otherCandidate.Name != tablePrimaryIndexName &&
+		candidate.Size != otherCandidate.Size {
+
+		return candidate.Size < otherCandidate.Size
+	}
+	return breaksTie(candidate.Name, otherCandidate.Name)
+}
+
+// breaksTie returns true if the index name is preferred over the other index name between indexes
+// with equal scores
+func breaksTie(name, otherName string) bool {
+	if otherName == tablePrimaryIndexName {
+		return false
+	} else if name == tablePrimaryIndexName {
+		return true
+	}
+	return name < otherName
+}
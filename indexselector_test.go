@@ -0,0 +1,162 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestDefaultIndexSelectorTies(t *testing.T) {
+	candidate := func(name string, size int, score float64) IndexCandidate {
+		return IndexCandidate{IndexDescription: IndexDescription{Name: name, Size: size}, Score: score}
+	}
+
+	testCases := []struct {
+		name       string
+		candidates []IndexCandidate
+		selected   string
+	}{
+		{
+			"higher score wins",
+			[]IndexCandidate{candidate(PrimaryIndexName, 100, 1.0), candidate("a", 10, 1.2)},
+			"a",
+		},
+		{
+			"primary wins tie",
+			[]IndexCandidate{candidate(PrimaryIndexName, 100, 1.0), candidate("a", 10, 1.0)},
+			PrimaryIndexName,
+		},
+		{
+			"smaller index wins tie",
+			[]IndexCandidate{candidate("a", 100, 1.0), candidate("b", 10, 1.0)},
+			"b",
+		},
+		{
+			"name breaks size tie",
+			[]IndexCandidate{candidate("b", 10, 1.0), candidate("a", 10, 1.0)},
+			"a",
+		},
+		{
+			"zero score is selected",
+			[]IndexCandidate{candidate("a", 10, 0.0)},
+			"a",
+		},
+		{
+			"negative scores are compared",
+			[]IndexCandidate{candidate("a", 10, -2.0), candidate("b", 10, -1.0)},
+			"b",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			selected, err := DefaultIndexSelector{}.Select(testCase.candidates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if selected != testCase.selected {
+				t.Errorf("expected %q, got %q", testCase.selected, selected)
+			}
+		})
+	}
+}
+
+func TestNoViableIndexesReasonOrder(t *testing.T) {
+	gsi := func(name string) *dynamodb.GlobalSecondaryIndexDescription {
+		return &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String(name),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", "sk"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		}
+	}
+	descriptionOrders := [][]string{
+		{"z-idx", "a-idx", "m-idx"},
+		{"m-idx", "z-idx", "a-idx"},
+		{"a-idx", "m-idx", "z-idx"},
+	}
+
+	expectedOrder := []string{PrimaryIndexName, "a-idx", "m-idx", "z-idx"}
+	var expectedMessage string
+	for i := 0; i < 10; i++ {
+		service := newFakeService(1)
+		service.table.GlobalSecondaryIndexes = nil
+		for _, name := range descriptionOrders[i%len(descriptionOrders)] {
+			service.table.GlobalSecondaryIndexes = append(service.table.GlobalSecondaryIndexes,
+				gsi(name))
+		}
+		client := NewClient(service)
+
+		err := client.Validate(context.Background(), "T", NewExpression().Equal("other", "a"))
+		noViableErr, ok := err.(*ErrNoViableIndexes)
+		if !ok {
+			t.Fatalf("expected ErrNoViableIndexes, got %v", err)
+		}
+
+		order := []string{}
+		for _, indexErr := range noViableErr.IndexErrs {
+			order = append(order, indexErr.IndexName)
+		}
+		if !reflect.DeepEqual(order, expectedOrder) {
+			t.Errorf("expected reasons ordered %v, got %v", expectedOrder, order)
+		}
+
+		if i == 0 {
+			expectedMessage = err.Error()
+		} else if err.Error() != expectedMessage {
+			t.Errorf("expected stable message %s, got %s", expectedMessage, err.Error())
+		}
+	}
+}
+
+func TestTiedIndexesSelectedByName(t *testing.T) {
+	gsi := func(name string) *dynamodb.GlobalSecondaryIndexDescription {
+		return &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String(name),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", "sk"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		}
+	}
+	descriptionOrders := [][]string{
+		{"z-idx", "a-idx", "m-idx"},
+		{"m-idx", "z-idx", "a-idx"},
+		{"a-idx", "m-idx", "z-idx"},
+	}
+
+	expectedOrder := []string{PrimaryIndexName, "a-idx", "m-idx", "z-idx"}
+	for _, descriptionOrder := range descriptionOrders {
+		service := newFakeService(1)
+		service.table.GlobalSecondaryIndexes = nil
+		for _, name := range descriptionOrder {
+			service.table.GlobalSecondaryIndexes = append(service.table.GlobalSecondaryIndexes,
+				gsi(name))
+		}
+		client := NewClient(service)
+		ctx := context.Background()
+
+		expr := NewExpression().Equal("g", "x").GreaterThan("sk", 1)
+		indexName, err := client.Query("T", expr).SelectedIndexName(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if indexName != "a-idx" {
+			t.Errorf("expected a-idx for description order %v, got %s", descriptionOrder, indexName)
+		}
+
+		descriptions, err := client.DescribeIndexes(ctx, "T")
+		if err != nil {
+			t.Fatal(err)
+		}
+		order := []string{}
+		for _, description := range descriptions {
+			order = append(order, description.Name)
+		}
+		if !reflect.DeepEqual(order, expectedOrder) {
+			t.Errorf("expected indexes described in order %v, got %v", expectedOrder, order)
+		}
+	}
+}
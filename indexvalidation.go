@@ -0,0 +1,38 @@
+package autoquery
+
+// validateIndexes returns an ErrDuplicateIndex error if more than one of the table's indexes has
+// the same name, and reports secondary indexes with the same key schema as an earlier index of the
+// same kind to the client's RedundantIndex callback
+func (client *Client) validateIndexes(tableName string, indexes []*tableIndex) error {
+	indexesByName := map[string]*tableIndex{}
+	for _, index := range indexes {
+		if _, found := indexesByName[index.Name]; found {
+			return &ErrDuplicateIndex{TableName: tableName, IndexName: index.Name}
+		}
+		indexesByName[index.Name] = index
+	}
+
+	if client.RedundantIndex == nil {
+		return nil
+	}
+	for i, index := range indexes {
+		for _, otherIndex := range indexes[:i] {
+			if index.hasSameKeys(otherIndex) {
+				client.RedundantIndex(tableName, index.Name, otherIndex.Name)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasSameKeys returns true if both indexes are secondary indexes of the same kind with the same
+// partition and sort keys
+func (index *tableIndex) hasSameKeys(otherIndex *tableIndex) bool {
+	if index.Name == tablePrimaryIndexName || otherIndex.Name == tablePrimaryIndexName {
+		return false
+	}
+	return index.IsGlobal == otherIndex.IsGlobal &&
+		index.PartitionKey == otherIndex.PartitionKey && index.SortKey == otherIndex.SortKey
+}
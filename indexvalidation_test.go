@@ -0,0 +1,60 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestDuplicateIndexNames(t *testing.T) {
+	service := newFakeService(1)
+	service.table.GlobalSecondaryIndexes = append(service.table.GlobalSecondaryIndexes,
+		&dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String("g-sk"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("pk", "g"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+		})
+	client := NewClient(service)
+
+	err := client.Query("T", NewExpression().Equal("pk", "a")).Prepare(context.Background())
+	duplicateErr, ok := err.(*ErrDuplicateIndex)
+	if !ok {
+		t.Fatalf("expected ErrDuplicateIndex, got %v", err)
+	}
+	if duplicateErr.TableName != "T" || duplicateErr.IndexName != "g-sk" {
+		t.Errorf("expected duplicate g-sk on T, got %s on %s",
+			duplicateErr.IndexName, duplicateErr.TableName)
+	}
+	if entries := client.CacheStats().Entries; entries != 0 {
+		t.Errorf("expected invalid metadata not to be cached, got %d entries", entries)
+	}
+}
+
+func TestRedundantIndex(t *testing.T) {
+	service := newFakeService(1)
+	service.table.GlobalSecondaryIndexes = append(service.table.GlobalSecondaryIndexes,
+		&dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String("g-sk-keys"),
+			ItemCount:  aws.Int64(100),
+			KeySchema:  keySchema("g", "sk"),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+		})
+	client := NewClient(service)
+
+	reported := [][]string{}
+	client.RedundantIndex = func(tableName, indexName, sameKeysAs string) {
+		reported = append(reported, []string{tableName, indexName, sameKeysAs})
+	}
+
+	err := client.Query("T", NewExpression().Equal("pk", "a")).Prepare(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][]string{{"T", "g-sk-keys", "g-sk"}}; !reflect.DeepEqual(reported, expected) {
+		t.Errorf("expected %v, got %v", expected, reported)
+	}
+}
@@ -0,0 +1,208 @@
+package autoquery
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var keyConditionTokenPattern = regexp.MustCompile(`<=|>=|<>|[=<>(),]|[^\s=<>(),]+`)
+
+// expressionFromQueryInput derives an Expression from the key condition, projection, and read
+// consistency of a query input, sufficient for index selection
+func expressionFromQueryInput(input *dynamodb.QueryInput) (*Expression, error) {
+	expr := NewExpression()
+
+	if input.KeyConditionExpression == nil {
+		return nil, errors.New("query input does not contain a key condition expression")
+	}
+
+	p := &keyConditionParser{
+		tokens: keyConditionTokenPattern.FindAllString(*input.KeyConditionExpression, -1),
+		names:  input.ExpressionAttributeNames,
+		values: input.ExpressionAttributeValues,
+		expr:   expr,
+	}
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("invalid key condition expression: %s", err)
+	}
+
+	if input.ProjectionExpression != nil {
+		for _, path := range strings.Split(*input.ProjectionExpression, ",") {
+			// only the top-level attribute of a document path determines index coverage
+			attr := strings.TrimSpace(path)
+			attr = strings.SplitN(attr, ".", 2)[0]
+			attr = strings.SplitN(attr, "[", 2)[0]
+			if resolved, found := input.ExpressionAttributeNames[attr]; found {
+				attr = *resolved
+			}
+			expr.Select(attr)
+		}
+	}
+
+	if input.ConsistentRead != nil {
+		expr.ConsistentRead(*input.ConsistentRead)
+	}
+
+	return expr, nil
+}
+
+type keyConditionParser struct {
+	tokens []string
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+	expr   *Expression
+}
+
+func (p *keyConditionParser) parse() error {
+	if err := p.parseCondition(); err != nil {
+		return err
+	}
+	for p.pos < len(p.tokens) {
+		if err := p.expect("AND"); err != nil {
+			return err
+		}
+		if err := p.parseCondition(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *keyConditionParser) parseCondition() error {
+	token, err := p.next()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case token == "(":
+		if err := p.parseCondition(); err != nil {
+			return err
+		}
+		return p.expect(")")
+	case strings.EqualFold(token, "begins_with"):
+		if err := p.expect("("); err != nil {
+			return err
+		}
+		attr, err := p.nextName()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		prefix, err := p.nextValue()
+		if err != nil {
+			return err
+		}
+		if prefix.S == nil {
+			return errors.New("begins_with requires a string prefix")
+		}
+		p.expr.BeginsWith(attr, *prefix.S)
+		return p.expect(")")
+	}
+
+	attr, err := p.resolveName(token)
+	if err != nil {
+		return err
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(operator, "BETWEEN") {
+		lowval, err := p.nextValue()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("AND"); err != nil {
+			return err
+		}
+		highval, err := p.nextValue()
+		if err != nil {
+			return err
+		}
+		p.expr.Between(attr, lowval, highval)
+		return nil
+	}
+
+	value, err := p.nextValue()
+	if err != nil {
+		return err
+	}
+
+	switch operator {
+	case "=":
+		p.expr.Equal(attr, value)
+	case "<":
+		p.expr.LessThan(attr, value)
+	case "<=":
+		p.expr.LessThanEqual(attr, value)
+	case ">":
+		p.expr.GreaterThan(attr, value)
+	case ">=":
+		p.expr.GreaterThanEqual(attr, value)
+	default:
+		return fmt.Errorf("unsupported operator: %s", operator)
+	}
+
+	return nil
+}
+
+func (p *keyConditionParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", errors.New("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, nil
+}
+
+func (p *keyConditionParser) expect(expected string) error {
+	token, err := p.next()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(token, expected) {
+		return fmt.Errorf("expected %s but found %s", expected, token)
+	}
+	return nil
+}
+
+func (p *keyConditionParser) nextName() (string, error) {
+	token, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	return p.resolveName(token)
+}
+
+func (p *keyConditionParser) resolveName(token string) (string, error) {
+	if !strings.HasPrefix(token, "#") {
+		return token, nil
+	}
+	name, found := p.names[token]
+	if !found {
+		return "", fmt.Errorf("undefined expression attribute name: %s", token)
+	}
+	return *name, nil
+}
+
+func (p *keyConditionParser) nextValue() (*dynamodb.AttributeValue, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	value, found := p.values[token]
+	if !found {
+		return nil, fmt.Errorf("undefined expression attribute value: %s", token)
+	}
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package autoquery
+
+import "github.com/aws/aws-sdk-go/service/dynamodb"
+
+// StrictProjection causes items returned for expressions which select attributes to include only
+// the selected attributes.
+//
+// Queries which select attributes always project the key attributes of the selected index and of
+// the table in addition to the selected attributes, since every index projects them at no
+// additional cost and they identify each item, so that items may be used to resume, merge, or
+// fetch from the query. By default, these key attributes are included in returned items. With
+// StrictProjection, key attributes which are not selected are removed from items after they are
+// read and before any post-filters are applied.
+func (expr *Expression) StrictProjection() *Expression {
+	expr.strictProjection = true
+	return expr
+}
+
+// projectedAttributes returns the expression's selected attributes followed by any of the index's
+// and table's key attributes which are not selected
+func (expr *Expression) projectedAttributes(index *tableIndex) []string {
+	attributes := append([]string{}, expr.attributes...)
+
+	selected := map[string]struct{}{}
+	for _, attribute := range expr.attributes {
+		selected[attribute] = struct{}{}
+	}
+	for _, key := range append(index.getKeys(), index.TableKeys...) {
+		if _, found := selected[key]; !found {
+			selected[key] = struct{}{}
+			attributes = append(attributes, key)
+		}
+	}
+
+	return attributes
+}
+
+// stripUnselectedAttributes removes attributes which are not selected from the items if the
+// expression uses strict projection
+func (expr *Expression) stripUnselectedAttributes(items []map[string]*dynamodb.AttributeValue) {
+	if !expr.strictProjection || !expr.attributesSpecified {
+		return
+	}
+
+	selected := map[string]struct{}{}
+	for _, attribute := range expr.attributes {
+		selected[attribute] = struct{}{}
+	}
+	for _, item := range items {
+		for attribute := range item {
+			if _, found := selected[attribute]; !found {
+				delete(item, attribute)
+			}
+		}
+	}
+}
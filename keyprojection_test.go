@@ -0,0 +1,122 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/dgravesa/dynamodb-autoquery/autoquerytest"
+)
+
+func TestNarrowProjectionPaginatesOnSecondaryIndex(t *testing.T) {
+	service := autoquerytest.NewService()
+	_, err := service.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("Orders"),
+		KeySchema: keySchema("customer", "id"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("customer"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("id"), AttributeType: aws.String("N")},
+			{AttributeName: aws.String("status"), AttributeType: aws.String("S")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("status-id"),
+				KeySchema: keySchema("status", "id"),
+				Projection: &dynamodb.Projection{
+					ProjectionType:   aws.String("INCLUDE"),
+					NonKeyAttributes: []*string{aws.String("total")},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 7; id++ {
+		_, err := service.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("Orders"),
+			Item: map[string]*dynamodb.AttributeValue{
+				"customer": {S: aws.String("c" + strconv.Itoa(id))},
+				"id":       {N: aws.String(strconv.Itoa(id))},
+				"status":   {S: aws.String("open")},
+				"total":    {N: aws.String(strconv.Itoa(id * 10))},
+				"note":     {S: aws.String("unprojected")},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name               string
+		strict             bool
+		expectedAttributes []string
+	}{
+		{"key attributes included", false, []string{"customer", "id", "status", "total"}},
+		{"strict projection", true, []string{"total"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projections := [][]string{}
+			client := NewClient(service)
+			client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+				opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+				projected := []string{}
+				for _, name := range input.ExpressionAttributeNames {
+					projected = append(projected, aws.StringValue(name))
+				}
+				sort.Strings(projected)
+				projections = append(projections, projected)
+				return service.QueryWithContext(ctx, input, opts...)
+			}
+
+			expr := NewExpression().Equal("status", "open").Select("total")
+			if tc.strict {
+				expr.StrictProjection()
+			}
+			parser := client.Query("Orders", expr).SetLimitPerPage(3)
+
+			totals := []int{}
+			for {
+				item := map[string]interface{}{}
+				err := parser.Next(context.Background(), &item)
+				if _, complete := err.(*ErrParsingComplete); complete {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+
+				attributes := []string{}
+				for attribute := range item {
+					attributes = append(attributes, attribute)
+				}
+				sort.Strings(attributes)
+				if !reflect.DeepEqual(attributes, tc.expectedAttributes) {
+					t.Errorf("expected attributes %v, got %v", tc.expectedAttributes, attributes)
+				}
+				totals = append(totals, int(item["total"].(float64)))
+			}
+
+			if expected := []int{10, 20, 30, 40, 50, 60, 70}; !reflect.DeepEqual(totals, expected) {
+				t.Errorf("expected totals %v, got %v", expected, totals)
+			}
+			if len(projections) != 3 {
+				t.Errorf("expected 3 pages, got %d", len(projections))
+			}
+			for _, projected := range projections {
+				expected := []string{"customer", "id", "status", "total"}
+				if !reflect.DeepEqual(projected, expected) {
+					t.Errorf("expected projected attributes %v, got %v", expected, projected)
+				}
+			}
+		})
+	}
+}
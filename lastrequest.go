@@ -0,0 +1,24 @@
+package autoquery
+
+import "github.com/aws/aws-sdk-go/service/dynamodb"
+
+// LastRequest returns a copy of the query input sent to DynamoDB for the most recent page, after
+// all of the parser's options have been applied, including the page's ExclusiveStartKey and
+// Limit. Unlike the initial plan described by Client.Plan, it reflects the live paginated request,
+// which is useful for debugging pagination. LastRequest returns nil if no page has been queried,
+// for PartiQL expressions, and for expressions split with ParallelRange. When prefetch is enabled,
+// the most recent page may have been requested ahead of the items returned by Next.
+func (parser *Parser) LastRequest() *dynamodb.QueryInput {
+	lastRequest, _ := parser.lastRequest.Load().(*dynamodb.QueryInput)
+	if lastRequest == nil {
+		return nil
+	}
+	request := *lastRequest
+	return &request
+}
+
+// recordRequest stores a copy of the query input sent for a page
+func (parser *Parser) recordRequest(input *dynamodb.QueryInput) {
+	request := *input
+	parser.lastRequest.Store(&request)
+}
@@ -0,0 +1,68 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestLastRequest(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 5; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open"})
+	}
+	client := NewClient(newOrdersService(t, orders...))
+
+	testCases := []struct {
+		name          string
+		parsedItems   int
+		expectRequest bool
+		expectedStart string
+	}{
+		{"before first page", 0, false, ""},
+		{"first page", 1, true, ""},
+		{"second page", 3, true, "2"},
+		{"third page", 5, true, "4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.Query("Orders", NewExpression().Equal("customer", "c")).
+				SetLimitPerPage(2)
+			for i := 0; i < tc.parsedItems; i++ {
+				var order testOrder
+				if err := parser.Next(context.Background(), &order); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			request := parser.LastRequest()
+			if !tc.expectRequest {
+				if request != nil {
+					t.Errorf("expected no last request, got %v", request)
+				}
+				return
+			} else if request == nil {
+				t.Fatal("expected last request")
+			}
+
+			if limit := aws.Int64Value(request.Limit); limit != 2 {
+				t.Errorf("expected limit 2, got %d", limit)
+			}
+			startID := ""
+			if request.ExclusiveStartKey != nil {
+				startID = aws.StringValue(request.ExclusiveStartKey["id"].N)
+			}
+			if startID != tc.expectedStart {
+				t.Errorf("expected exclusive start id %q, got %q", tc.expectedStart, startID)
+			}
+
+			// the returned request is a copy
+			request.Limit = aws.Int64(100)
+			if limit := aws.Int64Value(parser.LastRequest().Limit); limit != 2 {
+				t.Errorf("expected recorded limit to be unchanged, got %d", limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package autoquery
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// MetadataCache stores table descriptions so that they may be shared between Client instances,
+// such as when a client is created per request. Implementations may be backed by external stores
+// and must be safe for concurrent use.
+type MetadataCache interface {
+	// Get returns the cached description of the table, or false if the table is not cached.
+	Get(ctx context.Context, tableName string) (*dynamodb.TableDescription, bool, error)
+
+	// Set caches the description of the table.
+	Set(ctx context.Context, tableName string, description *dynamodb.TableDescription) error
+
+	// Invalidate removes the table's description from the cache, if present.
+	Invalidate(ctx context.Context, tableName string) error
+}
+
+// InMemoryMetadataCache is a MetadataCache which stores table descriptions in memory. It may be
+// shared by clients within a process.
+type InMemoryMetadataCache struct {
+	mutex        sync.RWMutex
+	descriptions map[string]*dynamodb.TableDescription
+}
+
+// NewInMemoryMetadataCache creates a new InMemoryMetadataCache instance.
+func NewInMemoryMetadataCache() *InMemoryMetadataCache {
+	return &InMemoryMetadataCache{
+		descriptions: map[string]*dynamodb.TableDescription{},
+	}
+}
+
+// Get returns the cached description of the table, or false if the table is not cached.
+func (cache *InMemoryMetadataCache) Get(
+	ctx context.Context, tableName string) (*dynamodb.TableDescription, bool, error) {
+
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	description, found := cache.descriptions[tableName]
+	return description, found, nil
+}
+
+// Set caches the description of the table.
+func (cache *InMemoryMetadataCache) Set(ctx context.Context, tableName string,
+	description *dynamodb.TableDescription) error {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.descriptions[tableName] = description
+	return nil
+}
+
+// Invalidate removes the table's description from the cache, if present.
+func (cache *InMemoryMetadataCache) Invalidate(ctx context.Context, tableName string) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	delete(cache.descriptions, tableName)
+	return nil
+}
+
+// describeTableThroughCache returns the table description from the client's shared metadata cache
+// if set and populated, and otherwise from the metadata provider. If refresh is true, the shared
+// metadata cache is not checked, but is updated with the provider's description. Descriptions from
+// scoped providers are shared under the scoped cache key.
+func (client *Client) describeTableThroughCache(ctx context.Context, tableName string,
+	refresh bool) (*dynamodb.TableDescription, error) {
+
+	if client.MetadataCache == nil {
+		tableDescription, err := client.getTableDescription(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTableStatus(tableName, tableDescription); err != nil {
+			return nil, err
+		}
+		return tableDescription, nil
+	}
+
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+	if !refresh {
+		tableDescription, found, err := client.MetadataCache.Get(ctx, cacheKey)
+		if err != nil {
+			return nil, err
+		} else if found {
+			return tableDescription, nil
+		}
+	}
+
+	tableDescription, err := client.getTableDescription(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	// transient statuses are not cached, so the table is described again once it is active
+	if err := checkTableStatus(tableName, tableDescription); err != nil {
+		return nil, err
+	}
+	if err := client.MetadataCache.Set(ctx, cacheKey, tableDescription); err != nil {
+		return nil, err
+	}
+
+	return tableDescription, nil
+}
+
+// checkTableStatus returns an ErrTableNotActive error if the table's status does not allow queries.
+// Descriptions without a status, such as static descriptions, are assumed to be active.
+func checkTableStatus(tableName string, tableDescription *dynamodb.TableDescription) error {
+	switch status := aws.StringValue(tableDescription.TableStatus); status {
+	case "", dynamodb.TableStatusActive, dynamodb.TableStatusUpdating:
+		return nil
+	default:
+		return &ErrTableNotActive{TableName: tableName, Status: status}
+	}
+}
+
+// getTableDescription returns the table description from the metadata provider, with any per-call
+// timeout carried by ctx
+func (client *Client) getTableDescription(
+	ctx context.Context, tableName string) (*dynamodb.TableDescription, error) {
+
+	var tableDescription *dynamodb.TableDescription
+	err := client.callWithTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		tableDescription, err = client.metadataProvider.Get(ctx, tableName)
+		return err
+	})
+	return tableDescription, err
+}
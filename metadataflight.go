@@ -0,0 +1,104 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+)
+
+// metadataFetch is an in-flight retrieval of a table's index metadata, shared by concurrent
+// queries on the same uncached table
+type metadataFetch struct {
+	done    chan struct{}
+	refresh bool
+
+	// completed is false if the retrieval panicked, in which case it has no result
+	completed     bool
+	indexMetadata *tableIndexMetadata
+	err           error
+}
+
+// cachedIndexMetadata returns the client's cached index metadata for the cache key
+func (client *Client) cachedIndexMetadata(cacheKey string) (*tableIndexMetadata, bool) {
+	client.metadataMutex.RLock()
+	defer client.metadataMutex.RUnlock()
+
+	indexMetadata, found := client.tableIndexMetadataCache[cacheKey]
+	return indexMetadata, found
+}
+
+// cacheIndexMetadata adds the index metadata to the client's cache, replacing any existing entry
+func (client *Client) cacheIndexMetadata(cacheKey string, indexMetadata *tableIndexMetadata) {
+	client.metadataMutex.Lock()
+	defer client.metadataMutex.Unlock()
+
+	if _, found := client.tableIndexMetadataCache[cacheKey]; found {
+		client.recordCacheEviction()
+	}
+	client.tableIndexMetadataCache[cacheKey] = indexMetadata
+	client.recordCacheEntries(len(client.tableIndexMetadataCache))
+}
+
+// fetchIndexMetadata retrieves the table's index metadata and adds it to the client's cache. If
+// the table's metadata is already being retrieved by another query, fetchIndexMetadata waits for
+// and returns its result rather than retrieving the metadata again. If refresh is true, the shared
+// metadata cache is bypassed and updated, and an in-flight retrieval which is not a refresh is
+// waited for and then followed by a refresh.
+func (client *Client) fetchIndexMetadata(
+	ctx context.Context, tableName string, refresh bool) (*tableIndexMetadata, error) {
+
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+	for {
+		client.metadataMutex.Lock()
+		fetch, inFlight := client.metadataFetches[cacheKey]
+		if !inFlight {
+			fetch = &metadataFetch{done: make(chan struct{}), refresh: refresh}
+			client.metadataFetches[cacheKey] = fetch
+		}
+		client.metadataMutex.Unlock()
+
+		if !inFlight {
+			return client.leadMetadataFetch(ctx, tableName, cacheKey, fetch)
+		}
+
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		switch {
+		case !fetch.completed:
+			// the retrieval panicked, so it is retried by this query
+			continue
+		case isContextErr(fetch.err) && ctx.Err() == nil:
+			// a fetch abandoned by its own query's context is retried with this query's context
+			continue
+		case refresh && !fetch.refresh:
+			// the in-flight retrieval may have been served from a shared cache
+			continue
+		}
+		return fetch.indexMetadata, fetch.err
+	}
+}
+
+// leadMetadataFetch retrieves the table's index metadata for an in-flight fetch, releasing any
+// queries waiting on the fetch when done, even if the retrieval panics
+func (client *Client) leadMetadataFetch(ctx context.Context, tableName, cacheKey string,
+	fetch *metadataFetch) (*tableIndexMetadata, error) {
+
+	defer func() {
+		client.metadataMutex.Lock()
+		delete(client.metadataFetches, cacheKey)
+		client.metadataMutex.Unlock()
+		close(fetch.done)
+	}()
+
+	fetch.indexMetadata, fetch.err = client.loadIndexMetadata(ctx, tableName, fetch.refresh)
+	fetch.completed = true
+	return fetch.indexMetadata, fetch.err
+}
+
+// isContextErr returns true if err is, or wraps, a context cancellation or deadline error
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,148 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestConcurrentQueriesShareMetadataFetch(t *testing.T) {
+	service := newFakeService(2, 1)
+	client := NewClient(service)
+	client.DescribeTableFunc = func(ctx aws.Context, input *dynamodb.DescribeTableInput,
+		opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+		// hold the retrieval open so that concurrent queries find it in flight
+		time.Sleep(20 * time.Millisecond)
+		return service.DescribeTableWithContext(ctx, input, opts...)
+	}
+
+	tableNames := []string{"T0", "T1", "T2"}
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(tableName string) {
+			defer wg.Done()
+
+			parser := client.Query(tableName, NewExpression().Equal("pk", "a"))
+			var item map[string]interface{}
+			for {
+				err := parser.Next(context.Background(), &item)
+				if _, complete := err.(*ErrParsingComplete); complete {
+					break
+				} else if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			client.Snapshot()
+		}(tableNames[i%len(tableNames)])
+	}
+	wg.Wait()
+
+	for _, tableName := range tableNames {
+		if calls := service.describeCallCount(tableName); calls != 1 {
+			t.Errorf("expected 1 DescribeTable call for %s, got %d", tableName, calls)
+		}
+	}
+	if entries := client.CacheStats().Entries; entries != len(tableNames) {
+		t.Errorf("expected %d cache entries, got %d", len(tableNames), entries)
+	}
+}
+
+func TestMetadataFetchReleasedAfterPanic(t *testing.T) {
+	service := newFakeService(1)
+	client := NewClient(service)
+	panicked := false
+	client.DescribeTableFunc = func(ctx aws.Context, input *dynamodb.DescribeTableInput,
+		opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+		if !panicked {
+			panicked = true
+			panic("describe failed")
+		}
+		return service.DescribeTableWithContext(ctx, input, opts...)
+	}
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered == nil {
+				t.Error("expected metadata retrieval to panic")
+			}
+		}()
+		client.pullIndexMetadata(context.Background(), "T")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.pullIndexMetadata(ctx, "T"); err != nil {
+		t.Fatalf("expected retrieval after panic to succeed, got %v", err)
+	}
+}
+
+func TestRefreshWaitsForInFlightFetch(t *testing.T) {
+	service := newFakeService(1)
+	client := NewClient(service)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	first := true
+	client.DescribeTableFunc = func(ctx aws.Context, input *dynamodb.DescribeTableInput,
+		opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+		if first {
+			first = false
+			close(started)
+			<-release
+		}
+		return service.DescribeTableWithContext(ctx, input, opts...)
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := client.pullIndexMetadata(context.Background(), "T")
+		errs <- err
+	}()
+	<-started
+
+	go func() {
+		errs <- client.RefreshTableMetadata(context.Background(), "T")
+	}()
+
+	// allow the refresh to find the retrieval in flight before releasing it
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := service.describeCallCount("T"); calls != 2 {
+		t.Errorf("expected refresh to retrieve metadata again, got %d DescribeTable calls", calls)
+	}
+}
+
+func ExampleClient_concurrentQueries() {
+	service := newFakeService(1)
+	client := NewClient(service)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Query("Events", NewExpression().Equal("pk", "a")).Prepare(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	fmt.Println(client.CacheStats().Entries)
+	// Output: 1
+}
@@ -0,0 +1,39 @@
+package autoquery
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshTableMetadata retrieves the table's metadata from the metadata provider and replaces the
+// client's cached metadata for the table, bypassing and updating any shared MetadataCache. This
+// may be used to pick up schema changes, such as a newly created global secondary index, or
+// updated item counts without waiting for MetadataTTL. Index sparseness is inferred again from the
+// retrieved item counts, so an index which is no longer sparse becomes viable accordingly.
+func (client *Client) RefreshTableMetadata(ctx context.Context, tableName string) error {
+	_, err := client.fetchIndexMetadata(ctx, tableName, true)
+	return err
+}
+
+// RefreshIfStale retrieves the table's metadata from the metadata provider if the client's cached
+// metadata for the table is older than MetadataTTL, or if the table is not cached, and returns
+// true if the metadata was retrieved. This may be used to freshen metadata before an important
+// query without discarding metadata which is still current. If MetadataTTL is 0, cached metadata
+// is never stale.
+func (client *Client) RefreshIfStale(ctx context.Context, tableName string) (bool, error) {
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+	indexMetadata, found := client.cachedIndexMetadata(cacheKey)
+	if found && !client.metadataStale(indexMetadata) {
+		return false, nil
+	}
+
+	if _, err := client.fetchIndexMetadata(ctx, tableName, found); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// metadataStale returns true if the cached metadata is older than the client's MetadataTTL
+func (client *Client) metadataStale(indexMetadata *tableIndexMetadata) bool {
+	return client.MetadataTTL > 0 && time.Since(indexMetadata.FetchedAt) >= client.MetadataTTL
+}
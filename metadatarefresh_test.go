@@ -0,0 +1,57 @@
+package autoquery
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefreshIfStale(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ttl             time.Duration
+		age             time.Duration
+		expectRefreshed bool
+	}{
+		{"within ttl", time.Minute, 0, false},
+		{"past ttl", time.Minute, 2 * time.Minute, true},
+		{"no ttl", 0, time.Hour, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeService(1)
+			client := NewClient(service)
+			client.MetadataTTL = tc.ttl
+			ctx := context.Background()
+
+			// uncached metadata is always retrieved
+			refreshed, err := client.RefreshIfStale(ctx, "T")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !refreshed || service.describeCallCount("T") != 1 {
+				t.Fatalf("expected uncached metadata to be retrieved once, got %d calls",
+					service.describeCallCount("T"))
+			}
+
+			indexMetadata, _ := client.cachedIndexMetadata(client.metadataCacheKey(ctx, "T"))
+			indexMetadata.FetchedAt = indexMetadata.FetchedAt.Add(-tc.age)
+
+			refreshed, err = client.RefreshIfStale(ctx, "T")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedCalls := 1
+			if tc.expectRefreshed {
+				expectedCalls = 2
+			}
+			if refreshed != tc.expectRefreshed {
+				t.Errorf("expected refreshed %v, got %v", tc.expectRefreshed, refreshed)
+			}
+			if calls := service.describeCallCount("T"); calls != expectedCalls {
+				t.Errorf("expected %d describe calls, got %d", expectedCalls, calls)
+			}
+		})
+	}
+}
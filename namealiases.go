@@ -0,0 +1,78 @@
+package autoquery
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	nameAliasPattern      = regexp.MustCompile(`^#[A-Za-z0-9_]+$`)
+	generatedAliasPattern = regexp.MustCompile(`#[0-9]+`)
+)
+
+func (expr *Expression) applyNameAliases(queryInput *dynamodb.QueryInput) error {
+	if len(expr.nameAliases) == 0 {
+		return nil
+	}
+
+	// map generated placeholders to user-supplied aliases
+	replacements := map[string]string{}
+	attrsByAlias := map[string]string{}
+	for placeholder, attr := range queryInput.ExpressionAttributeNames {
+		alias, found := expr.nameAliases[*attr]
+		if !found {
+			continue
+		}
+		if !nameAliasPattern.MatchString(alias) {
+			return fmt.Errorf("invalid alias for attribute %s: %s", *attr, alias)
+		}
+		if otherAttr, found := attrsByAlias[alias]; found {
+			return fmt.Errorf("alias %s is assigned to multiple attributes: %s, %s",
+				alias, otherAttr, *attr)
+		}
+		attrsByAlias[alias] = *attr
+		replacements[placeholder] = alias
+	}
+
+	// user-supplied aliases must not collide with generated placeholders that remain in use
+	for placeholder, attr := range queryInput.ExpressionAttributeNames {
+		if _, replaced := replacements[placeholder]; replaced {
+			continue
+		}
+		if otherAttr, found := attrsByAlias[placeholder]; found {
+			return fmt.Errorf("alias %s for attribute %s conflicts with generated alias for attribute %s",
+				placeholder, otherAttr, *attr)
+		}
+	}
+
+	replace := func(s *string) *string {
+		if s == nil {
+			return nil
+		}
+		return aws.String(generatedAliasPattern.ReplaceAllStringFunc(*s, func(placeholder string) string {
+			if alias, found := replacements[placeholder]; found {
+				return alias
+			}
+			return placeholder
+		}))
+	}
+
+	queryInput.KeyConditionExpression = replace(queryInput.KeyConditionExpression)
+	queryInput.FilterExpression = replace(queryInput.FilterExpression)
+	queryInput.ProjectionExpression = replace(queryInput.ProjectionExpression)
+
+	names := map[string]*string{}
+	for placeholder, attr := range queryInput.ExpressionAttributeNames {
+		if alias, found := replacements[placeholder]; found {
+			names[alias] = attr
+		} else {
+			names[placeholder] = attr
+		}
+	}
+	queryInput.ExpressionAttributeNames = names
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package autoquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAliasName(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	expr := NewExpression().Equal("pk", "a").Equal("status", "x").AliasName("status", "#status")
+	explanation, err := client.Query("T", expr).Explain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if explanation.FilterExpression != "#status = :0" {
+		t.Errorf("expected filter on #status, got %s", explanation.FilterExpression)
+	}
+	if name := explanation.ExpressionAttributeNames["#status"]; name != "status" {
+		t.Errorf("expected #status to name status, got %s", name)
+	}
+	if name := explanation.ExpressionAttributeNames["#1"]; name != "pk" {
+		t.Errorf("expected generated placeholder #1 to name pk, got %s", name)
+	}
+}
+
+func TestAliasNameConflicts(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expr        *Expression
+		expectedErr string
+	}{
+		{
+			name: "alias assigned to multiple attributes",
+			expr: NewExpression().Equal("pk", "a").Equal("status", "x").
+				AliasName("status", "#k").AliasName("pk", "#k"),
+			expectedErr: "alias #k is assigned to multiple attributes",
+		},
+		{
+			name: "alias conflicts with generated placeholder",
+			expr: NewExpression().Equal("pk", "a").Equal("status", "x").
+				AliasName("status", "#1"),
+			expectedErr: "alias #1 for attribute status conflicts with generated alias",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(newFakeService(1))
+
+			_, err := client.Query("T", tc.expr).Explain(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
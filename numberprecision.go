@@ -0,0 +1,75 @@
+package autoquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// exactConditionValue returns the condition value in a form which marshals to an exact number.
+// DynamoDB stores numbers as arbitrary-precision decimal strings, so big.Int, big.Float, and
+// json.Number values are converted to dynamodbattribute.Number rather than marshaled as structs or
+// strings. Integral float values too large to be represented exactly are rejected, since the
+// marshaled number may not match the stored value the caller intended.
+func exactConditionValue(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case *big.Int:
+		if value != nil {
+			return dynamodbattribute.Number(value.String()), nil
+		}
+	case big.Int:
+		return dynamodbattribute.Number(value.String()), nil
+	case *big.Float:
+		if value != nil {
+			return dynamodbattribute.Number(value.Text('g', -1)), nil
+		}
+	case big.Float:
+		return dynamodbattribute.Number(value.Text('g', -1)), nil
+	case json.Number:
+		return dynamodbattribute.Number(value), nil
+	}
+
+	floatValue := reflect.ValueOf(v)
+	for floatValue.Kind() == reflect.Ptr && !floatValue.IsNil() {
+		floatValue = floatValue.Elem()
+	}
+	if floatValue.Kind() == reflect.Float32 || floatValue.Kind() == reflect.Float64 {
+		f := floatValue.Float()
+		mantissaBits := 53
+		if floatValue.Kind() == reflect.Float32 {
+			mantissaBits = 24
+		}
+		if f == math.Trunc(f) && math.Abs(f) > float64(int64(1)<<mantissaBits) {
+			return nil, fmt.Errorf("float value %v exceeds the range of exactly representable integers; "+
+				"use an integer type or dynamodbattribute.Number", f)
+		}
+	}
+
+	return v, nil
+}
+
+// exactConditionValues applies exactConditionValue to each of the filter's values
+func exactConditionValues(filter conditionFilter) (conditionFilter, error) {
+	var err error
+	switch f := filter.(type) {
+	case *equalsFilter:
+		f.value, err = exactConditionValue(f.value)
+	case *lessThanFilter:
+		f.value, err = exactConditionValue(f.value)
+	case *greaterThanFilter:
+		f.value, err = exactConditionValue(f.value)
+	case *lessThanEqualFilter:
+		f.value, err = exactConditionValue(f.value)
+	case *greaterThanEqualFilter:
+		f.value, err = exactConditionValue(f.value)
+	case *betweenFilter:
+		if f.lowval, err = exactConditionValue(f.lowval); err == nil {
+			f.highval, err = exactConditionValue(f.highval)
+		}
+	}
+	return filter, err
+}
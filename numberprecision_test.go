@@ -0,0 +1,114 @@
+package autoquery
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/dgravesa/dynamodb-autoquery/autoquerytest"
+)
+
+func TestExactNumberConditions(t *testing.T) {
+	decimal, _ := new(big.Float).SetPrec(200).SetString("0.12345678901234567890123")
+
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"large int64", int64(9007199254740993), "9007199254740993"},
+		{"big.Int", new(big.Int).SetInt64(9007199254740993), "9007199254740993"},
+		{"high-precision big.Float", decimal, "0.12345678901234567890123"},
+		{"json.Number", json.Number("0.12345678901234567890123"), "0.12345678901234567890123"},
+		{"dynamodbattribute.Number", dynamodbattribute.Number("0.12345678901234567890123"),
+			"0.12345678901234567890123"},
+	}
+
+	service := autoquerytest.NewService()
+	_, err := service.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("T"),
+		KeySchema: keySchema("pk", "sk"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("sk"), AttributeType: aws.String("N")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range testCases {
+		_, err := service.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("T"),
+			Item: map[string]*dynamodb.AttributeValue{
+				"pk": {S: aws.String("a")},
+				"sk": {N: aws.String(tc.expected)},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := NewClient(service)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := func() *Expression {
+				return NewExpression().Equal("pk", "a").Equal("sk", tc.value)
+			}
+
+			explanation, err := client.Query("T", expr()).Explain(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			numbers := []string{}
+			for _, value := range explanation.ExpressionAttributeValues {
+				if value.N != nil {
+					numbers = append(numbers, *value.N)
+				}
+			}
+			if len(numbers) != 1 || numbers[0] != tc.expected {
+				t.Errorf("expected N value %s, got %v", tc.expected, numbers)
+			}
+
+			var items []map[string]interface{}
+			parser := client.Query("T", expr())
+			for {
+				var it map[string]interface{}
+				err := parser.Next(context.Background(), &it)
+				if _, complete := err.(*ErrParsingComplete); complete {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				items = append(items, it)
+			}
+			if len(items) != 1 {
+				t.Errorf("expected the stored item to match, got %d items", len(items))
+			}
+		})
+	}
+}
+
+func TestLossyFloatConditionRejected(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	expr := NewExpression().Equal("pk", "a").Equal("sk", float64(1<<60))
+	err := client.Query("T", expr).Prepare(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "exactly representable") {
+		t.Errorf("expected lossy float to be rejected, got %v", err)
+	}
+}
+
+func TestReplacedLossyFloatConditionAccepted(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	expr := NewExpression().Equal("pk", "a").Equal("sk", float64(1<<60)).Equal("sk", 5)
+	if err := client.Query("T", expr).Prepare(context.Background()); err != nil {
+		t.Errorf("expected replaced lossy float condition to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package autoquery
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// rangeSegment holds the result of querying one sub-range of a split sort key range
+type rangeSegment struct {
+	done chan struct{}
+	page *queryPage
+	err  error
+}
+
+// parallelRangeBounds returns the inclusive bounds of each sub-range in ascending order when the
+// expression's sort key condition on the index may be split, or nil otherwise
+func (expr *Expression) parallelRangeBounds(index *tableIndex) [][2]int64 {
+	if expr.parallelSegments < 2 || expr.usePartiQL || !index.IsComposite {
+		return nil
+	}
+
+	between, ok := expr.filters[index.SortKey].(*betweenFilter)
+	if !ok {
+		return nil
+	}
+
+	low, lowOk := integerValue(between.lowval)
+	high, highOk := integerValue(between.highval)
+	if !lowOk || !highOk {
+		return nil
+	}
+
+	// sub-ranges are inclusive, so excluded integer bounds are narrowed by one
+	if between.excludeLow {
+		if low == math.MaxInt64 {
+			return nil
+		}
+		low++
+	}
+	if between.excludeHigh {
+		if high == math.MinInt64 {
+			return nil
+		}
+		high--
+	}
+	if high < low {
+		return nil
+	}
+
+	return splitIntegerRange(low, high, expr.parallelSegments)
+}
+
+// withSortKeyRange returns a copy of the expression restricted to the inclusive sort key range
+func (expr *Expression) withSortKeyRange(index *tableIndex, low, high int64) *Expression {
+	sub := *expr
+	sub.filters = map[string]conditionFilter{}
+	for attr, filter := range expr.filters {
+		sub.filters[attr] = filter
+	}
+	sub.filters[index.SortKey] = &betweenFilter{lowval: low, highval: high}
+	sub.parallelSegments = 0
+	sub.maxPages = 0
+	return &sub
+}
+
+// descending returns true if items are returned in descending sort key order
+func (expr *Expression) descending() bool {
+	return expr.latest || expr.descendingOrder || (expr.orderSpecified && !expr.orderAscending)
+}
+
+// splitIntegerRange splits the inclusive range [low, high] into at most segments contiguous,
+// non-overlapping sub-ranges of nearly equal size
+func splitIntegerRange(low, high int64, segments int) [][2]int64 {
+	// width is computed unsigned so that ranges spanning most of int64 do not overflow
+	width := uint64(high) - uint64(low)
+	n := uint64(segments)
+	if width < n-1 {
+		n = width + 1
+	}
+
+	// the range holds width+1 values, so the first remainder+1 segments hold one extra value
+	size, remainder := width/n, width%n
+
+	bounds := make([][2]int64, 0, n)
+	start := uint64(low)
+	for i := uint64(0); i < n; i++ {
+		count := size
+		if i <= remainder {
+			count++
+		}
+		end := start + count - 1
+		bounds = append(bounds, [2]int64{int64(start), int64(end)})
+		start = end + 1
+	}
+
+	return bounds
+}
+
+// integerValue returns the value of an integer condition value, dereferencing pointers in the same
+// way as values are marshaled
+func integerValue(v interface{}) (int64, bool) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return 0, false
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return 0, false
+	}
+
+	if number, ok := value.Interface().(dynamodbattribute.Number); ok {
+		i, err := strconv.ParseInt(string(number), 10, 64)
+		return i, err == nil
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(value.Uint()), true
+	}
+
+	return 0, false
+}
+
+// startParallelRange starts concurrent queries over each sub-range of the expression's sort key
+// range, returning false if the expression cannot be split on the selected index
+func (parser *Parser) startParallelRange(ctx context.Context) (bool, error) {
+	index, err := parser.selectIndex(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	bounds := parser.expr.parallelRangeBounds(index)
+	if bounds == nil {
+		return false, nil
+	}
+
+	// segments are returned in query order, so reverse them for descending queries
+	if parser.expr.descending() {
+		for i, j := 0, len(bounds)-1; i < j; i, j = i+1, j-1 {
+			bounds[i], bounds[j] = bounds[j], bounds[i]
+		}
+	}
+
+	if parser.expr.deduplicate {
+		indexMetadata, err := parser.client.pullIndexMetadata(ctx, parser.tableName)
+		if err != nil {
+			return false, err
+		}
+		parser.primaryKeys = indexMetadata.Indexes[0].getKeys()
+		parser.seenKeys = newKeySet(parser.expr.deduplicateMaxKeys)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	parser.rangeSegments = make([]*rangeSegment, len(bounds))
+	parser.nextSegment = 0
+	parser.cancelParallelRange = cancel
+	parser.parallelRangeDone = done
+
+	for i, bound := range bounds {
+		segment := &rangeSegment{done: make(chan struct{})}
+		parser.rangeSegments[i] = segment
+
+		subExpr := parser.expr.withSortKeyRange(index, bound[0], bound[1])
+		if parser.seenKeys != nil {
+			// items must include the table's key attributes to be deduplicated
+			subExpr = subExpr.withKeyAttributes(parser.primaryKeys)
+		}
+
+		sub := &Parser{
+			client:                parser.client,
+			tableName:             parser.tableName,
+			expr:                  subExpr,
+			limitPerPageSpecified: parser.limitPerPageSpecified,
+			limitPerPage:          parser.limitPerPage,
+			totalLimitSpecified:   parser.totalLimitSpecified,
+			totalLimit:            parser.totalLimit,
+			selectedIndex:         index,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(segment.done)
+			segment.page, segment.err = sub.fetchAllPages(ctx)
+			atomic.AddInt32(&parser.requestCount, sub.requestCount)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	return true, nil
+}
+
+// stopParallelRange cancels any sub-range queries, waits for them to exit, and discards their
+// results
+func (parser *Parser) stopParallelRange() {
+	if parser.cancelParallelRange != nil {
+		parser.cancelParallelRange()
+		<-parser.parallelRangeDone
+	}
+
+	parser.rangeSegments = nil
+	parser.nextSegment = 0
+	parser.cancelParallelRange = nil
+	parser.parallelRangeDone = nil
+	parser.seenKeys = nil
+}
+
+func (parser *Parser) nextRangeSegmentPage(ctx context.Context) (*queryPage, error) {
+	if parser.nextSegment == len(parser.rangeSegments) {
+		return nil, &ErrParsingComplete{reason: "all items have been parsed"}
+	}
+
+	segment := parser.rangeSegments[parser.nextSegment]
+	select {
+	case <-segment.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if segment.err != nil {
+		return nil, segment.err
+	}
+
+	parser.nextSegment++
+	if parser.seenKeys != nil {
+		// key attributes added for deduplication are removed once items are deduplicated
+		page := parser.deduplicatePage(segment.page)
+		parser.expr.stripUnselectedAttributes(page.items)
+		return page, nil
+	}
+	return segment.page, nil
+}
+
+// fetchAllPages fetches pages until all items have been parsed or the total limit is reached,
+// combining them into a single page
+func (parser *Parser) fetchAllPages(ctx context.Context) (*queryPage, error) {
+	all := &queryPage{items: []map[string]*dynamodb.AttributeValue{}}
+
+	for !parser.totalLimitReached() {
+		page, err := parser.fetchNextPage(ctx)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		all.items = append(all.items, page.items...)
+		all.consumedCapacity = addConsumedCapacity(all.consumedCapacity, page.consumedCapacity)
+		parser.returnedItems = len(all.items)
+	}
+
+	return all, nil
+}
@@ -0,0 +1,84 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// parseOrderIDs calls Next until parsing completes or fails, returning the ids of the returned
+// orders
+func parseOrderIDs(t *testing.T, parser *Parser) []int {
+	t.Helper()
+
+	ids := []int{}
+	for {
+		var order testOrder
+		err := parser.Next(context.Background(), &order)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			return ids
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, order.ID)
+	}
+}
+
+func TestParallelRangeMatchesSingleRange(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 50; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open"})
+	}
+	service := newOrdersService(t, orders...)
+
+	testCases := []struct {
+		name       string
+		descending bool
+	}{
+		{"ascending", false},
+		{"descending", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var queryCalls int32
+			client := NewClient(service)
+			client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+				opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+				atomic.AddInt32(&queryCalls, 1)
+				return service.QueryWithContext(ctx, input, opts...)
+			}
+
+			expr := func() *Expression {
+				expr := NewExpression().Equal("customer", "c").Between("id", 3, 47)
+				if tc.descending {
+					expr.Descending()
+				}
+				return expr
+			}
+
+			expected := parseOrderIDs(t, client.Query("Orders", expr()).SetLimitPerPage(5))
+			singleRangeCalls := atomic.LoadInt32(&queryCalls)
+			if len(expected) != 45 {
+				t.Fatalf("expected 45 orders in single range, got %d", len(expected))
+			}
+
+			atomic.StoreInt32(&queryCalls, 0)
+			actual := parseOrderIDs(t,
+				client.Query("Orders", expr().ParallelRange(4)).SetLimitPerPage(5))
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected %v, got %v", expected, actual)
+			}
+			if calls := atomic.LoadInt32(&queryCalls); calls <= singleRangeCalls {
+				t.Errorf("expected split range to query each sub-range, got %d calls", calls)
+			}
+		})
+	}
+}
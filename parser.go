@@ -2,6 +2,7 @@ package autoquery
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -9,12 +10,20 @@ import (
 )
 
 // Parser is used for parsing query results.
+//
+// A Parser is not safe for concurrent use. When prefetch is enabled, pagination state is owned by
+// a background goroutine, so methods other than Next, Close, and ConsumedCapacity should be called
+// before the first call to Next.
 type Parser struct {
 	client *Client
 
 	tableName string
 	expr      *Expression
 
+	// typeApplied is true once the table's registered type has been applied to the parser's
+	// expressions, which is deferred until a context identifies the scope to look it up in
+	typeApplied bool
+
 	maxPagesSpecified bool
 	maxPages          int
 	currentPage       int
@@ -22,12 +31,69 @@ type Parser struct {
 	limitPerPageSpecified bool
 	limitPerPage          int
 
+	totalLimitSpecified bool
+	totalLimit          int
+	returnedItems       int
+
 	exclusiveStartkey map[string]*dynamodb.AttributeValue
 
-	queryInput *dynamodb.QueryInput
+	selectedIndex *tableIndex
+	queryInput    *dynamodb.QueryInput
+
+	consumedCapacity *dynamodb.ConsumedCapacity
+
+	// requestCount is accessed atomically, since pages may be fetched by background goroutines
+	requestCount int32
+
+	// lastRequest holds the query input of the most recent page, stored by the page fetcher
+	lastRequest atomic.Value
+
+	statementInput *dynamodb.ExecuteStatementInput
+	nextToken      *string
 
 	bufferedItems      []map[string]*dynamodb.AttributeValue
 	currentBufferIndex int
+
+	// bufferedItemCount counts fetched items not yet returned by Next when max buffered items is
+	// set, and is accessed atomically, since pages may be fetched by background goroutines
+	maxBufferedItems  int
+	bufferedItemCount int32
+	bufferReleased    chan struct{}
+
+	prefetchPages   int
+	prefetchCtx     context.Context
+	prefetchedPages chan *prefetchedPage
+	cancelPrefetch  context.CancelFunc
+	prefetchDone    chan struct{}
+	prefetchErr     error
+
+	parallelRangeUnsplittable bool
+	rangeSegments             []*rangeSegment
+	nextSegment               int
+	cancelParallelRange       context.CancelFunc
+	parallelRangeDone         chan struct{}
+
+	// seenKeys holds the primary keys of items returned from merged sources when the expression
+	// deduplicates items
+	seenKeys    *keySet
+	primaryKeys []string
+
+	aggregates []*AggregateResult
+
+	fallbackExpr      *Expression
+	fallbackOnNoItems bool
+	usingFallback     bool
+
+	adaptivePagingThreshold int
+	pagingMode              PagingMode
+	eagerPagingErr          error
+
+	// eagerPagedItems is the number of items fetched by eager paging which are not yet buffered
+	eagerPagedItems int
+
+	transforms []func(
+		item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error)
+	transformErr error
 }
 
 // Next retrieves the next item in the query. The returnItem is unmarshaled with "dynamodbav"
@@ -35,8 +101,9 @@ type Parser struct {
 //
 // On the first call to Next with a new table, the table's index metadata will be retrieved using
 // the underlying metadata provider. For the default client created by NewClient, this requires
-// IAM permissions to describe the table. The metadata is cached for subsequent queries to the
-// table through the client instance used in the call to Query.
+// IAM permissions to describe the table, and an ErrDescribeTableAccessDenied error is returned if
+// permission is denied. The metadata is cached for subsequent queries to the table through the
+// client instance used in the call to Query.
 //
 // The first call to Next on a new Parser always makes a query call to DynamoDB. The query
 // automatically selects an index based on the table metadata and any expression restrictions. On
@@ -47,38 +114,76 @@ type Parser struct {
 // call returns an ErrNoViableIndexes error.
 //
 // Once all items have been returned or max pagination has been reached, the query will return
-// ErrParsingComplete.
+// ErrParsingComplete. A query that matches no items returns ErrParsingComplete on the first call
+// to Next, so an empty result set is always distinguishable from a failed query.
 func (parser *Parser) Next(ctx context.Context, returnItem interface{}) error {
-	// refill buffer if necessary, including first call
-	for parser.currentBufferIndex == len(parser.bufferedItems) {
-		// check for parsing complete conditions
-		if parser.allItemsParsed() {
-			return &ErrParsingComplete{reason: "all items have been parsed"}
-		} else if parser.maxPaginationReached() {
-			return &ErrParsingComplete{reason: "max pagination has been reached"}
-		}
+	currentItem, err := parser.nextItemWithFallback(ctx)
+	if err != nil {
+		return err
+	}
 
-		// construct query input using table metadata and expression on first call
-		if err := parser.buildQueryInput(ctx); err != nil {
-			return err
-		}
+	return dynamodbattribute.UnmarshalMap(currentItem, returnItem)
+}
+
+// nextItem returns the next item in the query, refilling the buffer as necessary
+func (parser *Parser) nextItem(ctx context.Context) (map[string]*dynamodb.AttributeValue, error) {
+	if parser.transformErr != nil {
+		return nil, parser.transformErr
+	} else if parser.totalLimitReached() {
+		return nil, &ErrParsingComplete{reason: "total limit has been reached"}
+	}
 
-		// execute new query to refill buffer
-		queryOutput, err := parser.client.dynamodbService.QueryWithContext(ctx, parser.queryInput)
+	// refill buffer if necessary, including first call
+	for parser.currentBufferIndex == len(parser.bufferedItems) {
+		page, err := parser.nextPage(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		parser.exclusiveStartkey = queryOutput.LastEvaluatedKey
-		parser.currentPage++
-		parser.bufferedItems = queryOutput.Items
+		parser.consumedCapacity = addConsumedCapacity(
+			parser.consumedCapacity, page.consumedCapacity)
+		parser.bufferedItems = page.items
 		parser.currentBufferIndex = 0
 	}
 
 	currentItem := parser.bufferedItems[parser.currentBufferIndex]
 	parser.currentBufferIndex++
+	parser.returnedItems++
+	parser.releaseBufferedItem()
 
-	return dynamodbattribute.UnmarshalMap(currentItem, returnItem)
+	currentItem, err := parser.applyTransforms(currentItem)
+	if err != nil {
+		return nil, err
+	}
+	parser.aggregateItem(currentItem)
+
+	return currentItem, nil
+}
+
+func (parser *Parser) nextPage(ctx context.Context) (*queryPage, error) {
+	if parser.expr.parallelSegments > 1 && !parser.parallelRangeUnsplittable {
+		if parser.rangeSegments == nil {
+			started, err := parser.startParallelRange(ctx)
+			if err != nil {
+				return nil, err
+			}
+			parser.parallelRangeUnsplittable = !started
+		}
+		if parser.rangeSegments != nil {
+			return parser.nextRangeSegmentPage(ctx)
+		}
+	}
+
+	if err := parser.choosePagingMode(ctx); err != nil {
+		return nil, err
+	}
+
+	if parser.prefetchPages > 0 {
+		return parser.nextPrefetchedPage(ctx)
+	} else if parser.pagingMode == EagerPaging {
+		return parser.fetchRemainingPages(ctx)
+	}
+	return parser.fetchNextPage(ctx)
 }
 
 // SetMaxPagination sets the maximum number of pages to query.
@@ -109,6 +214,128 @@ func (parser *Parser) UnsetLimitPerPage() *Parser {
 	return parser
 }
 
+// SetTotalLimit sets the maximum number of items returned by Next across all pages. Once limit
+// items have been returned, Next returns ErrParsingComplete.
+//
+// Unlike the limit per page, the total limit counts returned items rather than evaluated items.
+// When filter conditions discard items, additional pages are requested until the total limit is
+// reached or all items have been parsed. Unless prefetch is enabled, each page request evaluates
+// no more items than are needed to reach the total limit.
+func (parser *Parser) SetTotalLimit(limit int) *Parser {
+	parser.totalLimitSpecified = true
+	parser.totalLimit = limit
+	return parser
+}
+
+// UnsetTotalLimit unsets the maximum number of items returned by Next.
+func (parser *Parser) UnsetTotalLimit() *Parser {
+	parser.totalLimitSpecified = false
+	return parser
+}
+
+// SetPrefetch sets the number of pages that the parser may fetch ahead of the caller. When pages
+// is greater than 0, the first call to Next starts a background goroutine that requests pages
+// while previously fetched items are being consumed, overlapping network calls with processing.
+// Items are still returned in query order, and an error from a prefetched page is returned by
+// Next once all items preceding it have been returned.
+//
+// The background goroutine runs with the context passed to the first call to Next. It stops when
+// that context is cancelled, when parsing completes, or when Close is called. Prefetch should be
+// set before the first call to Next.
+func (parser *Parser) SetPrefetch(pages int) *Parser {
+	parser.prefetchPages = pages
+	return parser
+}
+
+// Prepare retrieves the table's metadata and selects the query's index without querying any items,
+// which otherwise happens on the first call to Next, so that an expression may be validated
+// before iterating. Prepare returns the same error Next would return if no index is viable, such
+// as ErrNoViableIndexes. The selected index is retained for subsequent calls to Next. Prepare
+// makes no service calls for PartiQL expressions.
+func (parser *Parser) Prepare(ctx context.Context) error {
+	if parser.expr.usePartiQL {
+		parser.applyRegisteredType(ctx)
+		return parser.expr.error()
+	}
+
+	_, err := parser.selectIndex(ctx)
+	return err
+}
+
+// SelectedIndexName returns the name of the index selected for the query, selecting it first if
+// Next or Prepare has not yet been called, so that the index a query resolves to may be logged or
+// asserted. The table's primary index is identified by PrimaryIndexName, or by PrimaryIndexLabel
+// if set. If no index is viable, the selection error is returned.
+func (parser *Parser) SelectedIndexName(ctx context.Context) (string, error) {
+	index, err := parser.selectIndex(ctx)
+	if err != nil {
+		return "", err
+	}
+	return parser.client.indexLabel(index.Name), nil
+}
+
+// Close stops any background page prefetching or parallel range queries and waits for them to
+// exit. Subsequent calls to Next return ErrParsingComplete once the items already buffered have
+// been returned. Close is only necessary when a parser with prefetch or a parallel range enabled
+// is abandoned before parsing completes and the context passed to Next is not cancelled.
+func (parser *Parser) Close() {
+	if parser.cancelPrefetch != nil {
+		parser.stopPrefetch()
+		parser.prefetchErr = &ErrParsingComplete{reason: "parser has been closed"}
+	}
+	if parser.cancelParallelRange != nil {
+		parser.stopParallelRange()
+		parser.rangeSegments = []*rangeSegment{}
+	}
+}
+
+// ConsumedCapacity returns the total capacity consumed by the query pages parsed so far, or nil
+// if consumed capacity was not returned. Consumed capacity is only returned when requested through
+// Client.ReturnConsumedCapacity or Expression.ReturnConsumedCapacity, and is not available for
+// PartiQL expressions.
+func (parser *Parser) ConsumedCapacity() *dynamodb.ConsumedCapacity {
+	return parser.consumedCapacity
+}
+
+// RequestCount returns the number of Query or ExecuteStatement calls made by the parser to fetch
+// pages so far, including calls made by background prefetching and parallel range queries. It does
+// not include DescribeTable calls made to retrieve the table's metadata, which is cached by the
+// client, or BatchGetItem calls made to fetch missing attributes.
+func (parser *Parser) RequestCount() int {
+	return int(atomic.LoadInt32(&parser.requestCount))
+}
+
+// Reset clears the parser's buffered items, pagination state, and consumed capacity so that the
+// next call to Next re-executes the query from the first page, such as when polling the same query
+// repeatedly. Any background prefetching or parallel range queries are stopped. The index selected
+// for the query and the constructed query input are retained, so index selection is not repeated.
+// Any exclusive start key set with SetExclusiveStartKey, the request returned by LastRequest, and
+// any error from a transform are also cleared, and the results of any aggregations are reset.
+func (parser *Parser) Reset() *Parser {
+	parser.stopPrefetch()
+	parser.prefetchErr = nil
+	parser.eagerPagingErr = nil
+	parser.transformErr = nil
+	parser.stopParallelRange()
+
+	parser.currentPage = 0
+	parser.exclusiveStartkey = nil
+	parser.nextToken = nil
+	parser.consumedCapacity = nil
+	parser.lastRequest.Store((*dynamodb.QueryInput)(nil))
+	atomic.StoreInt32(&parser.requestCount, 0)
+	parser.returnedItems = 0
+	parser.bufferedItems = []map[string]*dynamodb.AttributeValue{}
+	parser.currentBufferIndex = 0
+	atomic.StoreInt32(&parser.bufferedItemCount, 0)
+	for _, aggregate := range parser.aggregates {
+		aggregate.Value = 0
+		aggregate.Count = 0
+	}
+
+	return parser
+}
+
 // SetExclusiveStartKey sets the exclusive start key for the next page query call to DynamoDB.
 func (parser *Parser) SetExclusiveStartKey(
 	exclusiveStartKey map[string]*dynamodb.AttributeValue) *Parser {
@@ -116,6 +343,20 @@ func (parser *Parser) SetExclusiveStartKey(
 	return parser
 }
 
+// NextToken returns the last evaluated key of the most recent page query, or nil if no page has
+// been queried or all items have been parsed. When max pagination stops a query before all items
+// are parsed, the token may be passed to SetExclusiveStartKey on a new parser for the same
+// expression to resume the query once Next has returned ErrParsingComplete. NextToken always
+// returns nil for PartiQL expressions and for expressions split with ParallelRange.
+func (parser *Parser) NextToken() map[string]*dynamodb.AttributeValue {
+	if parser.expr.usePartiQL || parser.rangeSegments != nil || parser.currentPage == 0 ||
+		parser.lastEvaluatedKeyIsEmpty() {
+
+		return nil
+	}
+	return parser.exclusiveStartkey
+}
+
 // TODO: is this possible?
 // // LastParsedKey returns the key of the most recent item parsed by Next.
 // //
@@ -130,17 +371,158 @@ func (parser *Parser) lastEvaluatedKeyIsEmpty() bool {
 }
 
 func (parser *Parser) allItemsParsed() bool {
+	if parser.expr.usePartiQL {
+		return parser.currentPage > 0 && parser.nextToken == nil
+	}
 	return parser.currentPage > 0 && parser.lastEvaluatedKeyIsEmpty()
 }
 
+func (parser *Parser) totalLimitReached() bool {
+	return parser.totalLimitSpecified && (parser.returnedItems >= parser.totalLimit)
+}
+
 func (parser *Parser) maxPaginationReached() bool {
-	return parser.maxPagesSpecified && (parser.currentPage >= parser.maxPages)
+	if parser.maxPagesSpecified {
+		return parser.currentPage >= parser.maxPages
+	}
+	return parser.expr.maxPages > 0 && parser.currentPage >= parser.expr.maxPages
+}
+
+func (parser *Parser) fetchNextPage(ctx context.Context) (*queryPage, error) {
+	ctx = withPerCallTimeout(ctx, parser.expr.perCallTimeout)
+
+	// check for parsing complete conditions
+	if parser.allItemsParsed() {
+		return nil, &ErrParsingComplete{reason: "all items have been parsed"}
+	} else if parser.maxPaginationReached() {
+		return nil, &ErrParsingComplete{reason: "max pagination has been reached"}
+	}
+
+	// execute next page request
+	var page *queryPage
+	var err error
+	if parser.expr.usePartiQL {
+		page, err = parser.executeStatementPage(ctx)
+	} else {
+		page, err = parser.executeQueryPage(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parser.currentPage++
+	parser.holdBufferedItems(len(page.items))
+
+	return page, nil
+}
+
+func (parser *Parser) executeQueryPage(ctx context.Context) (*queryPage, error) {
+	// construct query input using table metadata and expression on first call
+	if err := parser.buildQueryInput(ctx); err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt32(&parser.requestCount, 1)
+	parser.recordRequest(parser.queryInput)
+	queryOutput, err := parser.client.query(
+		ctx, parser.queryInput, requestOptionsFromContext(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	parser.exclusiveStartkey = queryOutput.LastEvaluatedKey
+
+	items, err := parser.expr.discardExcludedBounds(parser.selectedIndex, queryOutput.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	consumedCapacity := queryOutput.ConsumedCapacity
+	if parser.expr.fetchesMissingAttributes(parser.selectedIndex) {
+		var fetchConsumedCapacity *dynamodb.ConsumedCapacity
+		items, fetchConsumedCapacity, err = parser.client.fetchItems(
+			ctx, parser.tableName, parser.expr, items, parser.queryInput.ReturnConsumedCapacity)
+		if err != nil {
+			return nil, err
+		}
+		consumedCapacity = addConsumedCapacity(consumedCapacity, fetchConsumedCapacity)
+	}
+	parser.expr.stripUnselectedAttributes(items)
+	if err := parser.expr.applyReadTransforms(items); err != nil {
+		return nil, err
+	}
+
+	return &queryPage{
+		items:            parser.expr.applyPostFilters(items),
+		consumedCapacity: consumedCapacity,
+	}, nil
+}
+
+func (parser *Parser) executeStatementPage(ctx context.Context) (*queryPage, error) {
+	// construct statement input using table metadata and expression on first call
+	if parser.statementInput == nil {
+		queryIndex, err := parser.selectIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		parser.statementInput, err = parser.expr.constructStatementInputGivenIndex(
+			parser.tableName, queryIndex)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	parser.statementInput.NextToken = parser.nextToken
+
+	atomic.AddInt32(&parser.requestCount, 1)
+	var statementOutput *dynamodb.ExecuteStatementOutput
+	err := parser.client.callWithTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		statementOutput, err = parser.client.dynamodbService.ExecuteStatementWithContext(
+			ctx, parser.statementInput, requestOptionsFromContext(ctx)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parser.nextToken = statementOutput.NextToken
+
+	if err := parser.expr.applyReadTransforms(statementOutput.Items); err != nil {
+		return nil, err
+	}
+
+	return &queryPage{items: parser.expr.applyPostFilters(statementOutput.Items)}, nil
+}
+
+func (parser *Parser) selectIndex(ctx context.Context) (*tableIndex, error) {
+	parser.applyRegisteredType(ctx)
+	if err := parser.expr.error(); err != nil {
+		return nil, err
+	}
+
+	// select index on first call
+	if parser.selectedIndex == nil {
+		queryIndex, err := parser.client.chooseIndex(ctx, parser.tableName, parser.expr)
+		if _, noViableIndexes := err.(*ErrNoViableIndexes); noViableIndexes &&
+			parser.switchToFallback() {
+
+			return parser.selectIndex(ctx)
+		} else if err != nil {
+			return nil, err
+		}
+		parser.expr = parser.expr.boundToIndex(queryIndex)
+		parser.selectedIndex = queryIndex
+	}
+
+	return parser.selectedIndex, nil
 }
 
 func (parser *Parser) buildQueryInput(ctx context.Context) error {
 	// select index and construct expression on first call
 	if parser.queryInput == nil {
-		queryIndex, err := parser.client.chooseIndex(ctx, parser.tableName, parser.expr)
+		queryIndex, err := parser.selectIndex(ctx)
 		if err != nil {
 			return err
 		}
@@ -153,13 +535,43 @@ func (parser *Parser) buildQueryInput(ctx context.Context) error {
 
 	parser.queryInput.TableName = aws.String(parser.tableName)
 
+	parser.queryInput.Limit = nil
 	if parser.limitPerPageSpecified {
 		parser.queryInput.Limit = aws.Int64(int64(parser.limitPerPage))
-	} else {
-		parser.queryInput.Limit = nil
+	}
+
+	// avoid evaluating more items than are needed to reach the total limit; returned items are
+	// only known by the page fetcher when pages are not prefetched, and Limit counts evaluated
+	// items, so it only bounds returned items when none are filtered out
+	if parser.totalLimitSpecified && parser.prefetchPages == 0 && !parser.filtersItems() {
+		remaining := int64(parser.totalLimit - parser.returnedItems - parser.eagerPagedItems)
+		if remaining > 0 && (parser.queryInput.Limit == nil || remaining < *parser.queryInput.Limit) {
+			parser.queryInput.Limit = aws.Int64(remaining)
+		}
+	}
+
+	// avoid fetching more items than the parser's buffer has space for
+	if parser.maxBufferedItems > 0 {
+		space := int64(parser.bufferSpace())
+		if space > 0 && (parser.queryInput.Limit == nil || space < *parser.queryInput.Limit) {
+			parser.queryInput.Limit = aws.Int64(space)
+		}
 	}
 
 	parser.queryInput.ExclusiveStartKey = parser.exclusiveStartkey
 
+	if parser.expr.returnConsumedCapacitySpecified {
+		parser.queryInput.ReturnConsumedCapacity = aws.String(parser.expr.returnConsumedCapacity)
+	} else if parser.client.ReturnConsumedCapacity != "" {
+		parser.queryInput.ReturnConsumedCapacity = aws.String(parser.client.ReturnConsumedCapacity)
+	}
+
 	return nil
 }
+
+// filtersItems returns true if items evaluated by the query may be left out of the results, either
+// by the query's filter expression or by conditions applied to returned pages
+func (parser *Parser) filtersItems() bool {
+	return parser.queryInput.FilterExpression != nil || len(parser.expr.postFilters) > 0 ||
+		parser.expr.excludedSortKeyBounds(parser.selectedIndex) != nil
+}
@@ -0,0 +1,237 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestSelectedIndexName(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	testCases := []struct {
+		name      string
+		expr      *Expression
+		label     string
+		indexName string
+	}{
+		{"primary", NewExpression().Equal("pk", "a"), "", PrimaryIndexName},
+		{"labeled primary", NewExpression().Equal("pk", "a"), "T", "T"},
+		{"secondary", NewExpression().Equal("g", "x"), "", "g-sk"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client.PrimaryIndexLabel = testCase.label
+
+			indexName, err := client.Query("T", testCase.expr).SelectedIndexName(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if indexName != testCase.indexName {
+				t.Errorf("expected index %q, got %q", testCase.indexName, indexName)
+			}
+		})
+	}
+}
+
+func TestSelectedIndexNameNoViableIndexes(t *testing.T) {
+	client := NewClient(newFakeService(1))
+
+	parser := client.Query("T", NewExpression().Equal("other", "a"))
+	indexName, err := parser.SelectedIndexName(context.Background())
+	if _, ok := err.(*ErrNoViableIndexes); !ok {
+		t.Fatalf("expected ErrNoViableIndexes, got %v", err)
+	}
+	if indexName != "" {
+		t.Errorf("expected no index name, got %q", indexName)
+	}
+}
+
+func TestSelectedIndexNameRetainsSelection(t *testing.T) {
+	service := newFakeService(1)
+	client := NewClient(service)
+
+	parser := client.Query("T", NewExpression().Equal("g", "x"))
+	if err := parser.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// removing the index does not change the already selected index
+	service.table.GlobalSecondaryIndexes = nil
+	if err := client.RefreshTableMetadata(context.Background(), "T"); err != nil {
+		t.Fatal(err)
+	}
+
+	indexName, err := parser.SelectedIndexName(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexName != "g-sk" {
+		t.Errorf("expected index %q, got %q", "g-sk", indexName)
+	}
+}
+
+func TestPrepareMakesNoServiceCallsForPartiQL(t *testing.T) {
+	service := newFakeService(1)
+	client := NewClient(service)
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a").UsePartiQL())
+	if err := parser.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := service.describeCallCount("T"); calls != 0 {
+		t.Errorf("expected no DescribeTable calls, got %d", calls)
+	}
+}
+
+func TestTotalLimitWithSelectiveFilter(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 20; id++ {
+		total := 0
+		if id%4 == 0 {
+			total = 1
+		}
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open", Total: total})
+	}
+	service := newOrdersService(t, orders...)
+
+	limits := []int64{}
+	client := NewClient(service)
+	client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		limits = append(limits, aws.Int64Value(input.Limit))
+		return service.QueryWithContext(ctx, input, opts...)
+	}
+
+	parser := client.Query("Orders", NewExpression().Equal("customer", "c").Equal("total", 1))
+	parser.SetLimitPerPage(4).SetTotalLimit(3)
+
+	ids := []int{}
+	for {
+		var order testOrder
+		err := parser.Next(context.Background(), &order)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, order.ID)
+	}
+
+	if expected := []int{4, 8, 12}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected ids %v, got %v", expected, ids)
+	}
+
+	// Limit counts evaluated items, so filtered pages use the page size rather than the remaining
+	// total, and no pages are fetched once the total is reached
+	if expected := []int64{4, 4, 4}; !reflect.DeepEqual(limits, expected) {
+		t.Errorf("expected page limits %v, got %v", expected, limits)
+	}
+}
+
+func TestTotalLimitCapsUnfilteredPages(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 20; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open"})
+	}
+	service := newOrdersService(t, orders...)
+
+	limits := []int64{}
+	client := NewClient(service)
+	client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		limits = append(limits, aws.Int64Value(input.Limit))
+		return service.QueryWithContext(ctx, input, opts...)
+	}
+
+	parser := client.Query("Orders", NewExpression().Equal("customer", "c"))
+	parser.SetLimitPerPage(4).SetTotalLimit(6)
+
+	if ids := parseOrderIDs(t, parser); !reflect.DeepEqual(ids, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("expected ids 1 through 6, got %v", ids)
+	}
+	if expected := []int64{4, 2}; !reflect.DeepEqual(limits, expected) {
+		t.Errorf("expected page limits %v, got %v", expected, limits)
+	}
+}
+
+func TestNextOnEmptyResult(t *testing.T) {
+	client := NewClient(newOrdersService(t, testOrder{Customer: "c", ID: 1, Status: "open"}))
+
+	testCases := []struct {
+		name string
+		expr *Expression
+	}{
+		{"no items in partition", NewExpression().Equal("customer", "d")},
+		{"no items in range", NewExpression().Equal("customer", "c").GreaterThan("id", 1)},
+		{"no items match filter", NewExpression().Equal("customer", "c").Equal("status", "closed")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.Query("Orders", tc.expr)
+
+			// parsing remains complete on later calls
+			for i := 0; i < 2; i++ {
+				var order testOrder
+				err := parser.Next(context.Background(), &order)
+				if _, complete := err.(*ErrParsingComplete); !complete {
+					t.Fatalf("expected ErrParsingComplete, got %v", err)
+				}
+				if !reflect.DeepEqual(order, testOrder{}) {
+					t.Errorf("expected item to be left unchanged, got %v", order)
+				}
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	service := newFakeService(2, 2)
+	client := NewClient(service)
+	ctx := context.Background()
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a"))
+	sortKeys, err := parseSortKeys(ctx, parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{1, 2, 3, 4}; !reflect.DeepEqual(sortKeys, expected) {
+		t.Fatalf("expected sort keys %v, got %v", expected, sortKeys)
+	}
+	if parser.LastRequest() == nil || parser.LastRequest().ExclusiveStartKey == nil {
+		t.Fatal("expected last request to continue from the first page")
+	}
+
+	parser.Reset()
+	if request := parser.LastRequest(); request != nil {
+		t.Errorf("expected no last request after reset, got %v", request)
+	}
+	if count := parser.RequestCount(); count != 0 {
+		t.Errorf("expected request count 0 after reset, got %d", count)
+	}
+
+	sortKeys, err = parseSortKeys(ctx, parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{1, 2, 3, 4}; !reflect.DeepEqual(sortKeys, expected) {
+		t.Errorf("expected sort keys %v after reset, got %v", expected, sortKeys)
+	}
+	if calls := service.queryCallCount(); calls != 4 {
+		t.Errorf("expected first page to be queried again, got %d query calls", calls)
+	}
+	if calls := service.describeCallCount("T"); calls != 1 {
+		t.Errorf("expected index selection to be retained, got %d describe calls", calls)
+	}
+	if input := service.queryInputs[2]; input.ExclusiveStartKey != nil {
+		t.Errorf("expected query from the first page, got start key %v", input.ExclusiveStartKey)
+	}
+}
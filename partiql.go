@@ -0,0 +1,168 @@
+package autoquery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (expr *Expression) constructStatementInputGivenIndex(
+	tableName string, index *tableIndex) (*dynamodb.ExecuteStatementInput, error) {
+
+	// conditions from the DynamoDB expression package cannot be translated to PartiQL
+	if len(expr.additionalConditions) > 0 {
+		return nil, errors.New("filter conditions are not supported by PartiQL expressions")
+	}
+
+	conditions := []string{}
+	parameters := []*dynamodb.AttributeValue{}
+
+	appendParameter := func(v interface{}) error {
+		av, err := dynamodbattribute.Marshal(v)
+		if err != nil {
+			return err
+		}
+		parameters = append(parameters, av)
+		return nil
+	}
+
+	// order conditions as partition key, sort key, then remaining attributes by name so that the
+	// generated statement is deterministic
+	attrs := []string{}
+	for attr := range expr.filters {
+		if attr != index.PartitionKey && !(index.IsComposite && attr == index.SortKey) {
+			attrs = append(attrs, attr)
+		}
+	}
+	sort.Strings(attrs)
+	if index.IsComposite {
+		if _, found := expr.filters[index.SortKey]; found {
+			attrs = append([]string{index.SortKey}, attrs...)
+		}
+	}
+	attrs = append([]string{index.PartitionKey}, attrs...)
+
+	for _, attr := range attrs {
+		// every item in the index has its sort key
+		isSortKey := index.IsComposite && attr == index.SortKey
+		attrConditions, err := partiQLConditions(quotePartiQLIdentifier(attr), expr.filters[attr],
+			isSortKey, appendParameter)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, attrConditions...)
+	}
+
+	// set projection if specified
+	projection := "*"
+	if expr.attributesSpecified {
+		names := []string{}
+		for _, attribute := range expr.attributes {
+			names = append(names, quotePartiQLIdentifier(attribute))
+		}
+		projection = strings.Join(names, ", ")
+	}
+
+	source := quotePartiQLIdentifier(tableName)
+	if index.Name != tablePrimaryIndexName {
+		source = fmt.Sprintf("%s.%s", source, quotePartiQLIdentifier(index.Name))
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		projection, source, strings.Join(conditions, " AND "))
+
+	if expr.descendingOrder && !index.IsComposite {
+		return nil, errDescendingWithoutSortKey(index)
+	}
+
+	if expr.latest || expr.descendingOrder {
+		statement = fmt.Sprintf("%s ORDER BY %s DESC",
+			statement, quotePartiQLIdentifier(index.SortKey))
+	} else if expr.orderSpecified {
+		direction := "ASC"
+		if !expr.orderAscending {
+			direction = "DESC"
+		}
+		statement = fmt.Sprintf("%s ORDER BY %s %s",
+			statement, quotePartiQLIdentifier(expr.orderAttribute), direction)
+	}
+
+	statementInput := &dynamodb.ExecuteStatementInput{
+		Statement:  aws.String(statement),
+		Parameters: parameters,
+	}
+
+	if expr.readsConsistently(index) {
+		statementInput.ConsistentRead = aws.Bool(true)
+	}
+
+	return statementInput, nil
+}
+
+func quotePartiQLIdentifier(identifier string) string {
+	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(identifier, "\"", "\"\""))
+}
+
+// partiQLConditions returns the PartiQL conditions for a filter on the named attribute, appending
+// the condition values with appendParameter
+func partiQLConditions(name string, filter conditionFilter, isSortKey bool,
+	appendParameter func(v interface{}) error) ([]string, error) {
+
+	conditions := []string{}
+	var err error
+	switch f := filter.(type) {
+	case *equalsFilter:
+		conditions = append(conditions, fmt.Sprintf("%s = ?", name))
+		err = appendParameter(f.value)
+	case *lessThanFilter:
+		conditions = append(conditions, fmt.Sprintf("%s < ?", name))
+		err = appendParameter(f.value)
+	case *greaterThanFilter:
+		conditions = append(conditions, fmt.Sprintf("%s > ?", name))
+		err = appendParameter(f.value)
+	case *lessThanEqualFilter:
+		conditions = append(conditions, fmt.Sprintf("%s <= ?", name))
+		err = appendParameter(f.value)
+	case *greaterThanEqualFilter:
+		conditions = append(conditions, fmt.Sprintf("%s >= ?", name))
+		err = appendParameter(f.value)
+	case *betweenFilter:
+		if f.excludeLow || f.excludeHigh {
+			lowOperator, highOperator := ">=", "<="
+			if f.excludeLow {
+				lowOperator = ">"
+			}
+			if f.excludeHigh {
+				highOperator = "<"
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s ? AND %s %s ?",
+				name, lowOperator, name, highOperator))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("%s BETWEEN ? AND ?", name))
+		}
+		if err = appendParameter(f.lowval); err == nil {
+			err = appendParameter(f.highval)
+		}
+	case *beginsWithFilter:
+		conditions = append(conditions, fmt.Sprintf("begins_with(%s, ?)", name))
+		err = appendParameter(f.prefix)
+	case *existsFilter:
+		if !isSortKey {
+			conditions = append(conditions, fmt.Sprintf("%s IS NOT MISSING", name))
+		}
+	case *andFilter:
+		for _, part := range f.filters {
+			partConditions, err := partiQLConditions(name, part, isSortKey, appendParameter)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, partConditions...)
+		}
+	}
+	return conditions, err
+}
@@ -0,0 +1,59 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestPartiQLStatement(t *testing.T) {
+	testCases := []struct {
+		name               string
+		expr               *Expression
+		expectedStatement  string
+		expectedParameters []*dynamodb.AttributeValue
+	}{
+		{
+			name:              "partition equals and sort key range on primary index",
+			expr:              NewExpression().Equal("pk", "a").Between("sk", 1, 9),
+			expectedStatement: `SELECT * FROM "T" WHERE "pk" = ? AND "sk" BETWEEN ? AND ?`,
+			expectedParameters: []*dynamodb.AttributeValue{
+				{S: aws.String("a")}, {N: aws.String("1")}, {N: aws.String("9")},
+			},
+		},
+		{
+			name:              "partition equals and sort key range on secondary index",
+			expr:              NewExpression().Equal("g", "x").GreaterThan("sk", 3),
+			expectedStatement: `SELECT * FROM "T"."g-sk" WHERE "g" = ? AND "sk" > ?`,
+			expectedParameters: []*dynamodb.AttributeValue{
+				{S: aws.String("x")}, {N: aws.String("3")},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeService(1)
+			client := NewClient(service)
+
+			explanation, err := client.Query("T", tc.expr.UsePartiQL()).Explain(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if explanation.Statement != tc.expectedStatement {
+				t.Errorf("expected statement %s, got %s", tc.expectedStatement, explanation.Statement)
+			}
+			if !reflect.DeepEqual(explanation.Parameters, tc.expectedParameters) {
+				t.Errorf("expected parameters %v, got %v",
+					tc.expectedParameters, explanation.Parameters)
+			}
+			if calls := service.queryCallCount(); calls != 0 {
+				t.Errorf("expected no query calls, got %d", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type perCallTimeoutKey struct{}
+
+// PerCallTimeout limits each individual DynamoDB call made for the expression, including Query,
+// ExecuteStatement, and BatchGetItem calls and calls to the metadata provider, to the duration d.
+// Each call is made with its own context derived from the context passed to Parser.Next or
+// Client.Count, so a single slow page cannot consume the whole of a long overall deadline.
+//
+// A call which exceeds the timeout while the overall context is still live is retried up to
+// Client.PerCallTimeoutRetries times, after which an ErrCallTimeout error is returned. If d is 0,
+// calls are limited only by the overall context. If d is negative, Parser.Next returns an error.
+func (expr *Expression) PerCallTimeout(d time.Duration) *Expression {
+	if d < 0 {
+		expr.setErr(fmt.Errorf("per-call timeout must not be negative: %s", d))
+		return expr
+	}
+	expr.perCallTimeout = d
+	return expr
+}
+
+// withPerCallTimeout returns a copy of ctx which carries the per-call timeout, if set
+func withPerCallTimeout(ctx context.Context, d time.Duration) context.Context {
+	if d <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, perCallTimeoutKey{}, d)
+}
+
+// callWithTimeout makes the call with the per-call timeout carried by ctx, if any, retrying calls
+// which time out while ctx is still live
+func (client *Client) callWithTimeout(
+	ctx context.Context, call func(ctx context.Context) error) error {
+
+	timeout, _ := ctx.Value(perCallTimeoutKey{}).(time.Duration)
+	if timeout <= 0 {
+		return call(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := call(callCtx)
+		timedOut := err != nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) &&
+			ctx.Err() == nil
+		cancel()
+
+		if !timedOut {
+			return err
+		} else if attempt >= client.PerCallTimeoutRetries {
+			return &ErrCallTimeout{Timeout: timeout, Attempts: attempt + 1, Err: err}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestPerCallTimeoutRetriesSlowCalls(t *testing.T) {
+	testCases := []struct {
+		name             string
+		slowCalls        int
+		retries          int
+		expectedAttempts int
+		expectTimeout    bool
+	}{
+		{"slow call retried", 1, 1, 2, false},
+		{"slow call not retried", 1, 0, 1, true},
+		{"retries exhausted", 5, 2, 3, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeService(2)
+			client := NewClient(service)
+			client.PerCallTimeoutRetries = tc.retries
+
+			calls := 0
+			client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+				opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+				calls++
+				if calls <= tc.slowCalls {
+					// hang until the per-call timeout cancels the call
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return service.QueryWithContext(ctx, input, opts...)
+			}
+
+			// the overall deadline is far longer than the per-call timeout
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			expr := NewExpression().Equal("pk", "a").PerCallTimeout(10 * time.Millisecond)
+			values, err := parseSortKeys(ctx, client.Query("T", expr))
+
+			if tc.expectTimeout {
+				timeoutErr, ok := err.(*ErrCallTimeout)
+				if !ok {
+					t.Fatalf("expected ErrCallTimeout, got %v", err)
+				}
+				if timeoutErr.Attempts != tc.expectedAttempts {
+					t.Errorf("expected %d attempts, got %d",
+						tc.expectedAttempts, timeoutErr.Attempts)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected := []int{1, 2}; !reflect.DeepEqual(values, expected) {
+					t.Errorf("expected %v, got %v", expected, values)
+				}
+			}
+
+			if calls != tc.expectedAttempts {
+				t.Errorf("expected %d query calls, got %d", tc.expectedAttempts, calls)
+			}
+		})
+	}
+}
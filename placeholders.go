@@ -0,0 +1,45 @@
+package autoquery
+
+import (
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var placeholderPattern = regexp.MustCompile(`[#:][A-Za-z0-9_]+`)
+
+// pruneUnusedPlaceholders removes expression attribute names and values which are not referenced
+// by the query input's expressions, since DynamoDB rejects unused placeholders
+func pruneUnusedPlaceholders(queryInput *dynamodb.QueryInput) {
+	referenced := map[string]struct{}{}
+	for _, s := range []*string{
+		queryInput.KeyConditionExpression,
+		queryInput.FilterExpression,
+		queryInput.ProjectionExpression,
+	} {
+		if s == nil {
+			continue
+		}
+		for _, placeholder := range placeholderPattern.FindAllString(*s, -1) {
+			referenced[placeholder] = struct{}{}
+		}
+	}
+
+	for placeholder := range queryInput.ExpressionAttributeNames {
+		if _, found := referenced[placeholder]; !found {
+			delete(queryInput.ExpressionAttributeNames, placeholder)
+		}
+	}
+	if len(queryInput.ExpressionAttributeNames) == 0 {
+		queryInput.ExpressionAttributeNames = nil
+	}
+
+	for placeholder := range queryInput.ExpressionAttributeValues {
+		if _, found := referenced[placeholder]; !found {
+			delete(queryInput.ExpressionAttributeValues, placeholder)
+		}
+	}
+	if len(queryInput.ExpressionAttributeValues) == 0 {
+		queryInput.ExpressionAttributeValues = nil
+	}
+}
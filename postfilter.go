@@ -0,0 +1,47 @@
+package autoquery
+
+import "github.com/aws/aws-sdk-go/service/dynamodb"
+
+// PostFilter adds a condition that is evaluated on each item after it is read from DynamoDB and
+// before it is returned by Parser.Next, for conditions that cannot be expressed in a DynamoDB
+// filter expression, such as regular expression matches. Items for which keep returns false are
+// discarded. Subsequent calls to PostFilter append additional post-filters, and an item is only
+// returned if every post-filter keeps it.
+//
+// Post-filters are not considered for index selection and do not reduce the items read from
+// DynamoDB, so the read cost of the query is the same as without them. Discarded items do not
+// count toward a parser's total limit; additional pages are requested until the total limit is
+// reached or all items have been parsed. Items only include the attributes projected by the query,
+// so any attributes used by a post-filter should be selected if attributes are specified.
+// Post-filters are not applied by Client.Count.
+func (expr *Expression) PostFilter(
+	keep func(item map[string]*dynamodb.AttributeValue) bool) *Expression {
+
+	expr.postFilters = append(expr.postFilters, keep)
+	return expr
+}
+
+// applyPostFilters returns the items kept by all of the expression's post-filters
+func (expr *Expression) applyPostFilters(
+	items []map[string]*dynamodb.AttributeValue) []map[string]*dynamodb.AttributeValue {
+
+	if len(expr.postFilters) == 0 {
+		return items
+	}
+
+	keptItems := []map[string]*dynamodb.AttributeValue{}
+	for _, item := range items {
+		kept := true
+		for _, keep := range expr.postFilters {
+			if !keep(item) {
+				kept = false
+				break
+			}
+		}
+		if kept {
+			keptItems = append(keptItems, item)
+		}
+	}
+
+	return keptItems
+}
@@ -0,0 +1,60 @@
+package autoquery
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestPostFilterTotalLimit(t *testing.T) {
+	orders := []testOrder{}
+	for id := 1; id <= 10; id++ {
+		orders = append(orders, testOrder{Customer: "c", ID: id, Status: "open"})
+	}
+	service := newOrdersService(t, orders...)
+
+	evenID := func(item map[string]*dynamodb.AttributeValue) bool {
+		id, err := strconv.Atoi(aws.StringValue(item["id"].N))
+		return err == nil && id%2 == 0
+	}
+
+	testCases := []struct {
+		name           string
+		limitPerPage   int
+		expectedLimits []int64
+	}{
+		{"without page size", 0, []int64{0}},
+		// Limit counts evaluated items, so pages are not capped at the remaining total
+		{"with page size", 2, []int64{2, 2, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limits := []int64{}
+			client := NewClient(service)
+			client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+				opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+				limits = append(limits, aws.Int64Value(input.Limit))
+				return service.QueryWithContext(ctx, input, opts...)
+			}
+
+			parser := client.Query("Orders", NewExpression().Equal("customer", "c").PostFilter(evenID))
+			if tc.limitPerPage > 0 {
+				parser.SetLimitPerPage(tc.limitPerPage)
+			}
+			parser.SetTotalLimit(3)
+
+			if ids := parseOrderIDs(t, parser); !reflect.DeepEqual(ids, []int{2, 4, 6}) {
+				t.Errorf("expected ids [2 4 6], got %v", ids)
+			}
+			if !reflect.DeepEqual(limits, tc.expectedLimits) {
+				t.Errorf("expected page limits %v, got %v", tc.expectedLimits, limits)
+			}
+		})
+	}
+}
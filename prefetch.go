@@ -0,0 +1,85 @@
+package autoquery
+
+import "context"
+
+type prefetchedPage struct {
+	page *queryPage
+	err  error
+}
+
+func (parser *Parser) startPrefetch(ctx context.Context) {
+	// the goroutine holds one fetched page while blocked, so the channel buffers one page fewer
+	// than the number of pages that may be fetched ahead
+	pages := make(chan *prefetchedPage, parser.prefetchPages-1)
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(ctx)
+
+	parser.prefetchCtx = ctx
+	parser.prefetchedPages = pages
+	parser.cancelPrefetch = cancel
+	parser.prefetchDone = done
+	parser.bufferReleased = make(chan struct{}, 1)
+
+	// pagination state is owned by the goroutine until it exits
+	go func() {
+		defer close(done)
+		defer close(pages)
+		for {
+			// fetching pauses while the parser's buffer is full
+			if err := parser.waitForBufferSpace(ctx); err != nil {
+				return
+			}
+
+			page, err := parser.fetchNextPage(ctx)
+			select {
+			case pages <- &prefetchedPage{page: page, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// stopPrefetch cancels any background prefetching and waits for the goroutine to exit, returning
+// ownership of pagination state to the caller
+func (parser *Parser) stopPrefetch() {
+	if parser.cancelPrefetch == nil {
+		return
+	}
+
+	parser.cancelPrefetch()
+	<-parser.prefetchDone
+
+	parser.prefetchCtx = nil
+	parser.prefetchedPages = nil
+	parser.cancelPrefetch = nil
+	parser.prefetchDone = nil
+}
+
+func (parser *Parser) nextPrefetchedPage(ctx context.Context) (*queryPage, error) {
+	if parser.prefetchErr != nil {
+		return nil, parser.prefetchErr
+	}
+
+	if parser.prefetchedPages == nil {
+		parser.startPrefetch(ctx)
+	}
+
+	select {
+	case prefetched, ok := <-parser.prefetchedPages:
+		if !ok {
+			// prefetching stopped before a final page was sent, so its context was cancelled
+			parser.prefetchErr = parser.prefetchCtx.Err()
+			return nil, parser.prefetchErr
+		}
+		if prefetched.err != nil {
+			parser.prefetchErr = prefetched.err
+		}
+		return prefetched.page, prefetched.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
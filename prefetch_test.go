@@ -0,0 +1,119 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestPrefetchOverlapsFetches(t *testing.T) {
+	service := newFakeService(2, 1, 0, 2)
+	service.queryDelay = 10 * time.Millisecond
+	client := NewClient(service)
+
+	queryStarted := make(chan struct{}, 10)
+	client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		queryStarted <- struct{}{}
+		return service.QueryWithContext(ctx, input, opts...)
+	}
+
+	ctx := context.Background()
+	parser := client.Query("T", NewExpression().Equal("pk", "a")).SetPrefetch(2)
+	defer parser.Close()
+
+	var record testRecord
+	if err := parser.Next(ctx, &record); err != nil {
+		t.Fatal(err)
+	}
+	<-queryStarted
+
+	// the next page is fetched while the caller holds the first page, without calling Next
+	select {
+	case <-queryStarted:
+	case <-time.After(time.Second):
+		t.Fatal("expected next page to be fetched in the background")
+	}
+
+	values, err := parseSortKeys(ctx, parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{2, 3, 4, 5}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected items %v in order, got %v", expected, values)
+	}
+}
+
+func TestPrefetchErrorAfterPrecedingItems(t *testing.T) {
+	service := newFakeService(2, 1, 2)
+	client := NewClient(service)
+
+	queryErr := errors.New("query failed")
+	client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		if service.queryCallCount() == 2 {
+			return nil, queryErr
+		}
+		return service.QueryWithContext(ctx, input, opts...)
+	}
+
+	parser := client.Query("T", NewExpression().Equal("pk", "a")).SetPrefetch(3)
+	values, err := parseSortKeys(context.Background(), parser)
+	if err != queryErr {
+		t.Errorf("expected query error, got %v", err)
+	}
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected items %v before the error, got %v", expected, values)
+	}
+}
+
+func TestPrefetchDoesNotLeakGoroutines(t *testing.T) {
+	testCases := []struct {
+		name string
+		stop func(parser *Parser, cancel context.CancelFunc)
+	}{
+		{"close", func(parser *Parser, cancel context.CancelFunc) { parser.Close() }},
+		{"cancel", func(parser *Parser, cancel context.CancelFunc) { cancel() }},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			service := newFakeService(1, 1, 1, 1, 1, 1)
+			service.queryDelay = 5 * time.Millisecond
+			client := NewClient(service)
+			goroutines := runtime.NumGoroutine()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			parser := client.Query("T", NewExpression().Equal("pk", "a")).SetPrefetch(2)
+
+			var record testRecord
+			if err := parser.Next(ctx, &record); err != nil {
+				t.Fatal(err)
+			}
+			testCase.stop(parser, cancel)
+
+			// the prefetch goroutine may take a moment to observe cancellation
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if remaining := runtime.NumGoroutine(); remaining > goroutines {
+				t.Errorf("expected %d goroutines after stopping, got %d", goroutines, remaining)
+			}
+
+			if err := parser.Next(ctx, &record); err == nil {
+				t.Error("expected Next to fail after stopping")
+			}
+		})
+	}
+}
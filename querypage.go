@@ -0,0 +1,8 @@
+package autoquery
+
+import "github.com/aws/aws-sdk-go/service/dynamodb"
+
+type queryPage struct {
+	items            []map[string]*dynamodb.AttributeValue
+	consumedCapacity *dynamodb.ConsumedCapacity
+}
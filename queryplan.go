@@ -0,0 +1,101 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// QueryPlan is the result of index selection for an expression on a table. A plan may be computed
+// once with Client.Plan and run many times, avoiding repeated index selection for queries that
+// differ only in their condition values.
+type QueryPlan struct {
+	// TableName is the name of the table the plan queries.
+	TableName string
+
+	// IndexName is the name of the selected index. The table's primary index is identified by
+	// PrimaryIndexName, or by the client's PrimaryIndexLabel if set.
+	IndexName string
+
+	client *Client
+	index  *tableIndex
+	expr   *Expression
+
+	// cacheKey is the table's metadata cache key in the scope the plan was created in, under which
+	// registered types are looked up for expressions run with the plan
+	cacheKey string
+}
+
+// Plan selects an index for the expression on the table and returns a plan that may be used to
+// query it without repeating index selection. As with Parser.Next, the table's index metadata is
+// retrieved using the underlying metadata provider if it is not already cached. If no indexes are
+// viable, an ErrNoViableIndexes error is returned.
+func (client *Client) Plan(ctx context.Context, tableName string,
+	expr *Expression) (*QueryPlan, error) {
+
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+	expr = client.applyRegisteredType(cacheKey, tableName, expr)
+
+	index, err := client.chooseIndex(ctx, tableName, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryPlan{
+		TableName: tableName,
+		IndexName: client.indexLabel(index.Name),
+		client:    client,
+		cacheKey:  cacheKey,
+		index:     index,
+		expr:      expr.boundToIndex(index),
+	}, nil
+}
+
+// Run returns a parser that queries the plan's selected index. If expr is nil, the parser queries
+// the expression the plan was created with. Otherwise, expr is queried in its place, which allows
+// a plan to be reused with different condition values. The planned index is checked for viability
+// with expr, but indexes are not scored again; if the planned index is not viable for expr, Next
+// returns an ErrIndexNotViable error.
+func (plan *QueryPlan) Run(expr *Expression) *Parser {
+	if expr == nil {
+		expr = plan.expr
+	} else {
+		expr = plan.client.applyRegisteredType(plan.cacheKey, plan.TableName, expr)
+		expr = plan.checkViability(expr.boundToIndex(plan.index))
+	}
+
+	return &Parser{
+		client:        plan.client,
+		tableName:     plan.TableName,
+		expr:          expr,
+		selectedIndex: plan.index,
+		typeApplied:   true,
+		bufferedItems: []map[string]*dynamodb.AttributeValue{},
+	}
+}
+
+// checkViability returns the expression with an error set if the planned index is not viable
+func (plan *QueryPlan) checkViability(expr *Expression) *Expression {
+	if expr.error() != nil {
+		return expr
+	}
+
+	// consistent read may have been downgraded when the plan was created
+	checkedExpr := *expr
+	if checkedExpr.consistentRead && !plan.index.ConsistentReadable &&
+		plan.client.ConsistencyPolicy == AutoDowngrade {
+		checkedExpr.consistentRead = false
+	}
+
+	notViableReasons := plan.client.listIndexViabilityInfractions(plan.index, &checkedExpr)
+	if len(notViableReasons) == 0 {
+		return expr
+	}
+
+	invalidExpr := *expr
+	invalidExpr.setErr(&ErrIndexNotViable{
+		IndexName:        plan.index.Name,
+		NotViableReasons: notViableReasons,
+	})
+	return &invalidExpr
+}
@@ -0,0 +1,71 @@
+package autoquery
+
+import "math"
+
+// missingAttributeFetchCost is the estimated cost of fetching one item from the table by key,
+// relative to reading a full item through a query
+const missingAttributeFetchCost = 2.0
+
+// estimateReadCost returns a relative estimate of the cost of reading an expression's items from a
+// viable index. The estimate grows with the number of items in the index and the width of its
+// projected items, and shrinks as the index's score for the expression increases.
+func estimateReadCost(index *tableIndex, score float64, tableAttributeCount int) float64 {
+	if score <= 0.0 {
+		return 0.0
+	}
+
+	return math.Max(float64(index.Size), 1.0) * index.projectionWidth(tableAttributeCount) / score
+}
+
+// estimateFetchCost returns a relative estimate of the cost of fetching the attributes missing from
+// a viable index's items from the table by key, in the same units as estimateReadCost. Each item
+// read from the index is fetched in full.
+func estimateFetchCost(index *tableIndex, score float64) float64 {
+	if score <= 0.0 {
+		return 0.0
+	}
+
+	return math.Max(float64(index.Size), 1.0) * missingAttributeFetchCost / score
+}
+
+// projectionWidth returns the estimated fraction of the table's attributes projected by the index
+func (index *tableIndex) projectionWidth(tableAttributeCount int) float64 {
+	if index.IncludesAllAttributes || tableAttributeCount <= 0 {
+		return 1.0
+	}
+	return math.Min(float64(len(index.AttributeSet))/float64(tableAttributeCount), 1.0)
+}
+
+// tableAttributeCount returns the number of distinct attributes known to be in the table's items,
+// from index projections and the attributes referenced by the expression
+func (indexMetadata *tableIndexMetadata) tableAttributeCount(expr *Expression) int {
+	attributeSet := map[string]struct{}{}
+	someIndexIncludesAllAttributes := false
+	for _, index := range indexMetadata.Indexes {
+		if index.IncludesAllAttributes {
+			someIndexIncludesAllAttributes = true
+		}
+		for attribute := range index.AttributeSet {
+			attributeSet[attribute] = struct{}{}
+		}
+		for _, key := range index.getKeys() {
+			attributeSet[key] = struct{}{}
+		}
+	}
+	for attribute := range expr.filters {
+		attributeSet[attribute] = struct{}{}
+	}
+	for _, attribute := range expr.attributes {
+		attributeSet[attribute] = struct{}{}
+	}
+
+	count := len(attributeSet)
+
+	// an index projecting all attributes is assumed to hold at least one attribute which is not
+	// projected by any other index
+	if someIndexIncludesAllAttributes {
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,49 @@
+package autoquery
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// attributeTransform is a read transform of a single attribute
+type attributeTransform struct {
+	attr      string
+	transform func(value *dynamodb.AttributeValue) error
+}
+
+// ReadTransform adds a transform which is applied to the value of the attribute attr in each item
+// after it is read from DynamoDB and before it is post-filtered or unmarshaled by Parser.Next, so
+// that attributes stored compressed or encrypted may be decoded transparently. The transform
+// modifies the value in place, such as by replacing its B field with decompressed bytes, and an
+// error from the transform is returned by Parser.Next. Items without the attribute are not
+// transformed. Multiple transforms of the same attribute are applied in the order they are added.
+//
+// Transforms are not considered for index selection, so conditions on a transformed attribute
+// match its stored value. Transforms are not applied by Client.Count.
+func (expr *Expression) ReadTransform(attr string,
+	transform func(value *dynamodb.AttributeValue) error) *Expression {
+
+	expr.readTransforms = append(expr.readTransforms, attributeTransform{
+		attr:      attr,
+		transform: transform,
+	})
+	return expr
+}
+
+// applyReadTransforms applies the expression's read transforms to each of the items
+func (expr *Expression) applyReadTransforms(items []map[string]*dynamodb.AttributeValue) error {
+	for _, item := range items {
+		for _, readTransform := range expr.readTransforms {
+			value, found := item[readTransform.attr]
+			if !found || value == nil {
+				continue
+			}
+			if err := readTransform.transform(value); err != nil {
+				return fmt.Errorf("read transform of attribute %s failed: %w",
+					readTransform.attr, err)
+			}
+		}
+	}
+	return nil
+}
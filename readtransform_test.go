@@ -0,0 +1,86 @@
+package autoquery
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// gunzip decompresses a binary attribute value in place
+func gunzip(value *dynamodb.AttributeValue) error {
+	reader, err := gzip.NewReader(bytes.NewReader(value.B))
+	if err != nil {
+		return err
+	}
+	value.B, err = ioutil.ReadAll(reader)
+	return err
+}
+
+func TestGzipReadTransform(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write([]byte("order notes")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		payload     *dynamodb.AttributeValue
+		expected    string
+		expectedErr string
+	}{
+		{"compressed payload", &dynamodb.AttributeValue{B: compressed.Bytes()}, "order notes", ""},
+		{"missing payload", nil, "", ""},
+		{"invalid payload", &dynamodb.AttributeValue{B: []byte("plain")},
+			"", "read transform of attribute payload failed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newOrdersService(t)
+			item := map[string]*dynamodb.AttributeValue{
+				"customer": {S: aws.String("c")},
+				"id":       {N: aws.String("1")},
+			}
+			if tc.payload != nil {
+				item["payload"] = tc.payload
+			}
+			_, err := service.PutItem(&dynamodb.PutItemInput{
+				TableName: aws.String("Orders"),
+				Item:      item,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := NewClient(service)
+			expr := NewExpression().Equal("customer", "c").ReadTransform("payload", gunzip)
+
+			var order struct {
+				ID      int    `dynamodbav:"id"`
+				Payload []byte `dynamodbav:"payload"`
+			}
+			err = client.Query("Orders", expr).Next(context.Background(), &order)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Errorf("expected error %q, got %v", tc.expectedErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if string(order.Payload) != tc.expected {
+				t.Errorf("expected payload %q, got %q", tc.expected, order.Payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// QueryReadYourWrites retrieves all items that match the expression, approximating
+// read-your-writes consistency when a global secondary index is selected. Writes propagate to
+// global secondary indexes asynchronously, so recently written items may be missing from, or stale
+// in, the index. In addition to querying the selected index, QueryReadYourWrites queries the
+// table's primary index with the same expression using consistent read, and merges the results by
+// primary key.
+//
+// The results are merged as a union de-duplicated by primary key. Items are returned in the
+// selected index's order, with the primary index's version of each item taking precedence,
+// followed by any items found only through the primary index in primary index order. Items found
+// only through the selected index are kept as returned by the index. Items are unmarshaled into
+// returnItems, which should be a pointer to a slice of items with dynamodbav attribute tags.
+//
+// The expression must include an Equal condition on the table's partition key, since the primary
+// index must be viable for the expression. The primary index query reads the entire partition
+// subject to any condition on the table's sort key, so this may be considerably more expensive than
+// querying the index alone. If the primary index is selected, it is queried only once.
+func (client *Client) QueryReadYourWrites(ctx context.Context, tableName string,
+	expr *Expression, returnItems interface{}) error {
+
+	plan, err := client.Plan(ctx, tableName, expr)
+	if err != nil {
+		return err
+	}
+
+	if plan.index.Name == tablePrimaryIndexName {
+		page, err := plan.Run(nil).fetchAllPages(ctx)
+		if err != nil {
+			return err
+		}
+		return dynamodbattribute.UnmarshalListOfMaps(page.items, returnItems)
+	}
+
+	indexMetadata, err := client.pullIndexMetadata(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	primaryKeys := indexMetadata.Indexes[0].getKeys()
+
+	// both queries must return the table's key attributes in order to merge items
+	keyedExpr := plan.expr.withKeyAttributes(primaryKeys)
+
+	primaryExpr := *keyedExpr
+	primaryExpr.restrictedIndexes = map[string]struct{}{tablePrimaryIndexName: {}}
+	primaryExpr.consistentRead = true
+	primaryExpr.parallelSegments = 0
+
+	primaryIndex, _, err := client.selectIndex(ctx, tableName, &primaryExpr)
+	if err != nil {
+		return fmt.Errorf("primary index is required to read your writes: %w", err)
+	}
+	primaryPage, err := client.newParserOnIndex(tableName, &primaryExpr, primaryIndex).
+		fetchAllPages(ctx)
+	if err != nil {
+		return err
+	}
+
+	indexPage, err := client.newParserOnIndex(tableName, keyedExpr, plan.index).fetchAllPages(ctx)
+	if err != nil {
+		return err
+	}
+
+	primaryItems := map[string]map[string]*dynamodb.AttributeValue{}
+	for _, item := range primaryPage.items {
+		primaryItems[itemKeyString(item, primaryKeys)] = item
+	}
+
+	mergedItems := []map[string]*dynamodb.AttributeValue{}
+	merged := map[string]struct{}{}
+	for _, item := range indexPage.items {
+		key := itemKeyString(item, primaryKeys)
+		if primaryItem, found := primaryItems[key]; found {
+			item = primaryItem
+		}
+		mergedItems = append(mergedItems, item)
+		merged[key] = struct{}{}
+	}
+	for _, item := range primaryPage.items {
+		if _, found := merged[itemKeyString(item, primaryKeys)]; !found {
+			mergedItems = append(mergedItems, item)
+		}
+	}
+
+	return dynamodbattribute.UnmarshalListOfMaps(mergedItems, returnItems)
+}
+
+func (client *Client) newParserOnIndex(
+	tableName string, expr *Expression, index *tableIndex) *Parser {
+
+	return &Parser{
+		client:        client,
+		tableName:     tableName,
+		expr:          expr,
+		selectedIndex: index,
+		bufferedItems: []map[string]*dynamodb.AttributeValue{},
+	}
+}
+
+// withKeyAttributes returns the expression with the key attributes added to its selected
+// attributes, if attributes are selected
+func (expr *Expression) withKeyAttributes(keys []string) *Expression {
+	if !expr.attributesSpecified {
+		return expr
+	}
+
+	keyedExpr := *expr
+	keyedExpr.additionalAttributes = keys
+	return keyedExpr.withAdditionalAttributes()
+}
+
+// itemKeyString returns a string which uniquely identifies an item by its key attributes
+func itemKeyString(item map[string]*dynamodb.AttributeValue, keys []string) string {
+	parts := []string{}
+	for _, key := range keys {
+		value := item[key]
+		switch {
+		case value == nil:
+			parts = append(parts, "")
+		case value.S != nil:
+			parts = append(parts, "S:"+aws.StringValue(value.S))
+		case value.N != nil:
+			parts = append(parts, "N:"+aws.StringValue(value.N))
+		case value.B != nil:
+			parts = append(parts, fmt.Sprintf("B:%x", value.B))
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
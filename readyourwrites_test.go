@@ -0,0 +1,182 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/dgravesa/dynamodb-autoquery/autoquerytest"
+)
+
+type testOrder struct {
+	Customer string `dynamodbav:"customer"`
+	ID       int    `dynamodbav:"id"`
+	Status   string `dynamodbav:"status"`
+	Total    int    `dynamodbav:"total"`
+}
+
+// newOrdersService creates an in-memory service with an Orders table keyed by customer and id,
+// with a global secondary index on status and id, holding the orders
+func newOrdersService(t *testing.T, orders ...testOrder) *autoquerytest.Service {
+	t.Helper()
+
+	service := autoquerytest.NewService()
+	_, err := service.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("Orders"),
+		KeySchema: keySchema("customer", "id"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("customer"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("id"), AttributeType: aws.String("N")},
+			{AttributeName: aws.String("status"), AttributeType: aws.String("S")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName:  aws.String("status-id"),
+				KeySchema:  keySchema("status", "id"),
+				Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range orders {
+		_, err := service.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("Orders"),
+			Item: map[string]*dynamodb.AttributeValue{
+				"customer": {S: aws.String(order.Customer)},
+				"id":       {N: aws.String(strconv.Itoa(order.ID))},
+				"status":   {S: aws.String(order.Status)},
+				"total":    {N: aws.String(strconv.Itoa(order.Total))},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return service
+}
+
+// withIndexLag returns a query function which applies lag to the items of queries on secondary
+// indexes, simulating writes which have not yet propagated to the index
+func withIndexLag(service *autoquerytest.Service,
+	lag func(items []map[string]*dynamodb.AttributeValue) []map[string]*dynamodb.AttributeValue,
+) QueryFunc {
+
+	return func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		output, err := service.QueryWithContext(ctx, input, opts...)
+		if err == nil && input.IndexName != nil {
+			output.Items = lag(output.Items)
+		}
+		return output, err
+	}
+}
+
+func pendingOrdersExpression() *Expression {
+	return NewExpression().Equal("customer", "c1").Equal("status", "pending").
+		RestrictToIndexes("status-id")
+}
+
+func TestQueryReadYourWritesIncludesItemMissingFromIndex(t *testing.T) {
+	service := newOrdersService(t,
+		testOrder{"c1", 1, "pending", 10},
+		testOrder{"c1", 2, "shipped", 20},
+		testOrder{"c1", 3, "pending", 30},
+		testOrder{"c2", 4, "pending", 40},
+	)
+	client := NewClient(service)
+
+	// the most recent order has not yet propagated to the index
+	client.QueryFunc = withIndexLag(service,
+		func(items []map[string]*dynamodb.AttributeValue) []map[string]*dynamodb.AttributeValue {
+			lagged := []map[string]*dynamodb.AttributeValue{}
+			for _, item := range items {
+				if aws.StringValue(item["id"].N) != "3" {
+					lagged = append(lagged, item)
+				}
+			}
+			return lagged
+		})
+
+	orders := []testOrder{}
+	err := client.QueryReadYourWrites(context.Background(), "Orders", pendingOrdersExpression(),
+		&orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []testOrder{{"c1", 1, "pending", 10}, {"c1", 3, "pending", 30}}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("expected %v, got %v", expected, orders)
+	}
+}
+
+func TestQueryReadYourWritesPrefersPrimaryVersion(t *testing.T) {
+	service := newOrdersService(t,
+		testOrder{"c1", 1, "pending", 10},
+		testOrder{"c1", 2, "pending", 20},
+	)
+	client := NewClient(service)
+
+	// the index holds a stale total for the first order
+	client.QueryFunc = withIndexLag(service,
+		func(items []map[string]*dynamodb.AttributeValue) []map[string]*dynamodb.AttributeValue {
+			for _, item := range items {
+				if aws.StringValue(item["id"].N) == "1" {
+					item["total"] = &dynamodb.AttributeValue{N: aws.String("5")}
+				}
+			}
+			return items
+		})
+
+	orders := []testOrder{}
+	err := client.QueryReadYourWrites(context.Background(), "Orders", pendingOrdersExpression(),
+		&orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []testOrder{{"c1", 1, "pending", 10}, {"c1", 2, "pending", 20}}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("expected %v, got %v", expected, orders)
+	}
+}
+
+func TestQueryReadYourWritesKeepsIndexOnlyItems(t *testing.T) {
+	service := newOrdersService(t,
+		testOrder{"c1", 1, "pending", 10},
+		testOrder{"c1", 2, "pending", 20},
+	)
+	client := NewClient(service)
+
+	// the primary index query does not return the second order, which is only in the index
+	client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+		opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+		output, err := service.QueryWithContext(ctx, input, opts...)
+		if err == nil && input.IndexName == nil {
+			output.Items = output.Items[:1]
+		}
+		return output, err
+	}
+
+	orders := []testOrder{}
+	err := client.QueryReadYourWrites(context.Background(), "Orders", pendingOrdersExpression(),
+		&orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []testOrder{{"c1", 1, "pending", 10}, {"c1", 2, "pending", 20}}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("expected %v, got %v", expected, orders)
+	}
+}
@@ -0,0 +1,109 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RegisterEntity records the attributes of the struct v as the attributes of an entity type stored
+// in the table, for tables which store multiple entity types. The attribute names are taken from
+// the struct's exported fields and their "dynamodbav" tags, in the same way as RegisterType.
+//
+// In single-table designs, the same attribute name may hold unrelated data for different entity
+// types, or may only be present for some of them. Expressions which declare an entity type with
+// Expression.ForEntity are validated against the entity's attributes, so that selecting or
+// conditioning on an attribute the entity does not have is reported as an error by Parser.Next
+// rather than silently returning no items or unrelated data.
+//
+// As with RegisterType, entities are registered in the scope of ctx if the client's metadata
+// provider is a ScopedDescriptionProvider.
+func (client *Client) RegisterEntity(
+	ctx context.Context, tableName, entityType string, v interface{}) error {
+	attributes, err := structAttributeNames(reflect.TypeOf(v))
+	if err != nil {
+		return err
+	}
+
+	client.registeredTypesMutex.Lock()
+	defer client.registeredTypesMutex.Unlock()
+
+	cacheKey := client.metadataCacheKey(ctx, tableName)
+	entities, found := client.registeredEntities[cacheKey]
+	if !found {
+		entities = map[string][]string{}
+		client.registeredEntities[cacheKey] = entities
+	}
+	entities[entityType] = attributes
+
+	return nil
+}
+
+// ForEntity declares the entity type queried by the expression, which must be registered for the
+// table with Client.RegisterEntity. Attributes selected by the expression and attributes with
+// conditions in the expression must be attributes of the entity, or Parser.Next returns an error.
+// Conditions added with Filter are not validated.
+func (expr *Expression) ForEntity(entityType string) *Expression {
+	expr.entityType = entityType
+	return expr
+}
+
+func (client *Client) registeredEntityAttributes(
+	cacheKey, entityType string) ([]string, bool) {
+
+	client.registeredTypesMutex.Lock()
+	defer client.registeredTypesMutex.Unlock()
+
+	attributes, found := client.registeredEntities[cacheKey][entityType]
+	return attributes, found
+}
+
+// validateEntity returns the expression with an error set if it references attributes which are
+// not attributes of its declared entity type, which is looked up under the table's cache key
+func (client *Client) validateEntity(
+	cacheKey, tableName string, expr *Expression) *Expression {
+	if expr.entityType == "" || expr.error() != nil {
+		return expr
+	}
+
+	validatedExpr := *expr
+
+	attributes, found := client.registeredEntityAttributes(cacheKey, expr.entityType)
+	if !found {
+		validatedExpr.setErr(fmt.Errorf("entity type is not registered for table %s: %s",
+			tableName, expr.entityType))
+		return &validatedExpr
+	}
+
+	attributeSet := map[string]struct{}{}
+	for _, attribute := range attributes {
+		attributeSet[attribute] = struct{}{}
+	}
+
+	unknownAttributeSet := map[string]struct{}{}
+	for attribute := range expr.filters {
+		if _, found := attributeSet[attribute]; !found {
+			unknownAttributeSet[attribute] = struct{}{}
+		}
+	}
+	for _, attribute := range append(append([]string{}, expr.attributes...),
+		expr.additionalAttributes...) {
+		if _, found := attributeSet[attribute]; !found {
+			unknownAttributeSet[attribute] = struct{}{}
+		}
+	}
+
+	if len(unknownAttributeSet) > 0 {
+		unknownAttributes := []string{}
+		for attribute := range unknownAttributeSet {
+			unknownAttributes = append(unknownAttributes, attribute)
+		}
+		sort.Strings(unknownAttributes)
+		validatedExpr.setErr(fmt.Errorf("attributes are not in entity type %s for table %s: %s",
+			expr.entityType, tableName, strings.Join(unknownAttributes, ", ")))
+	}
+
+	return &validatedExpr
+}
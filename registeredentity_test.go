@@ -0,0 +1,79 @@
+package autoquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func TestRegisteredEntity(t *testing.T) {
+	type recordWithGroup struct {
+		PK string `dynamodbav:"pk"`
+		SK int    `dynamodbav:"sk"`
+		G  string `dynamodbav:"g"`
+	}
+
+	client := NewClient(newFakeService(1))
+	ctx := context.Background()
+	if err := client.RegisterEntity(ctx, "T", "record", testRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RegisterEntity(ctx, "T", "grouped", recordWithGroup{}); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		expr        *Expression
+		expectedErr string
+	}{
+		{
+			name: "entity attributes",
+			expr: NewExpression().Equal("pk", "a").GreaterThan("sk", 1).Select("pk", "sk").
+				ForEntity("record"),
+		},
+		{
+			name:        "filter on unregistered field",
+			expr:        NewExpression().Equal("pk", "a").Equal("g", "x").ForEntity("record"),
+			expectedErr: "attributes are not in entity type record for table T: g",
+		},
+		{
+			name:        "select unregistered field",
+			expr:        NewExpression().Equal("pk", "a").Select("g", "other").ForEntity("record"),
+			expectedErr: "attributes are not in entity type record for table T: g, other",
+		},
+		{
+			name: "field of other entity",
+			expr: NewExpression().Equal("pk", "a").Equal("g", "x").ForEntity("grouped"),
+		},
+		{
+			name: "condition from expression package",
+			expr: NewExpression().Equal("pk", "a").
+				Filter(expression.Name("other").Equal(expression.Value(1))).ForEntity("record"),
+		},
+		{
+			name:        "unregistered entity",
+			expr:        NewExpression().Equal("pk", "a").ForEntity("other"),
+			expectedErr: "entity type is not registered for table T: other",
+		},
+		{
+			name: "no entity",
+			expr: NewExpression().Equal("pk", "a").Equal("other", "x"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := client.Query("T", tc.expr).Prepare(ctx)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Errorf("expected expression to be valid, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error %q, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package autoquery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RegisterType records the attributes of the struct v as the item type of the table. The
+// attribute names are taken from the struct's exported fields and their "dynamodbav" tags, in
+// the same way items are unmarshaled.
+//
+// Once a type is registered, attributes selected by expressions queried on the table must be
+// attributes of the type, or Parser.Next returns an error. Expressions which do not select
+// attributes project the attributes of the type rather than all attributes, which allows indexes
+// that project only those attributes to be viable.
+//
+// If the client's metadata provider is a ScopedDescriptionProvider, the type is registered for the
+// table in the scope of ctx only, since same-named tables in different scopes may store different
+// item types.
+func (client *Client) RegisterType(ctx context.Context, tableName string, v interface{}) error {
+	attributes, err := structAttributeNames(reflect.TypeOf(v))
+	if err != nil {
+		return err
+	}
+
+	client.registeredTypesMutex.Lock()
+	defer client.registeredTypesMutex.Unlock()
+
+	client.registeredTypes[client.metadataCacheKey(ctx, tableName)] = attributes
+
+	return nil
+}
+
+func (client *Client) registeredAttributes(cacheKey string) ([]string, bool) {
+	client.registeredTypesMutex.Lock()
+	defer client.registeredTypesMutex.Unlock()
+
+	attributes, found := client.registeredTypes[cacheKey]
+	return attributes, found
+}
+
+// applyRegisteredType returns the expression with its selected attributes validated against, or
+// defaulted to, the table's registered type, and extended with any attributes added by SelectAlso.
+// The expression is also validated against its declared entity type, if any. Types are looked up
+// under the table's metadata cache key, so that each scope's registrations are kept separate.
+func (client *Client) applyRegisteredType(
+	cacheKey, tableName string, expr *Expression) *Expression {
+
+	expr = client.validateEntity(cacheKey, tableName, expr)
+
+	attributes, found := client.registeredAttributes(cacheKey)
+	if !found {
+		return expr.withAdditionalAttributes()
+	}
+
+	typedExpr := *expr
+
+	if !expr.attributesSpecified {
+		typedExpr.attributesSpecified = true
+		typedExpr.attributes = append([]string{}, attributes...)
+		return typedExpr.withAdditionalAttributes()
+	}
+
+	attributeSet := map[string]struct{}{}
+	for _, attribute := range attributes {
+		attributeSet[attribute] = struct{}{}
+	}
+	unknownAttributes := []string{}
+	for _, attribute := range expr.attributes {
+		if _, found := attributeSet[attribute]; !found {
+			unknownAttributes = append(unknownAttributes, attribute)
+		}
+	}
+	if len(unknownAttributes) > 0 {
+		typedExpr.setErr(fmt.Errorf("selected attributes are not in registered type for table %s: %s",
+			tableName, strings.Join(unknownAttributes, ", ")))
+	}
+
+	return typedExpr.withAdditionalAttributes()
+}
+
+// applyRegisteredType applies the table's registered type to the parser's expressions on first
+// use, looking the type up in the scope of ctx
+func (parser *Parser) applyRegisteredType(ctx context.Context) {
+	if parser.typeApplied {
+		return
+	}
+	parser.typeApplied = true
+
+	cacheKey := parser.client.metadataCacheKey(ctx, parser.tableName)
+	parser.expr = parser.client.applyRegisteredType(cacheKey, parser.tableName, parser.expr)
+	if parser.fallbackExpr != nil {
+		parser.fallbackExpr = parser.client.applyRegisteredType(
+			cacheKey, parser.tableName, parser.fallbackExpr)
+	}
+}
+
+func structAttributeNames(t reflect.Type) ([]string, error) {
+	fields, err := structFieldAttributes(t)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := []string{}
+	for _, field := range fields {
+		attributes = append(attributes, field.attribute)
+	}
+	return attributes, nil
+}
+
+// fieldAttribute is the attribute name to which a struct field is marshaled
+type fieldAttribute struct {
+	fieldName string
+	attribute string
+
+	// depth is the number of embedded structs through which the field is promoted
+	depth int
+}
+
+// structFieldAttributes returns the attributes of the struct's exported fields in field order,
+// including the fields of untagged embedded structs
+func structFieldAttributes(t reflect.Type) ([]fieldAttribute, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("registered type must be a struct: %v", t)
+	}
+
+	fields := []fieldAttribute{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("dynamodbav")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		// untagged embedded structs are flattened into the item
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embedded, err := structFieldAttributes(fieldType)
+			if err != nil {
+				return nil, err
+			}
+			for _, embeddedField := range embedded {
+				embeddedField.depth++
+				fields = append(fields, embeddedField)
+			}
+			continue
+		}
+
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, fieldAttribute{fieldName: field.Name, attribute: name})
+	}
+
+	return fields, nil
+}
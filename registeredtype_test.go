@@ -0,0 +1,68 @@
+package autoquery
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisteredType(t *testing.T) {
+	testCases := []struct {
+		name               string
+		expr               *Expression
+		expectedProjection []string
+		expectedErr        string
+	}{
+		{
+			name:               "default projection",
+			expr:               NewExpression().Equal("pk", "a"),
+			expectedProjection: []string{"pk", "sk"},
+		},
+		{
+			name:               "known field",
+			expr:               NewExpression().Equal("pk", "a").Select("sk"),
+			expectedProjection: []string{"sk", "pk"},
+		},
+		{
+			name:        "unknown field",
+			expr:        NewExpression().Equal("pk", "a").Select("sk", "skk"),
+			expectedErr: "selected attributes are not in registered type for table T: skk",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(newFakeService(1))
+			ctx := context.Background()
+			if err := client.RegisterType(ctx, "T", testRecord{}); err != nil {
+				t.Fatal(err)
+			}
+
+			explanation, err := client.Query("T", tc.expr).Explain(ctx)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Errorf("expected error %q, got %v", tc.expectedErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			projected := []string{}
+			for _, name := range strings.Split(explanation.ProjectionExpression, ", ") {
+				projected = append(projected, explanation.ExpressionAttributeNames[name])
+			}
+			if !reflect.DeepEqual(projected, tc.expectedProjection) {
+				t.Errorf("expected projection %v, got %v", tc.expectedProjection, projected)
+			}
+		})
+	}
+}
+
+func TestRegisterTypeRequiresStruct(t *testing.T) {
+	client := NewClient(newFakeService(1))
+	if err := client.RegisterType(context.Background(), "T", "record"); err == nil {
+		t.Error("expected non-struct type to be rejected")
+	}
+}
@@ -0,0 +1,26 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a copy of ctx which carries request options for DynamoDB calls. Calls
+// made by the client with the returned context, including Query and ExecuteStatement calls made
+// by Parser.Next, GetItem and PutItem calls, and DescribeTable calls made by the default metadata
+// provider, are made with the options applied. This may be used to apply per-request credentials,
+// handlers, or other request customizations.
+//
+// Options are appended to any options already carried by ctx.
+func WithRequestOptions(ctx context.Context, opts ...request.Option) context.Context {
+	combined := append(append([]request.Option{}, requestOptionsFromContext(ctx)...), opts...)
+	return context.WithValue(ctx, requestOptionsKey{}, combined)
+}
+
+func requestOptionsFromContext(ctx context.Context) []request.Option {
+	opts, _ := ctx.Value(requestOptionsKey{}).([]request.Option)
+	return opts
+}
@@ -0,0 +1,51 @@
+package autoquery
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SelectFields selects the attributes to which the named fields of the struct v are marshaled, as
+// determined by their "dynamodbav" tags in the same way as RegisterType. Fields are named by their
+// Go field names, so selections are checked against the item type rather than written as attribute
+// name strings, and only the fields the caller reads are projected. Fields of untagged embedded
+// structs may be named directly. Subsequent calls append to the selected attributes, as with
+// Select.
+//
+// If v is not a struct, or a field name is not an exported, marshaled field of v, Parser.Next
+// returns an error.
+func (expr *Expression) SelectFields(v interface{}, fieldNames ...string) *Expression {
+	fields, err := structFieldAttributes(reflect.TypeOf(v))
+	if err != nil {
+		expr.setErr(err)
+		return expr
+	}
+
+	// shallower fields take precedence over promoted fields of the same name, as in Go
+	fieldsByName := map[string]fieldAttribute{}
+	for _, field := range fields {
+		existing, found := fieldsByName[field.fieldName]
+		if !found || field.depth < existing.depth {
+			fieldsByName[field.fieldName] = field
+		}
+	}
+
+	attributes := []string{}
+	unknownFields := []string{}
+	for _, fieldName := range fieldNames {
+		field, found := fieldsByName[fieldName]
+		if !found {
+			unknownFields = append(unknownFields, fieldName)
+			continue
+		}
+		attributes = append(attributes, field.attribute)
+	}
+	if len(unknownFields) > 0 {
+		expr.setErr(fmt.Errorf("fields are not marshaled attributes of %v: %s",
+			reflect.TypeOf(v), strings.Join(unknownFields, ", ")))
+		return expr
+	}
+
+	return expr.Select(attributes...)
+}
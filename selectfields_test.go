@@ -0,0 +1,55 @@
+package autoquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectFields(t *testing.T) {
+	type audit struct {
+		UpdatedBy string `dynamodbav:"updated_by"`
+	}
+	type order struct {
+		audit
+		Customer string `dynamodbav:"customer"`
+		ID       int    `dynamodbav:"id"`
+		Note     string
+		Internal string `dynamodbav:"-"`
+		secret   string
+	}
+
+	testCases := []struct {
+		name        string
+		v           interface{}
+		fields      []string
+		expected    []string
+		expectedErr string
+	}{
+		{"tagged fields", order{}, []string{"ID", "Customer"}, []string{"id", "customer"}, ""},
+		{"untagged field", order{}, []string{"Note"}, []string{"Note"}, ""},
+		{"promoted field", order{}, []string{"UpdatedBy"}, []string{"updated_by"}, ""},
+		{"unknown field", order{}, []string{"ID", "Idd"}, nil, "Idd"},
+		{"skipped field", order{}, []string{"Internal"}, nil, "Internal"},
+		{"unexported field", order{}, []string{"secret"}, nil, "secret"},
+		{"not a struct", "order", []string{"ID"}, nil, "struct"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := NewExpression().SelectFields(tc.v, tc.fields...)
+			err := expr.error()
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Errorf("expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expr.attributes, tc.expected) {
+				t.Errorf("expected attributes %v, got %v", tc.expected, expr.attributes)
+			}
+		})
+	}
+}
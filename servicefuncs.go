@@ -0,0 +1,55 @@
+package autoquery
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// QueryFunc performs a DynamoDB Query call, matching the signature of QueryWithContext.
+type QueryFunc func(ctx aws.Context, input *dynamodb.QueryInput,
+	opts ...request.Option) (*dynamodb.QueryOutput, error)
+
+// DescribeTableFunc performs a DynamoDB DescribeTable call, matching the signature of
+// DescribeTableWithContext.
+type DescribeTableFunc func(ctx aws.Context, input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error)
+
+func (client *Client) query(ctx aws.Context, input *dynamodb.QueryInput,
+	opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+	var output *dynamodb.QueryOutput
+	err := client.callWithTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		if client.QueryFunc != nil {
+			output, err = client.QueryFunc(ctx, input, opts...)
+		} else {
+			output, err = client.dynamodbService.QueryWithContext(ctx, input, opts...)
+		}
+		return err
+	})
+	if isValidationError(err) {
+		return nil, &ErrInvalidQuery{
+			TableName:                 aws.StringValue(input.TableName),
+			IndexName:                 aws.StringValue(input.IndexName),
+			KeyConditionExpression:    aws.StringValue(input.KeyConditionExpression),
+			FilterExpression:          aws.StringValue(input.FilterExpression),
+			ProjectionExpression:      aws.StringValue(input.ProjectionExpression),
+			ExpressionAttributeNames:  input.ExpressionAttributeNames,
+			ExpressionAttributeValues: input.ExpressionAttributeValues,
+			Err:                       err,
+		}
+	}
+	return output, err
+}
+
+func (client *Client) describeTable(ctx aws.Context, input *dynamodb.DescribeTableInput,
+	opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+
+	if client.DescribeTableFunc != nil {
+		return client.DescribeTableFunc(ctx, input, opts...)
+	}
+	return client.dynamodbService.DescribeTableWithContext(ctx, input, opts...)
+}
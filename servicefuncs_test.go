@@ -0,0 +1,62 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestValidationExceptionIncludesExpressions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		code          string
+		expectWrapped bool
+	}{
+		{"validation exception", "ValidationException", true},
+		{"other error", dynamodb.ErrCodeProvisionedThroughputExceededException, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serviceErr := awserr.New(tc.code, "rejected", nil)
+			var sentKeyCondition string
+
+			client := NewClient(newFakeService(1))
+			client.QueryFunc = func(ctx aws.Context, input *dynamodb.QueryInput,
+				opts ...request.Option) (*dynamodb.QueryOutput, error) {
+
+				sentKeyCondition = aws.StringValue(input.KeyConditionExpression)
+				return nil, serviceErr
+			}
+
+			expr := NewExpression().Equal("pk", "a").GreaterThan("sk", 3)
+			_, err := parseSortKeys(context.Background(), client.Query("T", expr))
+
+			invalidErr, wrapped := err.(*ErrInvalidQuery)
+			if wrapped != tc.expectWrapped {
+				t.Fatalf("expected wrapped %v, got %v", tc.expectWrapped, err)
+			}
+			if !errors.Is(err, serviceErr) {
+				t.Errorf("expected service error to be unwrappable, got %v", err)
+			}
+			if !tc.expectWrapped {
+				return
+			}
+
+			if sentKeyCondition == "" || invalidErr.KeyConditionExpression != sentKeyCondition {
+				t.Errorf("expected key condition %q, got %q",
+					sentKeyCondition, invalidErr.KeyConditionExpression)
+			}
+			if !strings.Contains(err.Error(), sentKeyCondition) {
+				t.Errorf("expected error message to include key condition %q, got %s",
+					sentKeyCondition, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package autoquery
+
+import (
+	"sort"
+	"time"
+)
+
+// TableSnapshot is a copy of a table's cached index metadata.
+type TableSnapshot struct {
+	// TableName is the name of the table.
+	TableName string
+
+	// Scope is the scope for which the metadata was retrieved if the client's metadata provider is
+	// a ScopedDescriptionProvider, and empty otherwise.
+	Scope string
+
+	// FetchedAt is the time at which the table's metadata was retrieved from the metadata
+	// provider.
+	FetchedAt time.Time
+
+	// Indexes describes each index on the table, ordered as in Client.DescribeIndexes.
+	Indexes []IndexDescription
+}
+
+// Snapshot returns a copy of the index metadata of every table cached by the client, ordered by
+// table name and then scope. Snapshot does not retrieve metadata for tables which are not cached.
+// The returned snapshot shares no memory with the client's cache, so it may be retained or
+// modified freely, such as when periodically reporting metadata to a monitoring endpoint.
+func (client *Client) Snapshot() []TableSnapshot {
+	client.metadataMutex.RLock()
+	defer client.metadataMutex.RUnlock()
+
+	snapshots := []TableSnapshot{}
+	for _, indexMetadata := range client.tableIndexMetadataCache {
+		descriptions := []IndexDescription{}
+		for _, index := range indexMetadata.Indexes {
+			descriptions = append(descriptions, newIndexDescription(index))
+		}
+		client.labelIndexDescriptions(descriptions)
+
+		snapshots = append(snapshots, TableSnapshot{
+			TableName: indexMetadata.TableName,
+			Scope:     indexMetadata.Scope,
+			FetchedAt: indexMetadata.FetchedAt,
+			Indexes:   descriptions,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].TableName != snapshots[j].TableName {
+			return snapshots[i].TableName < snapshots[j].TableName
+		}
+		return snapshots[i].Scope < snapshots[j].Scope
+	})
+
+	return snapshots
+}
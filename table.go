@@ -37,3 +37,17 @@ func (table Table) Put(ctx context.Context, item interface{}) error {
 func (table Table) Query(expr *Expression) *Parser {
 	return table.autoqueryClient.Query(table.name, expr)
 }
+
+// Latest retrieves the item with the highest sort key value that matches the expression. The item
+// is returned in returnItem, which should have dynamodbav attribute tags pertaining to the desired
+// return attributes in the table.
+//
+// If no items match the expression, ErrNoItems is returned.
+func (table Table) Latest(ctx context.Context, expr *Expression, returnItem interface{}) error {
+	return table.autoqueryClient.Latest(ctx, table.name, expr, returnItem)
+}
+
+// Count returns the number of items in the table that match the expression.
+func (table Table) Count(ctx context.Context, expr *Expression) (int, error) {
+	return table.autoqueryClient.Count(ctx, table.name, expr)
+}
@@ -2,21 +2,43 @@ package autoquery
 
 import (
 	"math"
+	"regexp"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
 const tablePrimaryIndexName = "#primary"
 
+// PrimaryIndexName is the name used to identify a table's primary index in index reporting, such as
+// Client.IndexesFor. It is never sent to DynamoDB as an IndexName.
+const PrimaryIndexName = tablePrimaryIndexName
+
+// genericKeyPattern matches key attribute names typical of overloaded indexes in single-table
+// designs, such as PK, SK, GSI1PK, or GSI1SK
+var genericKeyPattern = regexp.MustCompile(`(?i)^((g|l)si[0-9]*_?)?(pk|sk|hk|rk)[0-9]*$`)
+
 type tableIndex struct {
 	Name                  string
 	PartitionKey          string
 	SortKey               string
+	SortKeyType           string
 	IsComposite           bool
 	AttributeSet          map[string]struct{}
 	IncludesAllAttributes bool
 	Size                  int
 	ConsistentReadable    bool
+	IsGlobal              bool
+
+	// CapacityShare is the index's provisioned read capacity relative to the index of the table
+	// with the most provisioned read capacity, or 0 if read capacity is not provisioned
+	CapacityShare float64
+
+	// TableKeys are the key attributes of the table's primary index
+	TableKeys []string
+
+	// Backfilling is true if the index was being created or backfilled when its metadata was
+	// retrieved
+	Backfilling bool
 
 	IsSparse                 bool
 	Sparsity                 float64
@@ -37,6 +59,19 @@ func (index *tableIndex) loadKeysFromSchema(keySchema []*dynamodb.KeySchemaEleme
 	}
 }
 
+func (index *tableIndex) loadKeyTypesFromDefinitions(
+	attributeDefinitions []*dynamodb.AttributeDefinition) {
+
+	if !index.IsComposite {
+		return
+	}
+	for _, definition := range attributeDefinitions {
+		if *definition.AttributeName == index.SortKey {
+			index.SortKeyType = *definition.AttributeType
+		}
+	}
+}
+
 func (index tableIndex) getKeys() []string {
 	if index.IsComposite {
 		return []string{index.PartitionKey, index.SortKey}
@@ -44,6 +79,20 @@ func (index tableIndex) getKeys() []string {
 	return []string{index.PartitionKey}
 }
 
+// appearsOverloaded returns true if the index is a composite global secondary index whose keys
+// have generic names, suggesting that it stores multiple entity types distinguished by sort key
+func (index tableIndex) appearsOverloaded() bool {
+	if !index.IsGlobal || !index.IsComposite {
+		return false
+	}
+	for _, key := range index.getKeys() {
+		if !genericKeyPattern.MatchString(key) {
+			return false
+		}
+	}
+	return true
+}
+
 func (index *tableIndex) loadAttributesFromProjection(
 	projection *dynamodb.Projection, tablePrimaryIndexKeys []string) {
 
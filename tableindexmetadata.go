@@ -1,5 +1,46 @@
 package autoquery
 
+import (
+	"sort"
+	"time"
+)
+
 type tableIndexMetadata struct {
+	TableName string
+
+	// Scope is the provider scope of the request context for which the metadata was retrieved
+	Scope string
+
 	Indexes []*tableIndex
+
+	// BillingMode is the table's billing mode, which determines the cost weights of index scoring
+	BillingMode string
+
+	// FetchedAt is the time at which the table description was retrieved
+	FetchedAt time.Time
+}
+
+// sortIndexes orders the indexes with the primary index first, followed by global secondary
+// indexes and then local secondary indexes, each ordered by name, so that candidates are
+// considered in the same order regardless of the order of the table description
+func (indexMetadata *tableIndexMetadata) sortIndexes() {
+	rank := func(index *tableIndex) int {
+		switch {
+		case index.Name == tablePrimaryIndexName:
+			return 0
+		case index.IsGlobal:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	indexes := indexMetadata.Indexes
+	sort.SliceStable(indexes, func(i, j int) bool {
+		rankI, rankJ := rank(indexes[i]), rank(indexes[j])
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return indexes[i].Name < indexes[j].Name
+	})
 }
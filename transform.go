@@ -0,0 +1,35 @@
+package autoquery
+
+import "github.com/aws/aws-sdk-go/service/dynamodb"
+
+// Transform adds a transform which is applied to each item before it is returned by Next, such as
+// to rename, redact, or enrich attributes. The transform receives each item after the expression's
+// post-filters have kept it and returns the item that is unmarshaled by Next, which may be the
+// same item modified in place. Multiple transforms are applied in the order they are added, and
+// aggregations and GroupBy see the transformed items.
+//
+// If a transform returns an error, Next returns the error and iteration is aborted: subsequent
+// calls to Next return the same error until the parser is reset. Transform should be called
+// before the first call to Next.
+func (parser *Parser) Transform(transform func(
+	item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error)) *Parser {
+
+	parser.transforms = append(parser.transforms, transform)
+	return parser
+}
+
+// applyTransforms applies the parser's transforms to the item, recording any error so that
+// iteration is aborted
+func (parser *Parser) applyTransforms(
+	item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+
+	for _, transform := range parser.transforms {
+		var err error
+		item, err = transform(item)
+		if err != nil {
+			parser.transformErr = err
+			return nil, err
+		}
+	}
+	return item, nil
+}
@@ -0,0 +1,116 @@
+package autoquery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type transformedOrder struct {
+	Customer string `dynamodbav:"customer"`
+	ID       int    `dynamodbav:"id"`
+	Amount   int    `dynamodbav:"amount"`
+}
+
+// renameTotal renames the total attribute to amount
+func renameTotal(
+	item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+
+	item["amount"] = item["total"]
+	delete(item, "total")
+	return item, nil
+}
+
+// redactCustomer removes the customer attribute
+func redactCustomer(
+	item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+
+	delete(item, "customer")
+	return item, nil
+}
+
+func TestTransform(t *testing.T) {
+	client := NewClient(newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Status: "open", Total: 10},
+		testOrder{Customer: "c", ID: 2, Status: "open", Total: 20},
+	))
+
+	keepLarge := func(item map[string]*dynamodb.AttributeValue) bool {
+		return aws.StringValue(item["total"].N) != "10"
+	}
+
+	testCases := []struct {
+		name     string
+		expr     *Expression
+		expected []transformedOrder
+	}{
+		{
+			name:     "rename and redact",
+			expr:     NewExpression().Equal("customer", "c"),
+			expected: []transformedOrder{{ID: 1, Amount: 10}, {ID: 2, Amount: 20}},
+		},
+		{
+			name:     "after post-filter",
+			expr:     NewExpression().Equal("customer", "c").PostFilter(keepLarge),
+			expected: []transformedOrder{{ID: 2, Amount: 20}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.Query("Orders", tc.expr).Transform(renameTotal).Transform(redactCustomer)
+
+			orders := []transformedOrder{}
+			for {
+				var order transformedOrder
+				err := parser.Next(context.Background(), &order)
+				if _, complete := err.(*ErrParsingComplete); complete {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				orders = append(orders, order)
+			}
+			if !reflect.DeepEqual(orders, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, orders)
+			}
+		})
+	}
+}
+
+func TestTransformErrorAbortsIteration(t *testing.T) {
+	client := NewClient(newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Status: "open"},
+		testOrder{Customer: "c", ID: 2, Status: "open"},
+		testOrder{Customer: "c", ID: 3, Status: "open"},
+	))
+
+	transformErr := errors.New("transform failed")
+	parser := client.Query("Orders", NewExpression().Equal("customer", "c")).Transform(
+		func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			if aws.StringValue(item["id"].N) == "2" {
+				return nil, transformErr
+			}
+			return item, nil
+		})
+
+	ctx := context.Background()
+	var order testOrder
+	if err := parser.Next(ctx, &order); err != nil || order.ID != 1 {
+		t.Fatalf("expected first item, got %v, %v", order, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := parser.Next(ctx, &order); err != transformErr {
+			t.Errorf("expected transform error, got %v", err)
+		}
+	}
+
+	parser.Reset()
+	if err := parser.Next(ctx, &order); err != nil || order.ID != 1 {
+		t.Errorf("expected reset to restart iteration, got %v, %v", order, err)
+	}
+}
@@ -0,0 +1,158 @@
+package autoquery
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Operator is a condition operator which may be derived from URL query parameters.
+type Operator string
+
+// Operators supported by ExpressionFromValues. In query parameters, the operator follows the
+// attribute name in brackets, such as "year[gte]=2000". A parameter without an operator is an
+// equal condition. Between values are separated by a comma, such as "year[between]=1990,1999".
+const (
+	OperatorEqual            Operator = "eq"
+	OperatorLessThan         Operator = "lt"
+	OperatorLessThanEqual    Operator = "lte"
+	OperatorGreaterThan      Operator = "gt"
+	OperatorGreaterThanEqual Operator = "gte"
+	OperatorBetween          Operator = "between"
+	OperatorBeginsWith       Operator = "begins_with"
+)
+
+// AttributeSchema describes how an attribute may be used in query parameters.
+type AttributeSchema struct {
+	// Numeric is true if values for the attribute are numbers. Otherwise values are strings.
+	Numeric bool
+
+	// Operators lists the operators allowed on the attribute.
+	Operators []Operator
+}
+
+// ValuesSchema allow-lists the attributes which may be used in query parameters, keyed by
+// attribute name.
+type ValuesSchema map[string]AttributeSchema
+
+var valuesKeyPattern = regexp.MustCompile(`^([^\[\]]+)(?:\[([a-z_]+)\])?$`)
+
+// ExpressionFromValues creates a new Expression from URL query parameters, such as those of an
+// HTTP request to an API backed by DynamoDB. Each parameter adds one condition to the expression.
+// Parameters must refer to attributes in schema and use operators allowed by the schema, so that
+// callers cannot add arbitrary conditions. An error is returned for unknown attributes,
+// unsupported or disallowed operators, repeated parameters, multiple conditions on the same
+// attribute, and malformed values.
+func ExpressionFromValues(values url.Values, schema ValuesSchema) (*Expression, error) {
+	expr := NewExpression()
+
+	// apply parameters in a consistent order
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// only the most recent condition on an attribute applies, so reject multiple conditions
+	conditionedAttrs := map[string]struct{}{}
+
+	for _, key := range keys {
+		match := valuesKeyPattern.FindStringSubmatch(key)
+		if match == nil {
+			return nil, fmt.Errorf("invalid query parameter: %s", key)
+		}
+		attr, operator := match[1], Operator(match[2])
+		if operator == "" {
+			operator = OperatorEqual
+		}
+
+		attrSchema, found := schema[attr]
+		if !found {
+			return nil, fmt.Errorf("attribute not allowed in query parameters: %s", attr)
+		}
+		if _, found := conditionedAttrs[attr]; found {
+			return nil, fmt.Errorf("multiple conditions on attribute: %s", attr)
+		}
+		conditionedAttrs[attr] = struct{}{}
+		if !attrSchema.allows(operator) {
+			return nil, fmt.Errorf("operator not allowed on attribute %s: %s", attr, operator)
+		}
+		if len(values[key]) != 1 {
+			return nil, fmt.Errorf("query parameter must be specified exactly once: %s", key)
+		}
+		raw := values[key][0]
+
+		if operator == OperatorBeginsWith {
+			if attrSchema.Numeric {
+				return nil, fmt.Errorf("operator not supported on numeric attribute %s: %s",
+					attr, operator)
+			}
+			expr.BeginsWith(attr, raw)
+			continue
+		}
+
+		if operator == OperatorBetween {
+			bounds := strings.Split(raw, ",")
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("between parameter requires two values: %s", key)
+			}
+			lowval, err := attrSchema.parseValue(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %s", key, err)
+			}
+			highval, err := attrSchema.parseValue(bounds[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %s", key, err)
+			}
+			expr.Between(attr, lowval, highval)
+			continue
+		}
+
+		value, err := attrSchema.parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %s", key, err)
+		}
+
+		switch operator {
+		case OperatorEqual:
+			expr.Equal(attr, value)
+		case OperatorLessThan:
+			expr.LessThan(attr, value)
+		case OperatorLessThanEqual:
+			expr.LessThanEqual(attr, value)
+		case OperatorGreaterThan:
+			expr.GreaterThan(attr, value)
+		case OperatorGreaterThanEqual:
+			expr.GreaterThanEqual(attr, value)
+		default:
+			return nil, fmt.Errorf("unsupported operator on attribute %s: %s", attr, operator)
+		}
+	}
+
+	return expr, nil
+}
+
+func (s AttributeSchema) allows(operator Operator) bool {
+	for _, allowed := range s.Operators {
+		if allowed == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func (s AttributeSchema) parseValue(raw string) (interface{}, error) {
+	if !s.Numeric {
+		return raw, nil
+	}
+	// numbers are passed through as strings to preserve their precision
+	if _, err := strconv.ParseFloat(raw, 64); err != nil {
+		return nil, fmt.Errorf("not a number: %s", raw)
+	}
+	return dynamodbattribute.Number(raw), nil
+}
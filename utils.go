@@ -1,7 +1,33 @@
 package autoquery
 
-import "reflect"
+import (
+	"errors"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
 
 func typesMatch(a, b interface{}) bool {
 	return reflect.TypeOf(a) == reflect.TypeOf(b)
 }
+
+func itemCountOrDefault(itemCount *int64, defaultCount int) int {
+	if itemCount == nil {
+		return defaultCount
+	}
+	return int(*itemCount)
+}
+
+// isAccessDenied returns true if err is, or wraps, an AWS error indicating that the caller lacks
+// permission for the request
+func isAccessDenied(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == "AccessDeniedException"
+}
+
+// isValidationError returns true if err is, or wraps, an AWS error indicating that the request was
+// rejected as invalid
+func isValidationError(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == "ValidationException"
+}
@@ -0,0 +1,71 @@
+package autoquery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// WriteJSONL writes all remaining items in the query to w in JSON Lines format, one JSON object
+// per line, and returns the number of items written. Items are converted from DynamoDB attribute
+// values to plain JSON, so numbers are written as JSON numbers with their stored precision, sets as
+// arrays, and binary values as base64 strings. Items are written as they are parsed, so the full
+// result is never held in memory, which is useful for exporting query results to data pipelines.
+//
+// Max pagination and total limits apply as with Next. If an error occurs while querying or
+// writing, the count of items written before the error is returned with the error.
+func (parser *Parser) WriteJSONL(ctx context.Context, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	decoder := dynamodbattribute.NewDecoder(func(d *dynamodbattribute.Decoder) {
+		d.UseNumber = true
+	})
+
+	written := 0
+	for {
+		item, err := parser.nextItemWithFallback(ctx)
+		if _, complete := err.(*ErrParsingComplete); complete {
+			return written, nil
+		} else if err != nil {
+			return written, err
+		}
+
+		var jsonItem interface{}
+		if err := decoder.Decode(&dynamodb.AttributeValue{M: item}, &jsonItem); err != nil {
+			return written, err
+		}
+
+		// Encode writes each value followed by a newline
+		if err := encoder.Encode(jsonNumbers(jsonItem)); err != nil {
+			return written, err
+		}
+		written++
+	}
+}
+
+// jsonNumbers replaces the DynamoDB numbers in a decoded value with JSON numbers, which are encoded
+// as numbers rather than strings
+func jsonNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case dynamodbattribute.Number:
+		return json.Number(v)
+	case []dynamodbattribute.Number:
+		numbers := make([]json.Number, len(v))
+		for i, number := range v {
+			numbers[i] = json.Number(number)
+		}
+		return numbers
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = jsonNumbers(element)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = jsonNumbers(element)
+		}
+	}
+	return value
+}
@@ -0,0 +1,102 @@
+package autoquery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// failingWriter accepts a number of writes before failing
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		return 0, errors.New("write failed")
+	}
+	w.writes--
+	return len(p), nil
+}
+
+func TestWriteJSONL(t *testing.T) {
+	service := newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Status: "open", Total: 10},
+		testOrder{Customer: "c", ID: 2, Status: "closed", Total: 20},
+	)
+	_, err := service.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("Orders"),
+		Item: map[string]*dynamodb.AttributeValue{
+			"customer": {S: aws.String("c")},
+			"id":       {N: aws.String("3")},
+			"price":    {N: aws.String("12345678901234567890.5")},
+			"tags":     {SS: []*string{aws.String("a<b")}},
+			"data":     {B: []byte("hi")},
+			"nested":   {M: map[string]*dynamodb.AttributeValue{"n": {NS: []*string{aws.String("7")}}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(service)
+
+	testCases := []struct {
+		name       string
+		totalLimit int
+		expected   string
+	}{
+		{
+			name: "all items",
+			expected: `{"customer":"c","id":1,"status":"open","total":10}` + "\n" +
+				`{"customer":"c","id":2,"status":"closed","total":20}` + "\n" +
+				`{"customer":"c","data":"aGk=","id":3,"nested":{"n":[7]},` +
+				`"price":12345678901234567890.5,"tags":["a<b"]}` + "\n",
+		},
+		{
+			name:       "total limit",
+			totalLimit: 1,
+			expected:   `{"customer":"c","id":1,"status":"open","total":10}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := client.Query("Orders", NewExpression().Equal("customer", "c"))
+			if tc.totalLimit > 0 {
+				parser.SetTotalLimit(tc.totalLimit)
+			}
+
+			var output bytes.Buffer
+			written, err := parser.WriteJSONL(context.Background(), &output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output.String() != tc.expected {
+				t.Errorf("expected output:\n%s\ngot:\n%s", tc.expected, output.String())
+			}
+			if expected := bytes.Count([]byte(tc.expected), []byte("\n")); written != expected {
+				t.Errorf("expected %d items written, got %d", expected, written)
+			}
+		})
+	}
+}
+
+func TestWriteJSONLWriteError(t *testing.T) {
+	service := newOrdersService(t,
+		testOrder{Customer: "c", ID: 1, Status: "open"},
+		testOrder{Customer: "c", ID: 2, Status: "open"},
+	)
+	parser := NewClient(service).Query("Orders", NewExpression().Equal("customer", "c"))
+
+	written, err := parser.WriteJSONL(context.Background(), &failingWriter{writes: 1})
+	if err == nil {
+		t.Error("expected write error")
+	}
+	if written != 1 {
+		t.Errorf("expected 1 item written before the error, got %d", written)
+	}
+}